@@ -0,0 +1,131 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// Awaiter waits for JavaScript Promises to settle on behalf of the streams in this package. Every blocking operation,
+// such as Read or Write, goes through the package's Awaiter.
+//
+// Await must block the calling goroutine until promise is either fulfilled or rejected. If it is fulfilled, Await must
+// return the value it was fulfilled with and a nil error. If it is rejected, Await must return a non-nil error describing
+// the rejection reason. Await must be safe to call from multiple goroutines at once, must not assume it is the only code
+// reacting to promise, and is never called from within a JavaScript callback.
+type Awaiter interface {
+	Await(promise js.Value) (js.Value, error)
+}
+
+// waitGroupAwaiter is the default Awaiter. It attaches callbacks to the promise and parks the goroutine on a WaitGroup
+// until one of them runs, which relies on the Go scheduler yielding to the JavaScript event loop while goroutines are
+// blocked, as it does in browsers and Node.js.
+type waitGroupAwaiter struct{}
+
+func (waitGroupAwaiter) Await(promise js.Value) (result js.Value, err error) {
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(1)
+
+	onFulfilled := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		defer waitGroup.Done()
+		result = args[0]
+		return nil
+	})
+	defer onFulfilled.Release()
+
+	onRejected := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		defer waitGroup.Done()
+		err = jsErrorToGo(args[0])
+		return nil
+	})
+	defer onRejected.Release()
+
+	promise.Call("then", onFulfilled, onRejected)
+	waitGroup.Wait()
+
+	return result, err
+}
+
+var awaiter Awaiter = waitGroupAwaiter{}
+
+// SetAwaiter replaces the Awaiter used by the package, which is useful in WASM hosts where blocking a goroutine on a
+// WaitGroup doesn't let the host's event loop run, and so would deadlock. Passing nil restores the default Awaiter.
+// SetAwaiter is not safe to call while streams are in use, so it should be called during initialisation.
+func SetAwaiter(a Awaiter) {
+	if a == nil {
+		a = waitGroupAwaiter{}
+	}
+	awaiter = a
+}
+
+// opSlots holds a slot for each read or write waiting on JavaScript, if their number is limited, so that acquiring a slot
+// blocks once all of them are taken.
+var opSlots atomic.Pointer[chan struct{}]
+
+// SetMaxConcurrentOps limits how many reads and writes, across every stream in the package, may be waiting on JavaScript
+// at once, to cap the pressure on the bridge in an application with many concurrent streams. Any beyond the limit queue
+// until one finishes. Reads served from data already buffered in Go don't count. A limit that is not positive, which is
+// the default, removes the limit. Operations already in progress keep the slots they took under the old limit. The limit
+// must leave room for every operation another is waiting on, such as the write that feeds a read through a pipe, or they
+// deadlock.
+func SetMaxConcurrentOps(n int) {
+	if n <= 0 {
+		opSlots.Store(nil)
+		return
+	}
+	slots := make(chan struct{}, n)
+	opSlots.Store(&slots)
+}
+
+// acquireOp waits for a slot under the limit set with SetMaxConcurrentOps, returning a function that frees it again.
+func acquireOp() (release func()) {
+	slots := opSlots.Load()
+	if slots == nil {
+		return func() {}
+	}
+	*slots <- struct{}{}
+	return func() { <-*slots }
+}
+
+// await blocks until promise settles, using the package's Awaiter.
+func await(promise js.Value) (js.Value, error) {
+	return awaiter.Await(promise)
+}
+
+// jsErrorToGo converts the reason a Promise was rejected with to a StreamError. A reason is normally an Error, whose name
+// and message are kept, but it can be any value at all, such as a string, in which case the message is the reason
+// converted to a string, and there is no name.
+func jsErrorToGo(reason js.Value) error {
+	if reason.Type() == js.TypeObject && reason.InstanceOf(js.Global().Get("Error")) {
+		streamErr := &StreamError{Message: reason.Get("message").String()}
+		if name := reason.Get("name"); name.Type() == js.TypeString {
+			streamErr.Name = name.String()
+		}
+		return streamErr
+	}
+
+	return &StreamError{Message: js.Global().Call("String", reason).String()}
+}
+
+// goErrorToJS converts err to a JavaScript Error, for rejecting a Promise or erroring a stream from Go, so that JavaScript
+// code can inspect the failure through the Error's name and message. The name is taken from err, or the first error it
+// wraps, that has a Name method, and a StreamError keeps the name and message it came from JavaScript with, so an error
+// that makes the round trip comes back as it left; otherwise the name is Error.
+func goErrorToJS(err error) js.Value {
+	message, name := err.Error(), ""
+	var named interface{ Name() string }
+	if streamErr, ok := err.(*StreamError); ok {
+		message, name = streamErr.Message, streamErr.Name
+	} else if errors.As(err, &named) {
+		name = named.Name()
+	}
+
+	jsError := js.Global().Get("Error").New(message)
+	if name != "" && name != "Error" {
+		jsError.Set("name", name)
+	}
+	return jsError
+}
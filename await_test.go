@@ -0,0 +1,108 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"fmt"
+	"io"
+	"syscall/js"
+	"testing"
+	"testing/iotest"
+)
+
+type countingAwaiter struct {
+	calls int
+}
+
+func (c *countingAwaiter) Await(promise js.Value) (js.Value, error) {
+	c.calls++
+	return waitGroupAwaiter{}.Await(promise)
+}
+
+func TestSetAwaiter(t *testing.T) {
+	counter := &countingAwaiter{}
+	SetAwaiter(counter)
+	defer SetAwaiter(nil)
+
+	jsStream, sink := newTestWritableStream()
+	if _, err := NewWritableStream(jsStream).Write([]byte("Hello")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if string(sink.bytes()) != "Hello" {
+		t.Fatalf("sink received %q, want %q", sink.bytes(), "Hello")
+	}
+	if counter.calls == 0 {
+		t.Fatal("custom Awaiter was never called")
+	}
+}
+
+type quotaError struct{}
+
+func (quotaError) Error() string { return "storage is full" }
+func (quotaError) Name() string  { return "QuotaExceededError" }
+
+func TestGoErrorToJS(t *testing.T) {
+	for _, test := range []struct {
+		err           error
+		name, message string
+	}{
+		{io.ErrUnexpectedEOF, "Error", "unexpected EOF"},
+		{quotaError{}, "QuotaExceededError", "storage is full"},
+		{fmt.Errorf("writing: %w", quotaError{}), "QuotaExceededError", "writing: storage is full"},
+		{&StreamError{Name: "AbortError", Message: "aborted"}, "AbortError", "aborted"},
+	} {
+		jsError := goErrorToJS(test.err)
+		if !jsError.InstanceOf(js.Global().Get("Error")) {
+			t.Fatalf("goErrorToJS(%v) is not an Error", test.err)
+		}
+		if name, message := jsError.Get("name").String(), jsError.Get("message").String(); name != test.name || message != test.message {
+			t.Fatalf("goErrorToJS(%v) is %s: %s, want %s: %s", test.err, name, message, test.name, test.message)
+		}
+	}
+
+	// A named error from an io.Reader reaches JavaScript code reading the converted stream.
+	stream := ReaderToReadableStream(iotest.ErrReader(quotaError{}))
+	_, err := await(stream.Call("getReader").Call("read"))
+	if streamErr, ok := err.(*StreamError); !ok || streamErr.Name != "QuotaExceededError" || streamErr.Message != "storage is full" {
+		t.Fatalf("read returned %v, want QuotaExceededError: storage is full", err)
+	}
+}
+
+func TestSetMaxConcurrentOps(t *testing.T) {
+	SetMaxConcurrentOps(1)
+	defer SetMaxConcurrentOps(0)
+
+	// Each stream takes a while to produce its only chunk, keeping track of how many are producing at once.
+	state := js.Global().Get("Function").New(`
+		const state = { active: 0, most: 0 };
+		state.newStream = () => new ReadableStream({
+			pull(controller) {
+				state.most = Math.max(state.most, ++state.active);
+				return new Promise((resolve) => setTimeout(() => {
+					state.active--;
+					controller.enqueue(new Uint8Array([1]));
+					controller.close();
+					resolve();
+				}, 20));
+			},
+		}, { highWaterMark: 0 });
+		return state;
+	`).Invoke()
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		stream := NewReadableStream(state.Call("newStream"))
+		go func() {
+			_, err := stream.Read(make([]byte, 1))
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+	}
+	if most := state.Get("most").Int(); most != 1 {
+		t.Fatalf("%d reads ran at once, want 1", most)
+	}
+}
@@ -0,0 +1,83 @@
+package jsStreams
+
+import (
+	"encoding/base64"
+	"io"
+	"sync"
+)
+
+// base64Decoder decodes base64 text read from a stream.
+type base64Decoder struct {
+	io.Reader
+	source *ReadableStream
+}
+
+func (d *base64Decoder) Close() error {
+	return d.source.Close()
+}
+
+// Base64DecodeStream creates a ReadableStream that yields the bytes encoded by the standard, padded, base64 text read
+// from r, as used in data URLs. The text is decoded as it is read, and a group of 4 characters may be split across any
+// number of chunks. Newlines in the text are ignored. Closing the returned stream closes r.
+func Base64DecodeStream(r *ReadableStream) *ReadableStream {
+	return newGoReadableStream(&base64Decoder{
+		Reader: base64.NewDecoder(base64.StdEncoding, r),
+		source: r,
+	})
+}
+
+// base64Encoder encodes the bytes read from a stream as base64 text. Input is only encoded in groups of 3 bytes, with
+// any remainder carried over to the next read, so that padding only appears at the very end.
+type base64Encoder struct {
+	source  *ReadableStream
+	buffer  []byte
+	carry   []byte
+	pending []byte
+	eof     bool
+	lock    sync.Mutex
+}
+
+func (e *base64Encoder) Read(p []byte) (int, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for len(e.pending) == 0 {
+		if e.eof {
+			return 0, io.EOF
+		}
+
+		n, err := e.source.Read(e.buffer[len(e.carry):])
+		copy(e.buffer, e.carry)
+		input := e.buffer[:len(e.carry)+n]
+		if err == io.EOF {
+			e.eof = true
+		} else if err != nil {
+			return 0, err
+		} else {
+			whole := len(input) - len(input)%3
+			e.carry = append(e.carry[:0], input[whole:]...)
+			input = input[:whole]
+		}
+
+		e.pending = make([]byte, base64.StdEncoding.EncodedLen(len(input)))
+		base64.StdEncoding.Encode(e.pending, input)
+	}
+
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+func (e *base64Encoder) Close() error {
+	return e.source.Close()
+}
+
+// Base64EncodeStream creates a ReadableStream that yields the bytes read from r encoded as standard, padded, base64
+// text. The bytes are encoded as they are read, so the input never has to be held in memory all at once. Closing the
+// returned stream closes r.
+func Base64EncodeStream(r *ReadableStream) *ReadableStream {
+	return newGoReadableStream(&base64Encoder{
+		source: r,
+		buffer: make([]byte, defaultChunkSize),
+	})
+}
@@ -0,0 +1,106 @@
+package jsStreams
+
+import (
+	"io"
+	"testing"
+)
+
+// chunkReader returns one of its chunks per Read, so that tests control where chunk boundaries fall.
+type chunkReader struct {
+	chunks []string
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(c.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, c.chunks[0])
+	c.chunks[0] = c.chunks[0][n:]
+	if c.chunks[0] == "" {
+		c.chunks = c.chunks[1:]
+	}
+	return n, nil
+}
+
+// newChunkedStream creates a ReadableStream that yields each of chunks in turn.
+func newChunkedStream(chunks ...string) *ReadableStream {
+	return newGoReadableStream(io.NopCloser(&chunkReader{chunks: chunks}))
+}
+
+func TestBase64DecodeStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{"whole", []string{"SGVsbG8sIHdvcmxkIQ=="}, "Hello, world!"},
+		{"split group", []string{"SGVsbG", "8sIHdv", "cmxkIQ=="}, "Hello, world!"},
+		{"split padding", []string{"SGVsbG8sIHdvcmxkIQ=", "="}, "Hello, world!"},
+		{"newlines", []string{"SGVsbG8s\n", "IHdvcmxkIQ==\n"}, "Hello, world!"},
+		{"empty", nil, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := io.ReadAll(Base64DecodeStream(newChunkedStream(test.chunks...)))
+			if err != nil {
+				t.Fatalf("ReadAll returned error: %v", err)
+			}
+			if string(decoded) != test.want {
+				t.Fatalf("decoded %q, want %q", decoded, test.want)
+			}
+		})
+	}
+
+	t.Run("one byte per chunk", func(t *testing.T) {
+		decoded, err := io.ReadAll(Base64DecodeStream(newStringStream("SGVsbG8sIHdvcmxkIQ==")))
+		if err != nil {
+			t.Fatalf("ReadAll returned error: %v", err)
+		}
+		if string(decoded) != "Hello, world!" {
+			t.Fatalf("decoded %q, want %q", decoded, "Hello, world!")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := io.ReadAll(Base64DecodeStream(newChunkedStream("SGV", "s!bG8="))); err == nil {
+			t.Fatal("ReadAll returned no error for invalid base64")
+		}
+	})
+}
+
+func TestBase64EncodeStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{"whole", []string{"Hello, world!"}, "SGVsbG8sIHdvcmxkIQ=="},
+		{"split group", []string{"Hel", "lo, w", "o", "rld!"}, "SGVsbG8sIHdvcmxkIQ=="},
+		{"no padding", []string{"He", "llo"}, "SGVsbG8="},
+		{"empty", nil, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded, err := io.ReadAll(Base64EncodeStream(newChunkedStream(test.chunks...)))
+			if err != nil {
+				t.Fatalf("ReadAll returned error: %v", err)
+			}
+			if string(encoded) != test.want {
+				t.Fatalf("encoded %q, want %q", encoded, test.want)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		decoded, err := io.ReadAll(Base64DecodeStream(Base64EncodeStream(newStringStream("Hello, world!"))))
+		if err != nil {
+			t.Fatalf("ReadAll returned error: %v", err)
+		}
+		if string(decoded) != "Hello, world!" {
+			t.Fatalf("decoded %q, want %q", decoded, "Hello, world!")
+		}
+	})
+}
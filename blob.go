@@ -0,0 +1,194 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sync"
+	"syscall"
+	"syscall/js"
+	"time"
+)
+
+// smallBlobSize is the largest Blob BlobReader reads all at once with arrayBuffer, rather than through its stream.
+const smallBlobSize = defaultChunkSize
+
+// BlobReader creates a ReadableStream that reads the contents of a JavaScript Blob, or a File, such as one chosen through
+// a file input. Large blobs are read through the ReadableStream returned by their stream method, while small ones are read
+// with a single call to arrayBuffer, which avoids the overhead of a stream for the sake of one chunk. Either way, nothing
+// is read until the first Read. The Blob's size is set as the stream's expected length, for RemainingLength.
+func BlobReader(blob js.Value) *ReadableStream {
+	stream := newBlobStream(blob)
+	stream.SetExpectedLength(int64(blob.Get("size").Float()))
+	return stream
+}
+
+// newBlobStream creates the ReadableStream BlobReader returns, reading the Blob whichever way suits its size.
+func newBlobStream(blob js.Value) *ReadableStream {
+	if blob.Get("size").Int() > smallBlobSize {
+		return NewReadableStream(blob.Call("stream"))
+	}
+
+	return NewReadableStream(js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"pull": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			controller := args[0]
+			return blob.Call("arrayBuffer").Call("then", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				if args[0].Get("byteLength").Int() > 0 {
+					controller.Call("enqueue", js.Global().Get("Uint8Array").New(args[0]))
+				}
+				closeController(controller)
+				return nil
+			}))
+		}),
+		"type": "bytes",
+	}, map[string]interface{}{"highWaterMark": 0}))
+}
+
+// ToBlob reads the rest of the stream and returns its contents as a JavaScript Blob of the given MIME type, which may be
+// empty, ready to be handed to browser APIs that want one, such as URL.createObjectURL for a download. The stream is read
+// in chunks, each copied into a Uint8Array as it arrives and used as a part of the Blob, so the data is never held in Go
+// all at once. If mimeType is empty, the stream's ContentType is used. Reaching the end of the stream is not an error.
+func (r *ReadableStream) ToBlob(mimeType string) (blob js.Value, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	if mimeType == "" {
+		mimeType = r.ContentType()
+	}
+
+	parts := js.Global().Get("Array").New()
+	buffer := make([]byte, defaultChunkSize)
+	for {
+		n, err := r.Read(buffer)
+		if n > 0 {
+			part := uint8ArrayConstructor.New(n)
+			js.CopyBytesToJS(part, buffer[:n])
+			parts.Call("push", part)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return js.Undefined(), err
+		}
+	}
+
+	return js.Global().Get("Blob").New(parts, map[string]interface{}{"type": mimeType}), nil
+}
+
+// blobFile implements http.File over a Blob, reading each chunk asked for by slicing the Blob, so that it can seek freely.
+type blobFile struct {
+	blob js.Value
+	info blobFileInfo
+
+	lock   sync.Mutex
+	offset int64
+	closed bool
+}
+
+func (f *blobFile) Read(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	if f.offset >= f.info.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	end := min(f.offset+int64(len(p)), f.info.size)
+	buffer, err := await(f.blob.Call("slice", f.offset, end).Call("arrayBuffer"))
+	if err != nil {
+		return 0, err
+	}
+	n := js.CopyBytesToGo(p, uint8ArrayConstructor.New(buffer))
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *blobFile) Seek(offset int64, whence int) (int64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.size
+	case io.SeekStart:
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *blobFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+func (f *blobFile) Readdir(int) ([]fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: f.info.name, Err: syscall.ENOTDIR}
+}
+
+func (f *blobFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// blobFileInfo describes the Blob behind a blobFile as a read-only regular file.
+type blobFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i blobFileInfo) Name() string       { return i.name }
+func (i blobFileInfo) Size() int64        { return i.size }
+func (i blobFileInfo) Mode() fs.FileMode  { return 0o444 }
+func (i blobFileInfo) ModTime() time.Time { return i.modTime }
+func (i blobFileInfo) IsDir() bool        { return false }
+func (i blobFileInfo) Sys() interface{}   { return nil }
+
+// BlobToHTTPFile adapts a JavaScript Blob, or a File, such as one chosen through a file input, to an http.File, so that Go
+// code that serves files, such as http.ServeContent or an http.FileSystem in a service worker, can serve it, including
+// range requests. Each Read slices the Blob at the current offset and reads only that part, so Seek is cheap. Stat reports
+// the Blob's size, name as its name, or the File's own name if name is empty, and the File's lastModified time as its
+// modification time, or the zero time for a plain Blob. The file is not a directory, so Readdir always fails. Closing it
+// releases nothing, as the Blob is owned by JavaScript, but further reads fail with fs.ErrClosed.
+func BlobToHTTPFile(blob js.Value, name string) http.File {
+	if name == "" && blob.Get("name").Type() == js.TypeString {
+		name = blob.Get("name").String()
+	}
+	var modTime time.Time
+	if lastModified := blob.Get("lastModified"); lastModified.Type() == js.TypeNumber {
+		modTime = time.UnixMilli(int64(lastModified.Float()))
+	}
+	return &blobFile{
+		blob: blob,
+		info: blobFileInfo{name: name, size: int64(blob.Get("size").Float()), modTime: modTime},
+	}
+}
@@ -0,0 +1,144 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// newTestBlob creates an object standing in for a Blob holding data, recording which of its methods is used to read it.
+func newTestBlob(data string, used *string) js.Value {
+	blob := js.Global().Get("Object").New()
+	blob.Set("size", len(data))
+	blob.Set("stream", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		*used = "stream"
+		return newTestReadableStream([]byte(data))
+	}))
+	blob.Set("arrayBuffer", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		*used = "arrayBuffer"
+		buffer := js.Global().Get("Uint8Array").New(len(data))
+		js.CopyBytesToJS(buffer, []byte(data))
+		return js.Global().Get("Promise").Call("resolve", buffer.Get("buffer"))
+	}))
+	return blob
+}
+
+func TestBlobReader(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		data string
+		want string
+	}{
+		{"small", "Hello, world!", "arrayBuffer"},
+		{"empty", "", "arrayBuffer"},
+		{"large", strings.Repeat("x", smallBlobSize+1), "stream"},
+	} {
+		var used string
+		data, err := io.ReadAll(BlobReader(newTestBlob(test.data, &used)))
+		if err != nil || string(data) != test.data {
+			t.Fatalf("%s: ReadAll returned %d bytes and %v, want %d bytes and nil", test.name, len(data), err, len(test.data))
+		}
+		if used != test.want {
+			t.Fatalf("%s: Blob was read with %s, want %s", test.name, used, test.want)
+		}
+	}
+
+	blob := js.Global().Get("Blob").New([]interface{}{"Hello, ", "world!"})
+	stream := BlobReader(blob)
+	if remaining, ok := stream.RemainingLength(); remaining != 13 || !ok {
+		t.Fatalf("RemainingLength returned %d, %v, want 13, true", remaining, ok)
+	}
+	if data, err := io.ReadAll(stream); err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll of a real Blob returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+}
+
+func TestToBlob(t *testing.T) {
+	stream := NewReadableStream(newTestReadableStream([]byte("Hello, "), []byte("world!")))
+	blob, err := stream.ToBlob("text/plain")
+	if err != nil {
+		t.Fatalf("ToBlob returned error: %v", err)
+	}
+	if size := blob.Get("size").Int(); size != len("Hello, world!") {
+		t.Fatalf("Blob has size %d, want %d", size, len("Hello, world!"))
+	}
+	if mimeType := blob.Get("type").String(); mimeType != "text/plain" {
+		t.Fatalf("Blob has type %q, want %q", mimeType, "text/plain")
+	}
+
+	// Reading the Blob back gives the stream's contents.
+	data, err := io.ReadAll(BlobReader(blob))
+	if err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("reading the Blob back returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+
+	empty, err := NewReadableStream(newTestReadableStream()).ToBlob("")
+	if err != nil || empty.Get("size").Int() != 0 || empty.Get("type").String() != "" {
+		t.Fatalf("ToBlob of an empty stream returned a Blob of size %d and type %q, and %v, want 0, \"\" and nil",
+			empty.Get("size").Int(), empty.Get("type").String(), err)
+	}
+}
+
+func TestBlobToHTTPFile(t *testing.T) {
+	blob := js.Global().Get("File").New([]interface{}{"Hello, world!"}, "hello.txt",
+		map[string]interface{}{"lastModified": 1700000000000})
+	file := BlobToHTTPFile(blob, "")
+
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+	if info.Name() != "hello.txt" || info.Size() != 13 || info.IsDir() || !info.ModTime().Equal(time.UnixMilli(1700000000000)) {
+		t.Fatalf("Stat returned name %q, size %d, directory %v and modification time %v, want %q, 13, false and %v",
+			info.Name(), info.Size(), info.IsDir(), info.ModTime(), "hello.txt", time.UnixMilli(1700000000000))
+	}
+
+	buffer := make([]byte, 5)
+	if n, err := file.Read(buffer); n != 5 || err != nil || string(buffer) != "Hello" {
+		t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, "Hello")
+	}
+	if offset, err := file.Seek(-6, io.SeekEnd); offset != 7 || err != nil {
+		t.Fatalf("Seek returned %d, %v, want 7, nil", offset, err)
+	}
+	if data, err := io.ReadAll(file); string(data) != "world!" || err != nil {
+		t.Fatalf("ReadAll after Seek returned %q, %v, want %q, nil", data, err, "world!")
+	}
+	if _, err := file.Seek(-1, io.SeekStart); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("Seek to a negative offset returned %v, want %v", err, fs.ErrInvalid)
+	}
+	if _, err := file.Readdir(0); err == nil {
+		t.Fatal("Readdir returned no error")
+	}
+
+	// http.ServeContent can serve a range of it.
+	request := httptest.NewRequest("GET", "/hello.txt", nil)
+	request.Header.Set("Range", "bytes=7-11")
+	recorder := httptest.NewRecorder()
+	http.ServeContent(recorder, request, info.Name(), info.ModTime(), file)
+	if recorder.Code != http.StatusPartialContent || recorder.Body.String() != "world" {
+		t.Fatalf("ServeContent returned %d %q, want %d %q", recorder.Code, recorder.Body.String(),
+			http.StatusPartialContent, "world")
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := file.Read(buffer); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("Read after Close returned %v, want %v", err, fs.ErrClosed)
+	}
+
+	// A given name takes precedence, and a plain Blob has no modification time.
+	info, _ = BlobToHTTPFile(js.Global().Get("Blob").New([]interface{}{"data"}), "data.bin").Stat()
+	if info.Name() != "data.bin" || info.Size() != 4 || !info.ModTime().IsZero() {
+		t.Fatalf("Stat of a Blob returned name %q, size %d and modification time %v, want %q, 4 and the zero time",
+			info.Name(), info.Size(), info.ModTime(), "data.bin")
+	}
+}
@@ -0,0 +1,41 @@
+package jsStreams
+
+import (
+	"io"
+)
+
+// ReadableStreamToChannel starts a goroutine that reads r in chunks of up to chunkSize bytes, sending each of them on
+// the returned channel, which is closed once r reaches its end or a read fails. If chunkSize is not positive, the
+// default of 32 KiB is used. Every chunk is a new slice, so it can be kept by the receiver. This gives Go code a
+// select-friendly way to consume a stream.
+//
+// Errors are not delivered on the channel. Once it has been closed, r's WaitClosed returns the error that ended the
+// stream, or nil if it reached its end. The channel is unbuffered, so the goroutine, and the stream, are only released
+// once the channel has been received from until it is closed.
+func ReadableStreamToChannel(r *ReadableStream, chunkSize int) <-chan []byte {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		for {
+			chunk := make([]byte, chunkSize)
+			n, err := r.Read(chunk)
+			if n > 0 {
+				ch <- chunk[:n]
+			}
+			if err == io.EOF {
+				r.finished.finish(nil)
+				return
+			}
+			if err != nil {
+				// Not every error finishes the stream by itself, so we make sure WaitClosed sees it.
+				r.finished.finish(err)
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,49 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+// ChannelToReadableStream creates a JavaScript ReadableStream that yields each slice received from ch as a chunk, and
+// closes once ch is closed. The channel is only received from when the stream pulls, so a producer sending on an
+// unbuffered channel is held back until the consumer wants more. Empty slices are skipped, and the slices are copied,
+// so they can be reused once they have been received. Once the stream is cancelled it stops pulling, although a pull that
+// is already waiting on ch still receives, and discards, one more slice.
+func ChannelToReadableStream(ch <-chan []byte) js.Value {
+	return js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"pull": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			readController := args[0]
+			promise, resolve, reject := newPromise()
+			go func() {
+				defer func() {
+					// The stream may have been cancelled while we were waiting, in which case enqueue and close throw.
+					recovered := recover()
+					if recovered != nil {
+						reject.Invoke(js.Global().Get("Error").New(fmt.Sprint(recovered)))
+					}
+				}()
+
+				// The stream won't pull again until something is enqueued, so we have to keep receiving until we get data.
+				for {
+					chunk, ok := <-ch
+					if !ok {
+						closeController(readController)
+						break
+					}
+					if len(chunk) > 0 {
+						buffer := js.Global().Get("Uint8Array").New(len(chunk))
+						js.CopyBytesToJS(buffer, chunk)
+						readController.Call("enqueue", buffer)
+						break
+					}
+				}
+				resolve.Invoke()
+			}()
+			return promise
+		}),
+		"type": "bytes",
+	})
+}
@@ -0,0 +1,26 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"io"
+	"testing"
+)
+
+func TestChannelToReadableStream(t *testing.T) {
+	ch := make(chan []byte)
+	go func() {
+		for _, chunk := range []string{"Hello", ", ", "world!"} {
+			ch <- []byte(chunk)
+		}
+		close(ch)
+	}()
+
+	data, err := io.ReadAll(NewReadableStream(ChannelToReadableStream(ch)))
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, want %q", data, "Hello, world!")
+	}
+}
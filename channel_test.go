@@ -0,0 +1,43 @@
+package jsStreams
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadableStreamToChannel(t *testing.T) {
+	stream := newChunkedStream("Hello", ", ", "world!")
+
+	var data []byte
+	for chunk := range ReadableStreamToChannel(stream, 4) {
+		if len(chunk) > 4 {
+			t.Fatalf("received a chunk of %d bytes, want at most 4", len(chunk))
+		}
+		data = append(data, chunk...)
+	}
+	if string(data) != "Hello, world!" {
+		t.Fatalf("received %q, want %q", data, "Hello, world!")
+	}
+	if err := stream.WaitClosed(); err != nil {
+		t.Fatalf("WaitClosed returned error: %v", err)
+	}
+}
+
+func TestReadableStreamToChannelError(t *testing.T) {
+	lost := errors.New("connection lost")
+	stream := newGoReadableStream(io.NopCloser(io.MultiReader(strings.NewReader("Hello"), iotest.ErrReader(lost))))
+
+	var data []byte
+	for chunk := range ReadableStreamToChannel(stream, 0) {
+		data = append(data, chunk...)
+	}
+	if string(data) != "Hello" {
+		t.Fatalf("received %q, want %q", data, "Hello")
+	}
+	if err := stream.WaitClosed(); err == nil || !strings.Contains(err.Error(), lost.Error()) {
+		t.Fatalf("WaitClosed returned %v, want %v", err, lost)
+	}
+}
@@ -0,0 +1,71 @@
+package jsStreams
+
+import (
+	"errors"
+	"sync"
+)
+
+// coalescingWriter collects small writes into a buffer, writing it to a stream as a single chunk once it is full.
+type coalescingWriter struct {
+	dst       *WritableStream
+	threshold int
+	buffer    []byte
+	lock      sync.Mutex
+}
+
+func (c *coalescingWriter) Write(p []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// A write that is large enough on its own doesn't need to be copied into the buffer first.
+	if len(c.buffer) == 0 && len(p) >= c.threshold {
+		return c.dst.Write(p)
+	}
+
+	c.buffer = append(c.buffer, p...)
+	if len(c.buffer) >= c.threshold {
+		if err := c.flushLocked(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (c *coalescingWriter) Flush() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.flushLocked()
+}
+
+// flushLocked writes out the buffer, if there is anything in it. The caller must hold the writer's lock.
+func (c *coalescingWriter) flushLocked() error {
+	if len(c.buffer) == 0 {
+		return nil
+	}
+
+	_, err := c.dst.Write(c.buffer)
+	c.buffer = c.buffer[:0]
+	return err
+}
+
+func (c *coalescingWriter) Close() error {
+	return errors.Join(c.Flush(), c.dst.Close())
+}
+
+// CoalescingWriter creates a WritableStream that collects the data written to it in Go, writing it to w as a single
+// chunk once threshold bytes have built up, so that a producer making many small writes costs a fraction of the writes
+// to w. A write of threshold bytes or more on its own is passed straight through. If threshold is not positive, the
+// default of 32 KiB is used. Data is only written to w once there is enough of it, or when Flush or Close is called, so
+// a failure to write it may be reported by a later Write than the one that wrote it. Closing the returned stream writes
+// out what is left and closes w.
+func CoalescingWriter(w *WritableStream, threshold int) *WritableStream {
+	if threshold <= 0 {
+		threshold = defaultChunkSize
+	}
+	return newGoWritableStream(&coalescingWriter{
+		dst:       w,
+		threshold: threshold,
+		buffer:    make([]byte, 0, threshold),
+	})
+}
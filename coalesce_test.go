@@ -0,0 +1,58 @@
+package jsStreams
+
+import (
+	"testing"
+)
+
+func TestCoalescingWriter(t *testing.T) {
+	sink := &countingSink{}
+	stream := CoalescingWriter(newGoWritableStream(sink), 64)
+
+	for i := 0; i < 10; i++ {
+		if _, err := stream.Write([]byte("tiny")); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if sink.writes != 0 {
+		t.Fatalf("sink received %d writes before the threshold was reached, want 0", sink.writes)
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if sink.writes != 1 || sink.Len() != 40 {
+		t.Fatalf("sink received %d writes of %d bytes in total, want a single write of 40", sink.writes, sink.Len())
+	}
+	if !sink.closed {
+		t.Fatal("Close did not close the underlying stream")
+	}
+}
+
+func TestCoalescingWriterThreshold(t *testing.T) {
+	sink := &countingSink{}
+	stream := CoalescingWriter(newGoWritableStream(sink), 8)
+
+	// The buffer is written out as soon as it reaches the threshold, and on Flush.
+	for _, chunk := range []string{"one", "two", "three", "four"} {
+		if _, err := stream.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if sink.writes != 1 || sink.String() != "onetwothree" {
+		t.Fatalf("sink received %d writes of %q, want 1 of %q", sink.writes, sink.String(), "onetwothree")
+	}
+	if err := stream.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	if sink.writes != 2 || sink.String() != "onetwothreefour" {
+		t.Fatalf("after Flush, sink received %d writes of %q, want 2 of %q", sink.writes, sink.String(), "onetwothreefour")
+	}
+
+	// A write as large as the threshold goes straight through.
+	if _, err := stream.Write([]byte("a long chunk")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if sink.writes != 3 {
+		t.Fatalf("sink received %d writes, want 3", sink.writes)
+	}
+}
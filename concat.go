@@ -0,0 +1,85 @@
+package jsStreams
+
+import (
+	"io"
+	"sync"
+)
+
+// concatReader reads each of its streams in order, moving on to the next one once the current one reaches EOF. lock
+// serialises reads, while streamsLock guards streams, so that Close can cancel the active stream without waiting for a
+// read from it to return.
+type concatReader struct {
+	streams     []*ReadableStream
+	lock        sync.Mutex
+	streamsLock sync.Mutex
+}
+
+func (c *concatReader) Read(p []byte) (n int, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for {
+		stream := c.current()
+		if stream == nil {
+			return 0, io.EOF
+		}
+
+		n, err = stream.Read(p)
+		if err == io.EOF {
+			c.advance(stream)
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// current returns the active stream, or nil if every stream has been read or the reader has been closed.
+func (c *concatReader) current() *ReadableStream {
+	c.streamsLock.Lock()
+	defer c.streamsLock.Unlock()
+
+	if len(c.streams) == 0 {
+		return nil
+	}
+	return c.streams[0]
+}
+
+// advance moves on from stream once it has reached EOF, unless Close has already dropped it.
+func (c *concatReader) advance(stream *ReadableStream) {
+	c.streamsLock.Lock()
+	defer c.streamsLock.Unlock()
+
+	if len(c.streams) > 0 && c.streams[0] == stream {
+		c.streams = c.streams[1:]
+	}
+}
+
+// Close cancels the currently active stream, along with any streams that have not been read from yet. It doesn't wait
+// for a read in progress, which returns once its stream has been cancelled.
+func (c *concatReader) Close() (err error) {
+	c.streamsLock.Lock()
+	streams := c.streams
+	c.streams = nil
+	c.streamsLock.Unlock()
+
+	for _, stream := range streams {
+		closeErr := stream.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// ConcatReadableStreams creates a ReadableStream that is the logical concatenation of the provided streams. They are read
+// sequentially, with each stream being exhausted before moving on to the next, and the returned stream only closes once
+// the last one has. Any error returned by one of the streams is passed on to the reader. Closing the returned stream
+// cancels the currently active stream, as well as any that have not been reached yet. This is the ReadableStream
+// equivalent of io.MultiReader.
+func ConcatReadableStreams(streams ...*ReadableStream) *ReadableStream {
+	return newGoReadableStream(&concatReader{streams: append([]*ReadableStream(nil), streams...)})
+}
@@ -0,0 +1,113 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"io"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// newCancelRecordingStream creates a JavaScript ReadableStream that yields chunk and then waits, recording whether it
+// has been cancelled.
+func newCancelRecordingStream(chunk string) (js.Value, func() bool) {
+	state := js.Global().Get("Function").New("chunk", `
+		const state = { cancelled: false };
+		state.stream = new ReadableStream({
+			start(controller) { controller.enqueue(new TextEncoder().encode(chunk)); },
+			cancel() { state.cancelled = true; },
+		});
+		return state;
+	`).Invoke(chunk)
+	return state.Get("stream"), func() bool { return state.Get("cancelled").Bool() }
+}
+
+func TestConcatReadableStreamsOrder(t *testing.T) {
+	stream := ConcatReadableStreams(
+		NewReadableStream(newTestReadableStream([]byte("Hel"), []byte("lo"))),
+		NewReadableStream(newTestReadableStream()),
+		NewReadableStream(newTestReadableStream([]byte(", "))),
+		NewReadableStream(newTestReadableStream([]byte("wor"), []byte("ld"), []byte("!"))),
+	)
+
+	data, err := io.ReadAll(stream)
+	if err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+}
+
+func TestConcatReadableStreamsError(t *testing.T) {
+	failing := js.Global().Get("Function").New(`
+		return new ReadableStream({
+			start(controller) { controller.enqueue(new TextEncoder().encode("world")); },
+			pull(controller) { controller.error(new Error("source failed")); },
+		});
+	`).Invoke()
+	stream := ConcatReadableStreams(
+		NewReadableStream(newTestReadableStream([]byte("Hello, "))),
+		NewReadableStream(failing),
+		NewReadableStream(newTestReadableStream([]byte("!"))),
+	)
+
+	// The data before the error is delivered, then the error, and the streams after it aren't read.
+	data, err := io.ReadAll(stream)
+	if string(data) != "Hello, world" || err == nil || err.Error() != "source failed" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, %q", data, err, "Hello, world", "source failed")
+	}
+}
+
+func TestConcatReadableStreamsClose(t *testing.T) {
+	finished := NewReadableStream(newTestReadableStream([]byte("a")))
+	active, activeCancelled := newCancelRecordingStream("b")
+	pending, pendingCancelled := newCancelRecordingStream("c")
+	stream := ConcatReadableStreams(finished, NewReadableStream(active), NewReadableStream(pending))
+
+	// Read the whole of the first stream and the start of the second.
+	buffer := make([]byte, 1)
+	for _, want := range []string{"a", "b"} {
+		if n, err := stream.Read(buffer); err != nil || string(buffer[:n]) != want {
+			t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, want)
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if !activeCancelled() || !pendingCancelled() {
+		t.Fatalf("Close cancelled the active stream: %v, and the pending one: %v, want both", activeCancelled(),
+			pendingCancelled())
+	}
+}
+
+func TestConcatReadableStreamsCloseDuringRead(t *testing.T) {
+	active, activeCancelled := newCancelRecordingStream("a")
+	stream := ConcatReadableStreams(NewReadableStream(active))
+
+	buffer := make([]byte, 1)
+	if n, err := stream.Read(buffer); err != nil || string(buffer[:n]) != "a" {
+		t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, "a")
+	}
+
+	// The active stream has nothing more to give, so this read stalls until the stream is closed.
+	go func() {
+		_, _ = stream.Read(buffer)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- stream.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return while a read was pending")
+	}
+	if !activeCancelled() {
+		t.Fatal("Close did not cancel the active stream")
+	}
+}
@@ -0,0 +1,189 @@
+package jsStreams
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// streamAddr is the net.Addr a Conn returned by DuplexToConn reports when it wasn't given one.
+type streamAddr struct{}
+
+func (streamAddr) Network() string {
+	return "jsstreams"
+}
+
+func (streamAddr) String() string {
+	return "jsstreams"
+}
+
+// deadline is a read or write deadline that can be changed while a call is waiting on it, as net.Conn's can. cancel is
+// closed once the deadline passes, and only replaced once a new deadline is set after that, so a call waiting on it sees
+// every change made while it waits.
+type deadline struct {
+	lock   sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+func newDeadline() *deadline {
+	return &deadline{cancel: make(chan struct{})}
+}
+
+// set sets the deadline to t, closing cancel straight away if t has already passed. A zero t means no deadline.
+func (d *deadline) set(t time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		// The timer has fired, so wait for it to close cancel.
+		<-d.cancel
+	}
+	d.timer = nil
+
+	passed := false
+	select {
+	case <-d.cancel:
+		passed = true
+	default:
+	}
+
+	if t.IsZero() {
+		if passed {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+	if wait := time.Until(t); wait > 0 {
+		if passed {
+			d.cancel = make(chan struct{})
+		}
+		cancel := d.cancel
+		d.timer = time.AfterFunc(wait, func() {
+			close(cancel)
+		})
+		return
+	}
+	if !passed {
+		close(d.cancel)
+	}
+}
+
+// context returns a context that is done once the deadline, as it currently is or as it is later set to, passes, with
+// os.ErrDeadlineExceeded as its error.
+func (d *deadline) context() context.Context {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return deadlineContext{Context: context.Background(), done: d.cancel}
+}
+
+// deadlineContext is the context returned by deadline.context.
+type deadlineContext struct {
+	context.Context
+	done chan struct{}
+}
+
+func (c deadlineContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c deadlineContext) Err() error {
+	select {
+	case <-c.done:
+		return os.ErrDeadlineExceeded
+	default:
+		return nil
+	}
+}
+
+// DeadlineStream wraps a DuplexStream with read and write deadlines, like those of a net.Conn, for code that needs to time
+// out reads and writes but doesn't need a whole net.Conn, as DuplexToConn provides. Its methods are safe to call from any
+// goroutine.
+type DeadlineStream struct {
+	duplex *DuplexStream
+
+	readDeadline  *deadline
+	writeDeadline *deadline
+}
+
+// NewDeadlineStream wraps d in a DeadlineStream, with no deadlines set. A pair of separate streams can be wrapped by
+// putting them together in a DuplexStream.
+func NewDeadlineStream(d *DuplexStream) *DeadlineStream {
+	return &DeadlineStream{duplex: d, readDeadline: newDeadline(), writeDeadline: newDeadline()}
+}
+
+// Read reads from the readable side, like ReadableStream.Read, but returns os.ErrDeadlineExceeded once the read deadline
+// has passed, even if it was set while the Read was waiting. Data that arrives after a Read has given up isn't lost, but
+// returned by the next Read.
+func (s *DeadlineStream) Read(p []byte) (int, error) {
+	return s.duplex.ReadableStream.ReadContext(s.readDeadline.context(), p)
+}
+
+// Write writes to the writable side, like WritableStream.Write, but returns os.ErrDeadlineExceeded once the write deadline
+// has passed, even if it was set while the Write was waiting. A write that has given up may still reach the sink.
+func (s *DeadlineStream) Write(p []byte) (int, error) {
+	return s.duplex.WritableStream.writeContext(s.writeDeadline.context(), p)
+}
+
+// Close closes both sides of the wrapped DuplexStream.
+func (s *DeadlineStream) Close() error {
+	return s.duplex.Close()
+}
+
+// SetDeadline sets both the read and the write deadline, as net.Conn's SetDeadline does. A zero t means no deadline. A
+// deadline applies to a Read or Write that is already waiting as well as to later ones, so a deadline in the past
+// unblocks any pending call. It always returns nil.
+func (s *DeadlineStream) SetDeadline(t time.Time) error {
+	s.readDeadline.set(t)
+	s.writeDeadline.set(t)
+	return nil
+}
+
+// SetReadDeadline sets the read deadline, as net.Conn's SetReadDeadline does, including for a Read that is already
+// waiting. It always returns nil.
+func (s *DeadlineStream) SetReadDeadline(t time.Time) error {
+	s.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline sets the write deadline, as net.Conn's SetWriteDeadline does, including for a Write that is already
+// waiting. It always returns nil.
+func (s *DeadlineStream) SetWriteDeadline(t time.Time) error {
+	s.writeDeadline.set(t)
+	return nil
+}
+
+// duplexConn implements net.Conn over a DuplexStream.
+type duplexConn struct {
+	*DeadlineStream
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *duplexConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *duplexConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// DuplexToConn adapts d to a net.Conn, so that Go networking code, such as an HTTP client or a TLS connection, can run
+// over a pair of JavaScript streams unmodified, for instance a WebTransport bidirectional stream. localAddr and
+// remoteAddr are what LocalAddr and RemoteAddr report; either may be nil, in which case a placeholder address is used.
+// Deadlines are supported, including for a Read or Write that is already waiting when one is set, and a call that runs
+// into one returns os.ErrDeadlineExceeded. As with ReadContext, data read after a Read has given up isn't lost, but
+// returned by the next Read, and a write that has given up may still reach the sink.
+// Closing the Conn closes both sides of d.
+func DuplexToConn(d *DuplexStream, localAddr, remoteAddr net.Addr) net.Conn {
+	if localAddr == nil {
+		localAddr = streamAddr{}
+	}
+	if remoteAddr == nil {
+		remoteAddr = streamAddr{}
+	}
+	return &duplexConn{DeadlineStream: NewDeadlineStream(d), local: localAddr, remote: remoteAddr}
+}
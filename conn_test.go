@@ -0,0 +1,176 @@
+package jsStreams
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestConn(source io.Reader, sink *recordingSink) net.Conn {
+	duplex := &DuplexStream{
+		ReadableStream: newGoReadableStream(io.NopCloser(source)),
+		WritableStream: newGoWritableStream(sink),
+	}
+	return DuplexToConn(duplex, &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}, nil)
+}
+
+func TestDuplexToConn(t *testing.T) {
+	sink := &recordingSink{}
+	conn := newTestConn(&chunkReader{chunks: []string{"ping"}}, sink)
+
+	buffer := make([]byte, 16)
+	if n, err := conn.Read(buffer); err != nil || string(buffer[:n]) != "ping" {
+		t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, "ping")
+	}
+	if _, err := conn.Write([]byte("pong")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if sink.String() != "pong" {
+		t.Fatalf("sink received %q, want %q", sink.String(), "pong")
+	}
+
+	if conn.LocalAddr().String() != "127.0.0.1:1234" {
+		t.Fatalf("LocalAddr returned %v, want %v", conn.LocalAddr(), "127.0.0.1:1234")
+	}
+	if conn.RemoteAddr() == nil || conn.RemoteAddr().Network() == "" {
+		t.Fatalf("RemoteAddr returned %v, want a placeholder address", conn.RemoteAddr())
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if !sink.closed {
+		t.Fatal("Close did not close the writable side")
+	}
+}
+
+func TestDuplexToConnDeadline(t *testing.T) {
+	sink := &recordingSink{}
+	conn := newTestConn(&stallReader{stalled: make(chan struct{})}, sink)
+
+	if err := conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond)); err != nil {
+		t.Fatalf("SetReadDeadline returned error: %v", err)
+	}
+	_, err := conn.Read(make([]byte, 16))
+	var netErr net.Error
+	if !errors.Is(err, os.ErrDeadlineExceeded) || !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Read past the deadline returned %v, want a timeout", err)
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("SetDeadline returned error: %v", err)
+	}
+	if _, err := conn.Write([]byte("late")); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Write past the deadline returned %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	// Clearing the deadline lets writes through again.
+	if err := conn.SetWriteDeadline(time.Time{}); err != nil {
+		t.Fatalf("SetWriteDeadline returned error: %v", err)
+	}
+	if _, err := conn.Write([]byte("pong")); err != nil || sink.String() != "pong" {
+		t.Fatalf("Write returned %v, and the sink received %q, want nil and %q", err, sink.String(), "pong")
+	}
+}
+
+func TestDeadlineStream(t *testing.T) {
+	stream := NewDeadlineStream(&DuplexStream{
+		ReadableStream: newGoReadableStream(io.NopCloser(&stallReader{stalled: make(chan struct{})})),
+		WritableStream: newGoWritableStream(&recordingSink{}),
+	})
+
+	// A deadline that has already passed fails the Read straight away.
+	if err := stream.SetReadDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline returned error: %v", err)
+	}
+	_, err := stream.Read(make([]byte, 16))
+	var netErr net.Error
+	if !errors.Is(err, os.ErrDeadlineExceeded) || !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Read past the deadline returned %v, want a timeout", err)
+	}
+
+	// The write deadline is separate.
+	if _, err := stream.Write([]byte("pong")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+}
+
+func TestDeadlineStreamPendingRead(t *testing.T) {
+	stalled := make(chan struct{})
+	stream := NewDeadlineStream(&DuplexStream{
+		ReadableStream: newGoReadableStream(io.NopCloser(&stallReader{stalled: stalled})),
+		WritableStream: newGoWritableStream(&recordingSink{}),
+	})
+
+	// The Read starts with no deadline, and is unblocked by one set in the past, as net/http does to abort a read.
+	read := make(chan error, 1)
+	go func() {
+		_, err := stream.Read(make([]byte, 16))
+		read <- err
+	}()
+	<-stalled
+	if err := stream.SetReadDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline returned error: %v", err)
+	}
+	select {
+	case err := <-read:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("pending Read returned %v, want %v", err, os.ErrDeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending Read did not return once a past deadline was set")
+	}
+
+	// A deadline in the future set while a Read is waiting applies to it too.
+	if err := stream.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("SetReadDeadline returned error: %v", err)
+	}
+	go func() {
+		_, err := stream.Read(make([]byte, 16))
+		read <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := stream.SetDeadline(time.Now().Add(20 * time.Millisecond)); err != nil {
+		t.Fatalf("SetDeadline returned error: %v", err)
+	}
+	select {
+	case err := <-read:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("pending Read returned %v, want %v", err, os.ErrDeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending Read did not return once its deadline passed")
+	}
+}
+
+func TestDeadlineStreamPendingWrite(t *testing.T) {
+	// Nothing ever reads from the pipe, so the Write stalls.
+	pipeReader, pipeWriter := io.Pipe()
+	stream := NewDeadlineStream(&DuplexStream{
+		ReadableStream: newGoReadableStream(io.NopCloser(strings.NewReader(""))),
+		WritableStream: newGoWritableStream(pipeWriter),
+	})
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := stream.Write([]byte("Hello"))
+		written <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := stream.SetWriteDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("SetWriteDeadline returned error: %v", err)
+	}
+	select {
+	case err := <-written:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("pending Write returned %v, want %v", err, os.ErrDeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending Write did not return once a past deadline was set")
+	}
+	_ = pipeReader.Close()
+}
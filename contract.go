@@ -0,0 +1,41 @@
+package jsStreams
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DebugContractChecks makes streams check, on every Read and Write, that the io.Reader and io.Writer contracts are kept:
+// that n is never negative or more than len(p), that a Write which didn't write all of p returns an error, and that a
+// ReadableStream over a JavaScript stream only reports io.EOF once its data has been returned, with n == 0. This covers
+// the Go readers and writers wrapped in streams, such as by ReaderToReadableStream, as well as the streams' own methods,
+// so it catches a wrapper that miscounts what it read or wrote. It is a development aid: a ReadableStream or
+// WritableStream that breaks the contract panics with an error wrapping ErrContractViolation, while a Go reader or writer
+// that does so from JavaScript's side, where a panic would bring down the whole program, errors its stream with that
+// error instead. When it is false, which is the default, nothing is checked, and nothing is allocated for the checks. It
+// should not be changed while streams are in use.
+var DebugContractChecks bool
+
+// ErrContractViolation is wrapped by the errors DebugContractChecks reports.
+var ErrContractViolation = errors.New("io contract violated")
+
+// checkRead returns an error wrapping ErrContractViolation if n, returned by the Read method of reader for p, breaks the
+// io.Reader contract, or nil if it doesn't.
+func checkRead(reader interface{}, p []byte, n int) error {
+	if n < 0 || n > len(p) {
+		return fmt.Errorf("%w: %T.Read returned n = %d for a buffer of %d bytes", ErrContractViolation, reader, n, len(p))
+	}
+	return nil
+}
+
+// checkWrite returns an error wrapping ErrContractViolation if n and err, returned by the Write method of writer for p,
+// break the io.Writer contract, or nil if they don't.
+func checkWrite(writer interface{}, p []byte, n int, err error) error {
+	if n < 0 || n > len(p) {
+		return fmt.Errorf("%w: %T.Write returned n = %d for %d bytes", ErrContractViolation, writer, n, len(p))
+	}
+	if n < len(p) && err == nil {
+		return fmt.Errorf("%w: %T.Write wrote %d of %d bytes without an error", ErrContractViolation, writer, n, len(p))
+	}
+	return nil
+}
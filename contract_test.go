@@ -0,0 +1,55 @@
+package jsStreams
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// overstatingReader fills p, but claims to have read one byte more than it could hold.
+type overstatingReader struct{}
+
+func (overstatingReader) Read(p []byte) (int, error) {
+	return len(p) + 1, nil
+}
+
+// shortWriter only writes half of what it is given, without saying why.
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) {
+	return len(p) / 2, nil
+}
+
+func (shortWriter) Close() error {
+	return nil
+}
+
+// enableContractChecks sets DebugContractChecks for the rest of the test.
+func enableContractChecks(t *testing.T) {
+	DebugContractChecks = true
+	t.Cleanup(func() {
+		DebugContractChecks = false
+	})
+}
+
+func TestContractChecks(t *testing.T) {
+	p := make([]byte, 4)
+	for _, test := range []struct {
+		name      string
+		err       error
+		violation bool
+	}{
+		{"read", checkRead(nil, p, 4), false},
+		{"empty read", checkRead(nil, p, 0), false},
+		{"negative read", checkRead(nil, p, -1), true},
+		{"overstated read", checkRead(nil, p, 5), true},
+		{"write", checkWrite(nil, p, 4, nil), false},
+		{"failed write", checkWrite(nil, p, 2, io.ErrShortWrite), false},
+		{"short write", checkWrite(nil, p, 2, nil), true},
+		{"overstated write", checkWrite(nil, p, 5, nil), true},
+	} {
+		if violation := errors.Is(test.err, ErrContractViolation); violation != test.violation {
+			t.Errorf("%s: check returned %v, want a violation: %v", test.name, test.err, test.violation)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"syscall/js"
+)
+
+var (
+	// ErrInvalidIV is returned by reads from EncryptReadableStream and DecryptReadableStream if the algorithm doesn't have
+	// a 12-byte iv.
+	ErrInvalidIV = errors.New("algorithm must have a 12-byte iv")
+	// ErrInvalidCiphertext is returned by reads from DecryptReadableStream if the ciphertext isn't a sequence of frames
+	// produced by EncryptReadableStream with the same key and algorithm, or has been tampered with.
+	ErrInvalidCiphertext = errors.New("ciphertext is invalid or has been tampered with")
+)
+
+const (
+	// encryptedFrameSize is the most plaintext EncryptReadableStream puts in a single frame.
+	encryptedFrameSize = defaultChunkSize
+	// gcmTagSize is the size of the authentication tag AES-GCM appends to every frame's ciphertext by default, when the
+	// algorithm has no tagLength.
+	gcmTagSize = 16
+	// frameHeaderSize is the size of the header before every frame's ciphertext: a 4-byte big-endian length and a flag.
+	frameHeaderSize = 5
+)
+
+// The flags a frame can have, which are authenticated along with its ciphertext.
+const (
+	frameData  byte = 0
+	frameFinal byte = 1
+)
+
+// cryptoFrames derives the parameters each frame is encrypted or decrypted with, in order.
+type cryptoFrames struct {
+	key       js.Value
+	algorithm map[string]interface{}
+	iv        []byte
+	counter   uint64
+	// tagSize is the size in bytes of the authentication tag at the end of every frame's ciphertext.
+	tagSize uint32
+}
+
+func newCryptoFrames(key js.Value, algorithm map[string]interface{}) (*cryptoFrames, error) {
+	var iv []byte
+	switch value := algorithm["iv"].(type) {
+	case []byte:
+		iv = append([]byte(nil), value...)
+	case js.Value:
+		if array, ok := toUint8Array(value); ok {
+			iv = make([]byte, array.Length())
+			js.CopyBytesToGo(iv, array)
+		}
+	}
+	if len(iv) != 12 {
+		return nil, ErrInvalidIV
+	}
+
+	// The algorithm's tagLength is in bits.
+	tagSize := uint32(gcmTagSize)
+	switch value := algorithm["tagLength"].(type) {
+	case int:
+		tagSize = uint32(value / 8)
+	case float64:
+		tagSize = uint32(value / 8)
+	case js.Value:
+		if value.Type() == js.TypeNumber {
+			tagSize = uint32(value.Int() / 8)
+		}
+	}
+
+	return &cryptoFrames{key: key, algorithm: algorithm, iv: iv, tagSize: tagSize}, nil
+}
+
+// next returns the parameters for the next frame, which has the given flag. Every frame gets its own IV, made by XORing
+// the frame's index into the last 8 bytes of the algorithm's iv, so frames can't be reordered, and the flag is passed as
+// additional data, so the final frame can't be passed off as any other, and the end of the stream can't be cut off.
+func (c *cryptoFrames) next(flag byte) map[string]interface{} {
+	iv := append([]byte(nil), c.iv...)
+	counter := binary.BigEndian.Uint64(iv[4:]) ^ c.counter
+	binary.BigEndian.PutUint64(iv[4:], counter)
+	c.counter++
+
+	params := make(map[string]interface{}, len(c.algorithm))
+	for name, value := range c.algorithm {
+		params[name] = value
+	}
+	params["iv"] = bytesToJS(iv)
+	params["additionalData"] = bytesToJS([]byte{flag})
+	return params
+}
+
+// bytesToJS copies p into a new Uint8Array.
+func bytesToJS(p []byte) js.Value {
+	array := js.Global().Get("Uint8Array").New(len(p))
+	js.CopyBytesToJS(array, p)
+	return array
+}
+
+// subtleCrypto calls method, either "encrypt" or "decrypt", on the global SubtleCrypto, waiting for the result.
+func subtleCrypto(method string, params map[string]interface{}, key js.Value, data []byte) ([]byte, error) {
+	result, err := await(js.Global().Get("crypto").Get("subtle").Call(method, params, key, bytesToJS(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]byte, result.Get("byteLength").Int())
+	js.CopyBytesToGo(output, js.Global().Get("Uint8Array").New(result))
+	return output, nil
+}
+
+// encryptReader encrypts the data read from a stream, one frame per read from it.
+type encryptReader struct {
+	source  *ReadableStream
+	key     js.Value
+	frames  *cryptoFrames
+	err     error
+	buffer  []byte
+	pending []byte
+	done    bool
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	for len(e.pending) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+
+		n, err := e.source.Read(e.buffer)
+		if n > 0 {
+			e.err = e.encrypt(frameData, e.buffer[:n])
+		}
+		if e.err == nil && err == io.EOF && n == 0 {
+			e.done = true
+			e.err = e.encrypt(frameFinal, nil)
+		}
+		if e.err == nil && err != nil && err != io.EOF {
+			e.err = err
+		}
+		if e.err != nil {
+			return 0, e.err
+		}
+	}
+
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+// encrypt encrypts plaintext as the next frame, with the given flag, and queues it to be read.
+func (e *encryptReader) encrypt(flag byte, plaintext []byte) (err error) {
+	e.pending, err = encryptFrame(e.frames, e.key, flag, plaintext)
+	return err
+}
+
+// encryptFrame encrypts plaintext as the next of frames, with the given flag, returning the whole frame.
+func encryptFrame(frames *cryptoFrames, key js.Value, flag byte, plaintext []byte) ([]byte, error) {
+	ciphertext, err := subtleCrypto("encrypt", frames.next(flag), key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(ciphertext))
+	binary.BigEndian.PutUint32(frame, uint32(len(ciphertext)))
+	frame[4] = flag
+	return append(frame, ciphertext...), nil
+}
+
+func (e *encryptReader) Close() error {
+	return e.source.Close()
+}
+
+// EncryptReadableStream creates a ReadableStream that yields the data read from r, encrypted through WebCrypto's
+// SubtleCrypto with key, in frames that DecryptReadableStream can decrypt. algorithm is passed to subtle.encrypt, and
+// must be for AES-GCM, such as {"name": "AES-GCM", "iv": iv}, where iv is 12 bytes, either a []byte or a Uint8Array. The
+// same key and iv must never be used to encrypt more than one stream.
+//
+// SubtleCrypto can only encrypt a whole message at once, so r is split into frames of up to 32 KiB of plaintext, each of
+// which is encrypted separately, with its own IV derived from iv, and authenticated on its own. A frame is made up of a
+// 4-byte big-endian length, a flag marking the final frame, then the ciphertext, and the stream always ends with a final
+// frame, so that it can't be cut short without DecryptReadableStream noticing. If encryption fails, the error is passed on
+// to the reader. Closing the returned stream closes r.
+func EncryptReadableStream(r *ReadableStream, key js.Value, algorithm map[string]interface{}) *ReadableStream {
+	frames, err := newCryptoFrames(key, algorithm)
+	return newGoReadableStream(&encryptReader{
+		source: r,
+		key:    key,
+		frames: frames,
+		err:    err,
+		buffer: make([]byte, encryptedFrameSize),
+	})
+}
+
+// encryptWriter encrypts everything written to it, writing the frames to a stream.
+type encryptWriter struct {
+	dest   *WritableStream
+	key    js.Value
+	frames *cryptoFrames
+	err    error
+}
+
+func (e *encryptWriter) Write(p []byte) (n int, err error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	for n < len(p) {
+		size := len(p) - n
+		if size > encryptedFrameSize {
+			size = encryptedFrameSize
+		}
+		e.err = e.writeFrame(frameData, p[n:n+size])
+		if e.err != nil {
+			return n, e.err
+		}
+		n += size
+	}
+	return n, nil
+}
+
+// writeFrame encrypts plaintext as the next frame, with the given flag, and writes it as a single chunk.
+func (e *encryptWriter) writeFrame(flag byte, plaintext []byte) error {
+	frame, err := encryptFrame(e.frames, e.key, flag, plaintext)
+	if err != nil {
+		return err
+	}
+	_, err = e.dest.Write(frame)
+	return err
+}
+
+// Close writes the final frame, then closes the destination, which is closed even if the final frame can't be written.
+func (e *encryptWriter) Close() error {
+	err := e.err
+	if err == nil {
+		err = e.writeFrame(frameFinal, nil)
+	}
+
+	closeErr := e.dest.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// EncryptWritableStream creates a WritableStream that encrypts everything written to it through WebCrypto's SubtleCrypto
+// with key, and writes the ciphertext to w, in the same frames as EncryptReadableStream, so it can be decrypted by
+// DecryptReadableStream. algorithm is as for EncryptReadableStream, and the same key and iv must never be used to encrypt
+// more than one stream. Each write is encrypted straight away, as one frame, or several if it is larger than 32 KiB, and
+// each frame is written to w as a single chunk. Closing the returned stream writes the final frame and then closes w, so
+// a stream that isn't closed can't be decrypted in full. If encryption or a write fails, the error is returned by that
+// write and every one after it.
+func EncryptWritableStream(w *WritableStream, key js.Value, algorithm map[string]interface{}) *WritableStream {
+	frames, err := newCryptoFrames(key, algorithm)
+	return newGoWritableStream(&encryptWriter{dest: w, key: key, frames: frames, err: err})
+}
+
+// decryptReader decrypts a stream of frames produced by an encryptReader.
+type decryptReader struct {
+	source  *ReadableStream
+	key     js.Value
+	frames  *cryptoFrames
+	err     error
+	pending []byte
+	done    bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	for len(d.pending) == 0 {
+		d.err = d.decrypt()
+		if d.err != nil {
+			return 0, d.err
+		}
+	}
+
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// decrypt reads and decrypts the next frame, queueing its plaintext to be read, or returns io.EOF once the final frame has
+// been read and nothing follows it.
+func (d *decryptReader) decrypt() error {
+	var header [frameHeaderSize]byte
+	_, err := d.source.ReadFull(header[:])
+	if d.done {
+		if err == io.EOF {
+			return io.EOF
+		}
+		if err == nil || err == io.ErrUnexpectedEOF {
+			// Nothing is allowed after the final frame.
+			return ErrInvalidCiphertext
+		}
+		return err
+	}
+	if err == io.EOF {
+		// The stream was cut short before its final frame.
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(header[:4])
+	flag := header[4]
+	tagSize := d.frames.tagSize
+	if size < tagSize || size > encryptedFrameSize+tagSize || (flag != frameData && flag != frameFinal) {
+		return ErrInvalidCiphertext
+	}
+
+	ciphertext := make([]byte, size)
+	_, err = d.source.ReadFull(ciphertext)
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := subtleCrypto("decrypt", d.frames.next(flag), d.key, ciphertext)
+	if err != nil {
+		return ErrInvalidCiphertext
+	}
+	d.pending = plaintext
+	d.done = flag == frameFinal
+	return nil
+}
+
+func (d *decryptReader) Close() error {
+	return d.source.Close()
+}
+
+// DecryptReadableStream creates a ReadableStream that yields the plaintext of r, which must be a stream of frames
+// produced by EncryptReadableStream, decrypted through WebCrypto's SubtleCrypto with the same key and algorithm.
+//
+// Each frame is decrypted, and authenticated, as soon as all of it has been read, so at most one frame of up to 32 KiB is
+// buffered at a time, and no plaintext is ever returned from a frame that fails authentication. However, plaintext from
+// earlier frames may already have been returned by the time a later one fails, so it must not be trusted until the
+// stream has been read to the end without error. If any frame is invalid or has been tampered with, reads return
+// ErrInvalidCiphertext, and if the stream ends before its final frame, they return io.ErrUnexpectedEOF. Closing the
+// returned stream closes r.
+func DecryptReadableStream(r *ReadableStream, key js.Value, algorithm map[string]interface{}) *ReadableStream {
+	frames, err := newCryptoFrames(key, algorithm)
+	return newGoReadableStream(&decryptReader{source: r, key: key, frames: frames, err: err})
+}
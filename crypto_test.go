@@ -0,0 +1,130 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"bytes"
+	"io"
+	"syscall/js"
+	"testing"
+)
+
+func newTestKey(t *testing.T) js.Value {
+	t.Helper()
+
+	key, err := await(js.Global().Get("crypto").Get("subtle").Call("generateKey",
+		map[string]interface{}{"name": "AES-GCM", "length": 256}, false, []interface{}{"encrypt", "decrypt"}))
+	if err != nil {
+		t.Fatalf("generateKey returned error: %v", err)
+	}
+	return key
+}
+
+func encryptForTest(t *testing.T, key js.Value, algorithm map[string]interface{}, plaintext []byte) []byte {
+	t.Helper()
+
+	ciphertext, err := io.ReadAll(EncryptReadableStream(newGoReadableStream(io.NopCloser(bytes.NewReader(plaintext))), key, algorithm))
+	if err != nil {
+		t.Fatalf("reading EncryptReadableStream returned error: %v", err)
+	}
+	return ciphertext
+}
+
+func decryptForTest(key js.Value, algorithm map[string]interface{}, ciphertext []byte) ([]byte, error) {
+	return io.ReadAll(DecryptReadableStream(newGoReadableStream(io.NopCloser(bytes.NewReader(ciphertext))), key, algorithm))
+}
+
+func TestEncryptDecryptReadableStream(t *testing.T) {
+	key := newTestKey(t)
+	algorithm := map[string]interface{}{"name": "AES-GCM", "iv": []byte("0123456789ab")}
+
+	for _, size := range []int{0, 5, encryptedFrameSize, 3*encryptedFrameSize + 5} {
+		plaintext := bytes.Repeat([]byte("x"), size)
+		ciphertext := encryptForTest(t, key, algorithm, plaintext)
+		if bytes.Contains(ciphertext, []byte("xxxxx")) {
+			t.Fatalf("%d bytes: ciphertext contains the plaintext", size)
+		}
+
+		decrypted, err := decryptForTest(key, algorithm, ciphertext)
+		if err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("%d bytes: decrypting returned %d bytes and %v, want %d bytes and nil", size, len(decrypted), err, size)
+		}
+	}
+}
+
+func TestEncryptDecryptReadableStreamTagLength(t *testing.T) {
+	key := newTestKey(t)
+	for _, tagLength := range []interface{}{96, 32.0, js.ValueOf(64)} {
+		algorithm := map[string]interface{}{"name": "AES-GCM", "iv": []byte("0123456789ab"), "tagLength": tagLength}
+
+		// An empty final frame is only its tag, which is shorter than the default.
+		for _, size := range []int{0, encryptedFrameSize + 5} {
+			plaintext := bytes.Repeat([]byte("x"), size)
+			decrypted, err := decryptForTest(key, algorithm, encryptForTest(t, key, algorithm, plaintext))
+			if err != nil || !bytes.Equal(decrypted, plaintext) {
+				t.Fatalf("tagLength %v, %d bytes: decrypting returned %d bytes and %v, want %d bytes and nil", tagLength,
+					size, len(decrypted), err, size)
+			}
+		}
+	}
+}
+
+func TestDecryptReadableStreamInvalid(t *testing.T) {
+	key := newTestKey(t)
+	algorithm := map[string]interface{}{"name": "AES-GCM", "iv": []byte("0123456789ab")}
+	plaintext := bytes.Repeat([]byte("x"), encryptedFrameSize+5)
+	ciphertext := encryptForTest(t, key, algorithm, plaintext)
+	firstFrame := frameHeaderSize + encryptedFrameSize + gcmTagSize
+
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[frameHeaderSize] ^= 1
+	otherIV := map[string]interface{}{"name": "AES-GCM", "iv": []byte("ba9876543210")}
+
+	tests := []struct {
+		name       string
+		algorithm  map[string]interface{}
+		ciphertext []byte
+		want       error
+	}{
+		{"tampered", algorithm, tampered, ErrInvalidCiphertext},
+		{"wrong iv", otherIV, ciphertext, ErrInvalidCiphertext},
+		{"missing final frame", algorithm, ciphertext[:len(ciphertext)-frameHeaderSize-gcmTagSize], io.ErrUnexpectedEOF},
+		{"cut short", algorithm, ciphertext[:firstFrame-1], io.ErrUnexpectedEOF},
+		{"reordered", algorithm, append(append([]byte(nil), ciphertext[firstFrame:]...), ciphertext[:firstFrame]...), ErrInvalidCiphertext},
+		{"trailing data", algorithm, append(append([]byte(nil), ciphertext...), ciphertext...), ErrInvalidCiphertext},
+		{"no iv", map[string]interface{}{"name": "AES-GCM"}, ciphertext, ErrInvalidIV},
+	}
+
+	// Errors from a Go source come back through JavaScript, so only their messages survive.
+	for _, test := range tests {
+		if _, err := decryptForTest(key, test.algorithm, test.ciphertext); err == nil || err.Error() != test.want.Error() {
+			t.Errorf("%s: decrypting returned %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestEncryptWritableStream(t *testing.T) {
+	key := newTestKey(t)
+	algorithm := map[string]interface{}{"name": "AES-GCM", "iv": []byte("0123456789ab")}
+
+	var ciphertext recordingSink
+	stream := EncryptWritableStream(newGoWritableStream(&ciphertext), key, algorithm)
+	var plaintext []byte
+	for _, write := range [][]byte{[]byte("Hello, "), bytes.Repeat([]byte("x"), 2*encryptedFrameSize+1), []byte("world!")} {
+		if _, err := stream.Write(write); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		plaintext = append(plaintext, write...)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if !ciphertext.closed {
+		t.Fatal("closing the stream didn't close the destination")
+	}
+
+	decrypted, err := decryptForTest(key, algorithm, ciphertext.Bytes())
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("decrypting returned %d bytes and %v, want %d bytes and nil", len(decrypted), err, len(plaintext))
+	}
+}
@@ -0,0 +1,67 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"io"
+	"syscall/js"
+)
+
+// AutoDecompressStream sniffs the first bytes of r for a gzip or zlib header, and if it finds one, returns a stream that
+// yields r decompressed by a JavaScript DecompressionStream, or otherwise returns r itself, unchanged, so that callers
+// needn't know in advance whether data is compressed. A stream too short to hold a header is taken to be uncompressed.
+// The sniffed bytes aren't lost either way: they are the first returned by the next read of r, and the first fed to the
+// DecompressionStream. Once it has been sniffed, a compressed r belongs to the returned stream, and is closed when that
+// stream is cancelled. If the runtime has no DecompressionStream, AutoDecompressStream returns errors.ErrUnsupported for
+// a compressed r.
+func AutoDecompressStream(r *ReadableStream) (*ReadableStream, error) {
+	header, err := r.sniff(2)
+	if err != nil {
+		return nil, err
+	}
+
+	var format string
+	switch {
+	case len(header) < 2:
+		return r, nil
+	case header[0] == 0x1f && header[1] == 0x8b:
+		format = "gzip"
+	case header[0]&0x0f == 8 && header[0]>>4 <= 7 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0:
+		// A zlib header: the deflate method, a window of at most 32 KiB, and a check that makes it a multiple of 31.
+		format = "deflate"
+	default:
+		return r, nil
+	}
+
+	decompression := js.Global().Get("DecompressionStream")
+	if decompression.IsUndefined() {
+		return nil, errors.ErrUnsupported
+	}
+	// The sniffed bytes are held in Go, so the data is fed to the DecompressionStream through r's Read, not by piping the
+	// JavaScript stream under r.
+	return newGoReadableStream(r).PipeThrough(NewTransformStream(decompression.New(format)))
+}
+
+// sniff reads up to n bytes from the start of the stream without consuming them, so that they are returned again by the
+// next read. Fewer than n are returned only if the stream ends first.
+func (r *ReadableStream) sniff(n int) ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	header := make([]byte, n)
+	var read int
+	for read < n {
+		m, err := r.readHeld(header[read:])
+		read += m
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.unread(header[:read])
+			return nil, err
+		}
+	}
+	r.unread(header[:read])
+	return header[:read], nil
+}
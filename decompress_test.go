@@ -0,0 +1,45 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+)
+
+func TestAutoDecompressStream(t *testing.T) {
+	message := "Hello, world! Hello, world! Hello, world!"
+	var gzipped, zlibbed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write([]byte(message))
+	gzipWriter.Close()
+	zlibWriter := zlib.NewWriter(&zlibbed)
+	zlibWriter.Write([]byte(message))
+	zlibWriter.Close()
+
+	for _, test := range []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{"gzip", []string{gzipped.String()}, message},
+		// The header is split across chunks.
+		{"gzip split", []string{gzipped.String()[:1], gzipped.String()[1:]}, message},
+		{"zlib", []string{zlibbed.String()}, message},
+		{"plain", []string{message}, message},
+		{"short", []string{"\x1f"}, "\x1f"},
+		{"empty", nil, ""},
+	} {
+		stream, err := AutoDecompressStream(newChunkedStream(test.chunks...))
+		if err != nil {
+			t.Fatalf("%s: AutoDecompressStream returned error: %v", test.name, err)
+		}
+		data, err := io.ReadAll(stream)
+		if err != nil || string(data) != test.want {
+			t.Fatalf("%s: ReadAll returned %q, %v, want %q, nil", test.name, data, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package jsStreams
+
+import (
+	"errors"
+)
+
+// DuplexStream implements io.ReadWriteCloser for a pair of JavaScript streams, a ReadableStream and a WritableStream,
+// that together form a single bidirectional connection, such as a WebTransport bidirectional stream.
+type DuplexStream struct {
+	*ReadableStream
+	*WritableStream
+}
+
+// Close closes both sides of the DuplexStream. Both sides are always closed, and if either of them fails to close, the
+// errors are joined together and returned.
+func (d *DuplexStream) Close() error {
+	return errors.Join(d.ReadableStream.Close(), d.WritableStream.Close())
+}
+
+// CloseWrite closes only the writable side of the DuplexStream, like the half-close of a TCP connection, blocking until
+// everything written to it has been flushed and the sink has closed, which signals EOF to the peer. The readable side is
+// left open, so that a reply can still be read, for instance after sending a whole request over a bidirectional stream.
+// Once CloseWrite has been called, Write returns io.ErrClosedPipe. It returns an error if the sink fails to close.
+func (d *DuplexStream) CloseWrite() error {
+	return d.WritableStream.Close()
+}
+
+// Shutdown tears down both sides of the DuplexStream gracefully, like the half-closes of a TCP connection: the writable
+// side is closed first, flushing everything written to it, then the readable side is cancelled, telling the peer we have
+// stopped reading. Unlike Close, nothing is cancelled until the writes have been flushed. Both sides are always torn down,
+// and if either of them fails, the errors are joined together and returned.
+func (d *DuplexStream) Shutdown() error {
+	writeErr := d.CloseWrite()
+	return errors.Join(writeErr, d.ReadableStream.Close())
+}
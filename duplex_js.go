@@ -0,0 +1,89 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// NewDuplexStream creates a new DuplexStream from a JavaScript ReadableStream and a JavaScript WritableStream.
+func NewDuplexStream(readable js.Value, writable js.Value) *DuplexStream {
+	return &DuplexStream{
+		ReadableStream: NewReadableStream(readable),
+		WritableStream: NewWritableStream(writable),
+	}
+}
+
+// Abort tears down both sides of the DuplexStream after a failure, like resetting a TCP connection: the writable side is
+// aborted with reason, discarding anything written to it that hasn't reached the sink, and the readable side is cancelled
+// with it, so that both peers see why. Afterwards, WaitClosed on the writable side returns an error with reason as its
+// message. Both sides are always torn down, and if either of them fails, the errors are joined together and returned.
+func (d *DuplexStream) Abort(reason string) error {
+	err := errors.New(reason)
+	jsReason := goErrorToJS(err)
+	writeErr := d.WritableStream.abort(jsReason, err)
+	return errors.Join(writeErr, d.ReadableStream.cancel(jsReason))
+}
+
+// ReadWriteCloserToDuplex converts an io.ReadWriteCloser to a pair of JavaScript streams, the other way round from
+// NewDuplexStream, so that Go code can hand a connection, such as an in-memory one, to JavaScript code that expects a
+// duplex. The ReadableStream yields what is read from rwc, and what is written to the WritableStream is written to rwc.
+// rwc is closed once both sides have finished: the readable once it has been read to the end, has failed or has been
+// cancelled, and the writable once it has been closed, has failed or has been aborted. If the writable is the last side
+// to finish by being closed, the error from closing rwc rejects the close.
+func ReadWriteCloserToDuplex(rwc io.ReadWriteCloser) (readable js.Value, writable js.Value) {
+	closer := &duplexCloser{rwc: rwc}
+	closer.remaining.Store(2)
+
+	reader := &endNotifier{Reader: rwc, onEnd: closer.readDone}
+	readable = readerToReadableStream(context.Background(), reader, 0, []func(){closer.readDone}, nil, nil, 0)
+	writable = writerToWritableStream(context.Background(), rwc, closer.writeDone, func(error) { _ = closer.writeDone() }, 0)
+	return readable, writable
+}
+
+// duplexCloser closes an io.ReadWriteCloser once both of the streams made from it by ReadWriteCloserToDuplex have
+// finished.
+type duplexCloser struct {
+	rwc       io.ReadWriteCloser
+	remaining atomic.Int32
+	readOnce  sync.Once
+	writeOnce sync.Once
+}
+
+// readDone marks the readable side as finished.
+func (c *duplexCloser) readDone() {
+	c.readOnce.Do(func() { _ = c.finish() })
+}
+
+// writeDone marks the writable side as finished, returning the error from closing rwc if this closed it.
+func (c *duplexCloser) writeDone() (err error) {
+	c.writeOnce.Do(func() { err = c.finish() })
+	return err
+}
+
+// finish closes rwc once it has been called for both sides.
+func (c *duplexCloser) finish() error {
+	if c.remaining.Add(-1) > 0 {
+		return nil
+	}
+	return c.rwc.Close()
+}
+
+// endNotifier calls onEnd the first time a Read returns an error, including io.EOF.
+type endNotifier struct {
+	io.Reader
+	onEnd func()
+}
+
+func (e *endNotifier) Read(p []byte) (int, error) {
+	n, err := e.Reader.Read(p)
+	if err != nil {
+		e.onEnd()
+	}
+	return n, err
+}
@@ -0,0 +1,191 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestDuplexStream(t *testing.T) {
+	writable, sink := newTestWritableStream()
+	var duplex io.ReadWriteCloser = NewDuplexStream(newTestReadableStream([]byte("ping")), writable)
+
+	data, err := io.ReadAll(duplex)
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if string(data) != "ping" {
+		t.Fatalf("ReadAll returned %q, want %q", data, "ping")
+	}
+
+	if _, err := duplex.Write([]byte("pong")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if string(sink.bytes()) != "pong" {
+		t.Fatalf("sink received %q, want %q", sink.bytes(), "pong")
+	}
+
+	if err := duplex.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := duplex.Read(make([]byte, 1)); err != io.ErrClosedPipe {
+		t.Fatalf("Read after Close returned %v, want %v", err, io.ErrClosedPipe)
+	}
+	if _, err := duplex.Write([]byte("pong")); err != io.ErrClosedPipe {
+		t.Fatalf("Write after Close returned %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestDuplexStreamCloseWrite(t *testing.T) {
+	writable, sink := newTestWritableStream()
+	duplex := NewDuplexStream(newTestReadableStream([]byte("re"), []byte("ply")), writable)
+
+	if _, err := duplex.Write([]byte("request")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := duplex.CloseWrite(); err != nil {
+		t.Fatalf("CloseWrite returned error: %v", err)
+	}
+	if !sink.closed || string(sink.bytes()) != "request" {
+		t.Fatalf("sink received %q and was closed: %v, want %q and true", sink.bytes(), sink.closed, "request")
+	}
+	if _, err := duplex.Write([]byte("more")); err != io.ErrClosedPipe {
+		t.Fatalf("Write after CloseWrite returned %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	// The readable side is still open.
+	data, err := io.ReadAll(duplex)
+	if err != nil || string(data) != "reply" {
+		t.Fatalf("ReadAll after CloseWrite returned %q, %v, want %q, nil", data, err, "reply")
+	}
+	if err := duplex.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+}
+
+func TestReadWriteCloserToDuplex(t *testing.T) {
+	local, remote := net.Pipe()
+	readable, writable := ReadWriteCloserToDuplex(local)
+
+	// The remote end of the pipe answers every message.
+	go func() {
+		buffer := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(remote, buffer); err != nil {
+				return
+			}
+			if _, err := remote.Write(append([]byte("re:"), buffer...)); err != nil {
+				return
+			}
+		}
+	}()
+
+	reader := NewReadableStream(readable)
+	writer := NewWritableStream(writable)
+	for _, message := range []string{"ping", "pong"} {
+		if _, err := writer.Write([]byte(message)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		reply := make([]byte, 3+len(message))
+		if _, err := io.ReadFull(reader, reply); err != nil {
+			t.Fatalf("ReadFull returned error: %v", err)
+		}
+		if string(reply) != "re:"+message {
+			t.Fatalf("read %q, want %q", reply, "re:"+message)
+		}
+	}
+
+	// Closing one side leaves the connection open for the other.
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	_ = remote.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := remote.Read(make([]byte, 1)); err == io.EOF {
+		t.Fatal("closing the writable closed the connection")
+	}
+	_ = remote.SetReadDeadline(time.Time{})
+
+	// Closing both closes it.
+	if err := reader.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := remote.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("Read from the remote end returned %v once both sides were closed, want %v", err, io.EOF)
+	}
+}
+
+// newRecordingDuplex creates a DuplexStream over a pair of JavaScript streams that record, in order, how they were torn
+// down. The writable side's sink takes a while over every write, so that writes are still queued when it is torn down.
+func newRecordingDuplex() (*DuplexStream, func() []string) {
+	mock := js.Global().Get("Function").New(`
+		const events = [];
+		const readable = new ReadableStream({
+			cancel(reason) { events.push("cancel: " + reason); },
+		});
+		const writable = new WritableStream({
+			write(chunk) {
+				return new Promise((resolve) => setTimeout(() => {
+					events.push("write: " + new TextDecoder().decode(chunk));
+					resolve();
+				}, 10));
+			},
+			close() { events.push("close"); },
+			abort(reason) { events.push("abort: " + reason); },
+		});
+		return { readable, writable, events };
+	`).Invoke()
+	duplex := NewDuplexStream(mock.Get("readable"), mock.Get("writable"))
+	return duplex, func() []string {
+		var events []string
+		for i := 0; i < mock.Get("events").Length(); i++ {
+			events = append(events, mock.Get("events").Index(i).String())
+		}
+		return events
+	}
+}
+
+func TestDuplexStreamShutdown(t *testing.T) {
+	duplex, events := newRecordingDuplex()
+	if _, err := duplex.Write([]byte("bye")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := duplex.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+
+	// The write is flushed and the writable closed before the readable is cancelled.
+	want := []string{"write: bye", "close", "cancel: undefined"}
+	if got := events(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("streams were torn down with %q, want %q", got, want)
+	}
+	if err := duplex.WritableStream.WaitClosed(); err != nil {
+		t.Fatalf("WaitClosed returned error: %v", err)
+	}
+}
+
+func TestDuplexStreamAbort(t *testing.T) {
+	duplex, events := newRecordingDuplex()
+	if err := duplex.Abort("protocol error"); err != nil {
+		t.Fatalf("Abort returned error: %v", err)
+	}
+
+	// Both sides see the reason, and the writable isn't closed.
+	want := []string{"abort: Error: protocol error", "cancel: Error: protocol error"}
+	if got := events(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("streams were torn down with %q, want %q", got, want)
+	}
+	if err := duplex.WritableStream.WaitClosed(); err == nil || err.Error() != "protocol error" {
+		t.Fatalf("WaitClosed returned %v, want %q", err, "protocol error")
+	}
+	if _, err := duplex.Write([]byte("late")); err != io.ErrClosedPipe {
+		t.Fatalf("Write after Abort returned %v, want %v", err, io.ErrClosedPipe)
+	}
+	if err := duplex.Close(); err != nil {
+		t.Fatalf("Close after Abort returned error: %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package jsStreams
+
+// StreamError is the error returned when a JavaScript stream operation is rejected, such as a read from an errored
+// stream or a write the sink refused. It keeps the name of the JavaScript error alongside its message, so that specific
+// failures can be told apart, for instance a write rejected with a QuotaExceededError because storage is full. Name is
+// empty if the rejection reason had no name.
+type StreamError struct {
+	Name    string
+	Message string
+}
+
+// Error returns the error's message, prefixed by its name unless it is a plain Error.
+func (e *StreamError) Error() string {
+	if e.Name == "" || e.Name == "Error" {
+		return e.Message
+	}
+	return e.Name + ": " + e.Message
+}
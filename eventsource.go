@@ -0,0 +1,105 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"syscall/js"
+)
+
+// ErrEventSourceFailed is returned by a stream created by EventSourceStream once its EventSource has failed for good,
+// and won't reconnect.
+var ErrEventSourceFailed = errors.New("EventSource connection failed")
+
+// eventSourceClosed is the readyState of an EventSource that has failed or been closed.
+const eventSourceClosed = 2
+
+// eventQueue holds the events received by an EventSource until they are read.
+type eventQueue struct {
+	lock   sync.Mutex
+	events []string
+	err    error
+	// ready is closed, and replaced, whenever an event arrives or the queue ends, to wake up a waiting read.
+	ready chan struct{}
+}
+
+// push adds an event to the queue, unless it has ended.
+func (q *eventQueue) push(data string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.err != nil {
+		return
+	}
+	q.events = append(q.events, data)
+	q.wake()
+}
+
+// end ends the queue with err, which is returned once the events before it have been read. Only the first call has any
+// effect.
+func (q *eventQueue) end(err error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.err != nil {
+		return
+	}
+	q.err = err
+	q.wake()
+}
+
+// wake wakes up a waiting read. The caller must hold the queue's lock.
+func (q *eventQueue) wake() {
+	close(q.ready)
+	q.ready = make(chan struct{})
+}
+
+// next waits for the next event, returning the error the queue ended with once there are none left.
+func (q *eventQueue) next() (interface{}, error) {
+	for {
+		q.lock.Lock()
+		if len(q.events) > 0 {
+			data := q.events[0]
+			q.events = q.events[1:]
+			q.lock.Unlock()
+			return data, nil
+		}
+		err, ready := q.err, q.ready
+		q.lock.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+		<-ready
+	}
+}
+
+// EventSourceStream adapts a JavaScript EventSource, for a stream of Server-Sent Events, into an ObjectReadableStream that
+// yields the data of each message event as a string, one per ReadValue, in the order the events arrived. Events are
+// queued until they are read, so none are missed between reads. Only unnamed events, which are the ones delivered to
+// onmessage, are yielded. An error while the EventSource is reconnecting is ignored, as it carries on by itself, but
+// once it has failed for good, the stream ends with ErrEventSourceFailed after the events before it have been read. The
+// EventSource is listened to through its onmessage and onerror properties, which replaces any existing handlers.
+// Closing the returned stream closes the EventSource, and a ReadValue waiting for an event returns io.ErrClosedPipe.
+func EventSourceStream(es js.Value) *ObjectReadableStream {
+	queue := &eventQueue{ready: make(chan struct{})}
+
+	es.Set("onmessage", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		queue.push(args[0].Get("data").String())
+		return nil
+	}))
+	es.Set("onerror", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if es.Get("readyState").Int() == eventSourceClosed {
+			queue.end(ErrEventSourceFailed)
+		}
+		return nil
+	}))
+
+	return newObjectReadableStream(queue.next, func() error {
+		queue.end(io.ErrClosedPipe)
+		es.Call("close")
+		return nil
+	})
+}
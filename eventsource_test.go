@@ -0,0 +1,117 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"io"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// newMockEventSource creates a mock EventSource whose events are fired by calling its message and error methods.
+func newMockEventSource() js.Value {
+	return js.Global().Get("Function").New(`
+		return {
+			readyState: 1,
+			onmessage: null,
+			onerror: null,
+			message(data) {
+				this.onmessage({ data });
+			},
+			error(readyState) {
+				this.readyState = readyState;
+				this.onerror(new Event("error"));
+			},
+			close() {
+				this.readyState = 2;
+			},
+		};
+	`).Invoke()
+}
+
+func TestEventSourceStream(t *testing.T) {
+	es := newMockEventSource()
+	stream := EventSourceStream(es)
+
+	// Events fired before they are read are queued, and an error while reconnecting doesn't end the stream.
+	es.Call("message", "one")
+	es.Call("message", "two")
+	es.Call("error", 0)
+	es.Call("message", "three")
+	es.Call("error", 2)
+	es.Call("message", "late")
+
+	for _, want := range []string{"one", "two", "three"} {
+		value, err := stream.ReadValue()
+		if err != nil || value != want {
+			t.Fatalf("ReadValue returned %v, %v, want %q, nil", value, err, want)
+		}
+	}
+	if _, err := stream.ReadValue(); err != ErrEventSourceFailed {
+		t.Fatalf("ReadValue after the EventSource failed returned %v, want %v", err, ErrEventSourceFailed)
+	}
+}
+
+func TestEventSourceStreamClose(t *testing.T) {
+	es := newMockEventSource()
+	stream := EventSourceStream(es)
+
+	// A read waiting for an event gets it as soon as it arrives.
+	values := make(chan interface{}, 1)
+	go func() {
+		value, _ := stream.ReadValue()
+		values <- value
+	}()
+	es.Call("message", "Hello")
+	if value := <-values; value != "Hello" {
+		t.Fatalf("ReadValue returned %v, want %q", value, "Hello")
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if es.Get("readyState").Int() != 2 {
+		t.Fatal("Close did not close the EventSource")
+	}
+	if _, err := stream.ReadValue(); err != io.ErrClosedPipe {
+		t.Fatalf("ReadValue after Close returned %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestEventSourceStreamCloseDuringRead(t *testing.T) {
+	es := newMockEventSource()
+	stream := EventSourceStream(es)
+
+	// No event ever arrives, so the read waits until the stream is closed.
+	read := make(chan error, 1)
+	go func() {
+		_, err := stream.ReadValue()
+		read <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- stream.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked behind a pending ReadValue")
+	}
+	if es.Get("readyState").Int() != 2 {
+		t.Fatal("Close did not close the EventSource")
+	}
+	select {
+	case err := <-read:
+		if err != io.ErrClosedPipe {
+			t.Fatalf("pending ReadValue returned %v, want %v", err, io.ErrClosedPipe)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending ReadValue did not return once the stream was closed")
+	}
+}
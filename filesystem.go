@@ -0,0 +1,83 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall/js"
+)
+
+// FileSystemWriter opens a File System Access API file handle, such as one from showSaveFilePicker, for writing, by
+// awaiting its createWritable method, and wraps the FileSystemWritableFileStream it gives. The browser writes to a
+// temporary file, which only replaces the file once the stream is closed, so Close, which waits for that, commits the
+// file atomically, and a stream that is aborted, or never closed, leaves the file as it was. createWritable's default
+// options are used, so the file starts out empty. Besides writing, the stream can seek and truncate with SeekFile and
+// TruncateFile.
+func FileSystemWriter(fileHandle js.Value) (stream *WritableStream, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	writable, err := await(fileHandle.Call("createWritable"))
+	if err != nil {
+		return nil, err
+	}
+
+	stream = NewWritableStream(writable)
+	stream.fileSystem = true
+	return stream, nil
+}
+
+// SeekFile moves the position of a stream created by FileSystemWriter to position, a byte offset from the start of the
+// file, so that the next write goes there. Seeking past the end of the file is allowed, and writing there fills the gap
+// with zeros. Any other stream returns errors.ErrUnsupported.
+func (w *WritableStream) SeekFile(position int64) error {
+	return w.fileCommand(map[string]interface{}{"type": "seek", "position": position})
+}
+
+// TruncateFile resizes the file behind a stream created by FileSystemWriter to size bytes, cutting it short or extending
+// it with zeros. If the stream's position was beyond the new end, it is moved to it. Any other stream returns
+// errors.ErrUnsupported.
+func (w *WritableStream) TruncateFile(size int64) error {
+	return w.fileCommand(map[string]interface{}{"type": "truncate", "size": size})
+}
+
+// fileCommand writes command, one of the objects a FileSystemWritableFileStream takes in place of data, to the stream, in
+// order with the writes before it.
+func (w *WritableStream) fileCommand(command map[string]interface{}) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed.Load() {
+		return io.ErrClosedPipe
+	}
+	if !w.fileSystem {
+		return errors.ErrUnsupported
+	}
+
+	writer, err := w.getWriter()
+	if err != nil {
+		return err
+	}
+	defer w.releaseWriter(writer)
+
+	defer acquireOp()()
+	_, err = await(writer.Call("write", command))
+	if err != nil {
+		// As with a write, the command is only rejected if the stream has errored.
+		w.finished.finish(err)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+	"testing"
+)
+
+// newTestFileHandle creates an object standing in for a FileSystemFileHandle, whose writable applies writes, seeks and
+// truncates to a file's contents, only committing them when closed, and records the file's contents and what was done.
+func newTestFileHandle() js.Value {
+	return js.Global().Get("Function").New(`
+		const handle = { file: "", committed: false, events: [] };
+		handle.createWritable = () => {
+			let data = "", position = 0;
+			return Promise.resolve(new WritableStream({
+				write(chunk) {
+					if (chunk instanceof Uint8Array) {
+						const text = new TextDecoder().decode(chunk);
+						data = data.padEnd(position, "\0");
+						data = data.slice(0, position) + text + data.slice(position + text.length);
+						position += text.length;
+						handle.events.push("write: " + text);
+					} else if (chunk.type === "seek") {
+						position = chunk.position;
+						handle.events.push("seek: " + chunk.position);
+					} else if (chunk.type === "truncate") {
+						data = data.slice(0, chunk.size).padEnd(chunk.size, "\0");
+						position = Math.min(position, chunk.size);
+						handle.events.push("truncate: " + chunk.size);
+					}
+				},
+				close() {
+					handle.file = data;
+					handle.committed = true;
+					handle.events.push("commit");
+				},
+			}));
+		};
+		return handle;
+	`).Invoke()
+}
+
+func TestFileSystemWriter(t *testing.T) {
+	handle := newTestFileHandle()
+	stream, err := FileSystemWriter(handle)
+	if err != nil {
+		t.Fatalf("FileSystemWriter returned error: %v", err)
+	}
+
+	if _, err := stream.Write([]byte("Hello, world!")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := stream.SeekFile(7); err != nil {
+		t.Fatalf("SeekFile returned error: %v", err)
+	}
+	if _, err := stream.Write([]byte("there")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := stream.TruncateFile(12); err != nil {
+		t.Fatalf("TruncateFile returned error: %v", err)
+	}
+	if handle.Get("committed").Bool() {
+		t.Fatal("the file was committed before Close")
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if file := handle.Get("file").String(); file != "Hello, there" || !handle.Get("committed").Bool() {
+		t.Fatalf("the file holds %q and was committed: %v, want %q and true", file, handle.Get("committed").Bool(),
+			"Hello, there")
+	}
+	var events []string
+	for i := 0; i < handle.Get("events").Length(); i++ {
+		events = append(events, handle.Get("events").Index(i).String())
+	}
+	want := []string{"write: Hello, world!", "seek: 7", "write: there", "truncate: 12", "commit"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Fatalf("the writable received %q, want %q", events, want)
+	}
+
+	// Other streams can't seek or truncate.
+	writable, _ := newTestWritableStream()
+	if err := NewWritableStream(writable).SeekFile(0); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("SeekFile of a plain stream returned %v, want %v", err, errors.ErrUnsupported)
+	}
+
+	// A handle that can't be opened for writing gives its error.
+	denied := js.Global().Get("Function").New(`
+		return { createWritable: () => Promise.reject(new DOMException("permission denied", "NotAllowedError")) };
+	`).Invoke()
+	if _, err := FileSystemWriter(denied); err == nil || err.Error() != "NotAllowedError: permission denied" {
+		t.Fatalf("FileSystemWriter of a denied handle returned %v, want %q", err, "NotAllowedError: permission denied")
+	}
+}
@@ -0,0 +1,123 @@
+package jsStreams
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+)
+
+// hashReader feeds every byte read from a stream into a hash as it is passed on.
+type hashReader struct {
+	source *ReadableStream
+	hash   hash.Hash
+	lock   sync.Mutex
+}
+
+func (h *hashReader) Read(p []byte) (int, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	n, err := h.source.Read(p)
+	h.hash.Write(p[:n])
+	return n, err
+}
+
+func (h *hashReader) Close() error {
+	return h.source.Close()
+}
+
+func (h *hashReader) sum() []byte {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.hash.Sum(nil)
+}
+
+// HashReadableStream creates a ReadableStream that yields the same bytes as r, while writing each of them into h, so
+// that data can be verified without a separate pass. The returned function gives the digest of the bytes read so far,
+// which is the digest of the whole stream once it has been read to EOF. Closing the returned stream closes r.
+func HashReadableStream(r *ReadableStream, h hash.Hash) (*ReadableStream, func() []byte) {
+	reader := &hashReader{
+		source: r,
+		hash:   h,
+	}
+	return newGoReadableStream(reader), reader.sum
+}
+
+// ErrChecksumMismatch is returned by a stream created by ChecksumValidatingStream, in place of io.EOF, if the digest of
+// the data doesn't match the one expected.
+var ErrChecksumMismatch = errors.New("stream checksum does not match")
+
+// checksumReader hashes the bytes read from a stream, checking the digest against the one expected once it ends.
+type checksumReader struct {
+	hashReader
+	expected []byte
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.hashReader.Read(p)
+	if err == io.EOF {
+		if sum := c.sum(); subtle.ConstantTimeCompare(sum, c.expected) != 1 {
+			return n, fmt.Errorf("%w: got %x, want %x", ErrChecksumMismatch, sum, c.expected)
+		}
+	}
+	return n, err
+}
+
+// ChecksumValidatingStream creates a ReadableStream that yields the same bytes as r, hashing them with h as they pass
+// through, and checks the digest against expected once r ends. If they match, the stream ends with io.EOF as usual, but
+// if they don't, reading the end of the stream returns an error wrapping ErrChecksumMismatch instead, so a corrupted
+// transfer can't be mistaken for a complete one. The data is passed on as it is read, before it can be checked, so it
+// must not be trusted until the end of the stream has been reached cleanly. Closing the returned stream closes r.
+func ChecksumValidatingStream(r *ReadableStream, expected []byte, h hash.Hash) *ReadableStream {
+	return newGoReadableStream(&checksumReader{
+		hashReader: hashReader{source: r, hash: h},
+		expected:   expected,
+	})
+}
+
+// checksumWriter feeds every byte written to a stream into a checksum as it is passed on.
+type checksumWriter struct {
+	sink *WritableStream
+	hash hash.Hash32
+	lock sync.Mutex
+}
+
+func (c *checksumWriter) Write(p []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	n, err := c.sink.Write(p)
+	c.hash.Write(p[:n])
+	return n, err
+}
+
+func (c *checksumWriter) Flush() error {
+	return c.sink.Flush()
+}
+
+func (c *checksumWriter) Close() error {
+	return c.sink.Close()
+}
+
+func (c *checksumWriter) sum() uint32 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.hash.Sum32()
+}
+
+// ChecksumWriter creates a WritableStream that writes everything written to it to w, while feeding it into h, such as a
+// CRC-32 or Adler-32, so that the checksum can be sent along with the data for the consumer to verify, for instance with
+// ChecksumValidatingStream. The returned function gives the checksum of the bytes w has accepted so far, which is the
+// checksum of everything written once the returned stream has been closed. Closing the returned stream closes w.
+func ChecksumWriter(w *WritableStream, h hash.Hash32) (*WritableStream, func() uint32) {
+	writer := &checksumWriter{
+		sink: w,
+		hash: h,
+	}
+	return newGoWritableStream(writer), writer.sum
+}
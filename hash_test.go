@@ -0,0 +1,101 @@
+package jsStreams
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestHashReadableStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		newHash func() hash.Hash
+	}{
+		{"sha256", sha256.New},
+		{"crc32", func() hash.Hash { return crc32.NewIEEE() }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream, digest := HashReadableStream(newStringStream("Hello, world!"), test.newHash())
+
+			data, err := io.ReadAll(stream)
+			if err != nil {
+				t.Fatalf("ReadAll returned error: %v", err)
+			}
+			if string(data) != "Hello, world!" {
+				t.Fatalf("ReadAll returned %q, want %q", data, "Hello, world!")
+			}
+
+			expected := test.newHash()
+			expected.Write([]byte("Hello, world!"))
+			if !bytes.Equal(digest(), expected.Sum(nil)) {
+				t.Fatalf("digest is %x, want %x", digest(), expected.Sum(nil))
+			}
+		})
+	}
+}
+
+func TestChecksumValidatingStream(t *testing.T) {
+	sum := sha256.Sum256([]byte("Hello, world!"))
+
+	stream := ChecksumValidatingStream(newChunkedStream("Hello, ", "world!"), sum[:], sha256.New())
+	if data, err := io.ReadAll(stream); err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+
+	// A corrupted transfer still yields its data, but the end of the stream is an error rather than io.EOF.
+	stream = ChecksumValidatingStream(newChunkedStream("Hello, ", "world?"), sum[:], sha256.New())
+	buffer := make([]byte, 16)
+	var data []byte
+	var err error
+	for err == nil {
+		var n int
+		n, err = stream.Read(buffer)
+		data = append(data, buffer[:n]...)
+	}
+	if string(data) != "Hello, world?" {
+		t.Fatalf("stream yielded %q, want %q", data, "Hello, world?")
+	}
+	if err == io.EOF || !strings.Contains(err.Error(), ErrChecksumMismatch.Error()) {
+		t.Fatalf("final Read returned %v, want %v", err, ErrChecksumMismatch)
+	}
+}
+
+func TestChecksumWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		newHash func() hash.Hash32
+		sum     func([]byte) uint32
+	}{
+		{"crc32", crc32.NewIEEE, crc32.ChecksumIEEE},
+		{"adler32", adler32.New, adler32.Checksum},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			stream, checksum := ChecksumWriter(newGoWritableStream(sink), test.newHash())
+			for _, chunk := range []string{"Hello", ", ", "world!"} {
+				if _, err := stream.Write([]byte(chunk)); err != nil {
+					t.Fatalf("Write returned error: %v", err)
+				}
+			}
+			if err := stream.Close(); err != nil {
+				t.Fatalf("Close returned error: %v", err)
+			}
+
+			if sink.String() != "Hello, world!" || !sink.closed {
+				t.Fatalf("sink received %q, closed %v, want %q, closed", sink.String(), sink.closed, "Hello, world!")
+			}
+			if got, want := checksum(), test.sum([]byte("Hello, world!")); got != want {
+				t.Fatalf("checksum returned %08x, want %08x", got, want)
+			}
+		})
+	}
+}
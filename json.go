@@ -0,0 +1,14 @@
+package jsStreams
+
+import (
+	"bufio"
+	"encoding/json"
+)
+
+// JSONDecoder returns a json.Decoder that decodes values from the stream as they arrive, so that a stream of
+// newline-delimited JSON, or any other sequence of JSON values, can be decoded by calling Decode in a loop until it
+// returns io.EOF. Values can be split across any number of chunks. The stream is read through a buffer, so the decoder
+// may read past the last value it returns; Buffered on the decoder returns what it has read but not decoded.
+func (r *ReadableStream) JSONDecoder() *json.Decoder {
+	return json.NewDecoder(bufio.NewReaderSize(r, defaultChunkSize))
+}
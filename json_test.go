@@ -0,0 +1,47 @@
+package jsStreams
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestJSONDecoder(t *testing.T) {
+	type message struct {
+		ID   int    `json:"id"`
+		Text string `json:"text"`
+	}
+
+	stream := newChunkedStream(`{"id": 1, "te`, `xt": "one"}`+"\n"+`{"id": 2,`, ` "text": "two"}`, "\n"+`{"id": 3, "text": "three"}`)
+	decoder := stream.JSONDecoder()
+
+	var messages []message
+	for {
+		var m message
+		err := decoder.Decode(&m)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode returned error: %v", err)
+		}
+		messages = append(messages, m)
+	}
+
+	want := []message{{1, "one"}, {2, "two"}, {3, "three"}}
+	if !reflect.DeepEqual(messages, want) {
+		t.Fatalf("decoded %+v, want %+v", messages, want)
+	}
+}
+
+func TestJSONDecoderTruncated(t *testing.T) {
+	decoder := newChunkedStream(`{"id": 1}`, `{"id": `).JSONDecoder()
+
+	var v map[string]interface{}
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatalf("first Decode returned error: %v", err)
+	}
+	if err := decoder.Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Decode of a truncated value returned %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
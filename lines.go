@@ -0,0 +1,28 @@
+package jsStreams
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// LineReadableStream creates an ObjectReadableStream that yields the lines of r, one string per ReadValue, without their
+// line endings. Lines are split on "\n", and a "\r" before it is stripped as well, so "\r\n" line endings are handled
+// too. Lines can be split across any number of chunks, and can be of any length. A final line with no line ending is
+// still yielded, and the stream ends with io.EOF after the last line. Closing the returned stream closes r.
+func LineReadableStream(r *ReadableStream) *ObjectReadableStream {
+	reader := bufio.NewReaderSize(r, defaultChunkSize)
+	return newObjectReadableStream(func() (interface{}, error) {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF && line != "" {
+			// The last line has no line ending, but the next read will return io.EOF again.
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		return strings.TrimSuffix(line, "\r"), nil
+	}, r.Close)
+}
@@ -0,0 +1,86 @@
+package jsStreams
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readLines(t *testing.T, stream *ObjectReadableStream) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		value, err := stream.ReadValue()
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("ReadValue returned error: %v", err)
+		}
+		lines = append(lines, value.(string))
+	}
+}
+
+func TestLineReadableStream(t *testing.T) {
+	long := strings.Repeat("x", 3*defaultChunkSize)
+	lines := readLines(t, LineReadableStream(newChunkedStream("fir", "st\nsec", "ond\r", "\n\nthi", "rd\r\n"+long+"\nlast")))
+
+	want := []string{"first", "second", "", "third", long, "last"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("LineReadableStream yielded %q, want %q", lines, want)
+	}
+}
+
+func TestLineReadableStreamTrailingNewline(t *testing.T) {
+	stream := LineReadableStream(newStringStream("one\ntwo\n"))
+	if lines := readLines(t, stream); !reflect.DeepEqual(lines, []string{"one", "two"}) {
+		t.Fatalf("LineReadableStream yielded %q, want %q", lines, []string{"one", "two"})
+	}
+	if _, err := stream.ReadValue(); err != io.EOF {
+		t.Fatalf("ReadValue after the end returned %v, want %v", err, io.EOF)
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := stream.ReadValue(); err != io.ErrClosedPipe {
+		t.Fatalf("ReadValue after Close returned %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestLineReadableStreamCloseDuringRead(t *testing.T) {
+	// Nothing is ever written to the pipe, so the read stalls until the stream is closed.
+	pipeReader, _ := io.Pipe()
+	stream := LineReadableStream(newGoReadableStream(pipeReader))
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := stream.ReadValue()
+		read <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- stream.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked behind a pending ReadValue")
+	}
+	select {
+	case err := <-read:
+		if err != io.ErrClosedPipe {
+			t.Fatalf("pending ReadValue returned %v, want %v", err, io.ErrClosedPipe)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending ReadValue did not return once the stream was closed")
+	}
+}
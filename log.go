@@ -0,0 +1,28 @@
+package jsStreams
+
+// Logger, if set, is called whenever a stream reaches one of the lifecycle events below, with details about the event
+// such as the number of bytes involved or the error encountered. It is intended for tracing where a stalled pipe is
+// stuck. When Logger is nil, which is the default, no events are constructed at all. Logger may be called from multiple
+// goroutines at once, and should not be changed while streams are in use.
+var Logger func(event string, detail map[string]interface{})
+
+// The events passed to Logger. The detail map always contains "stream", which is either "readable" or "writable".
+const (
+	// EventReaderAcquired is logged when a reader has been acquired for a ReadableStream.
+	EventReaderAcquired = "reader acquired"
+	// EventChunkRead is logged when a chunk has been read, with its length in "size".
+	EventChunkRead = "chunk read"
+	// EventEOF is logged when a ReadableStream reports that it has ended.
+	EventEOF = "eof"
+	// EventError is logged when an operation fails, with the error in "error".
+	EventError = "error"
+	// EventWriterReady is logged when a WritableStream's writer is ready to accept a chunk.
+	EventWriterReady = "writer ready"
+	// EventWriteComplete is logged when a chunk has been written, with its length in "size".
+	EventWriteComplete = "write complete"
+	// EventClose is logged when a stream is closed.
+	EventClose = "close"
+	// EventLeaked is logged when a stream created with CloseOnFinalize is garbage collected without having been closed,
+	// just before it is closed.
+	EventLeaked = "leaked"
+)
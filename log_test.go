@@ -0,0 +1,32 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var events []string
+	Logger = func(event string, detail map[string]interface{}) {
+		events = append(events, event)
+	}
+	defer func() {
+		Logger = nil
+	}()
+
+	stream := NewReadableStream(newTestReadableStream([]byte("Hello")))
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	want := []string{EventReaderAcquired, EventChunkRead, EventReaderAcquired, EventEOF, EventClose}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("logged events %q, want %q", events, want)
+	}
+}
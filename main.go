@@ -1,26 +1,241 @@
+//go:build js
+
 package jsStreams
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"strings"
+	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"syscall/js"
+	"time"
 )
 
+// ErrStreamLocked is returned when a stream can't be read from or written to because it is locked to a reader or writer
+// that doesn't belong to us, such as one acquired by JavaScript code. The operation can be retried once the lock has been
+// released.
+var ErrStreamLocked = errors.New("stream is locked to another reader or writer")
+
+// ErrStreamBusy is returned by Read and ReadByte on a stream created with StrictSingleReader set if another read is
+// already in progress.
+var ErrStreamBusy = errors.New("another read of the stream is in progress")
+
 // ReadableStream implements io.ReadCloser for a JavaScript ReadableStream.
 type ReadableStream struct {
-	stream js.Value
-	lock   sync.Mutex
+	stream   js.Value
+	lock     sync.Mutex
+	closed   atomic.Bool
+	reader   *Reader
+	leftover []byte
+	finished closeNotifier
+
+	// scratch is the pool leftover data is kept in, if one was configured, and scratchBuffer is the buffer borrowed from
+	// it that currently backs leftover.
+	scratch       *sync.Pool
+	scratchBuffer *[]byte
+
+	// byobSize is the size of the ArrayBuffer BYOB reads are made into, if one is kept, and byobBuffer is that buffer
+	// while it isn't in the middle of a read.
+	byobSize   int
+	byobBuffer js.Value
+	// sharedBYOB makes BYOB reads go into sharedScratch, as SharedBYOBBuffer.
+	sharedBYOB bool
+
+	// allocBuffer and releaseBuffer hand out and take back the views BYOB reads are made into, as AllocBuffer and
+	// ReleaseBuffer.
+	allocBuffer   func(n int) js.Value
+	releaseBuffer func(view js.Value)
+
+	// emptyIsEOF makes an empty chunk end the stream, as TreatEmptyChunkAsEOF.
+	emptyIsEOF bool
+	fillMode   FillMode
+
+	// strict makes a read fail with ErrStreamBusy while another is in progress, as StrictSingleReader, and reading is
+	// set while one is.
+	strict  bool
+	reading atomic.Bool
+
+	// maxReadWait is the longest a read waits for the JavaScript stream, as MaxReadWait.
+	maxReadWait time.Duration
+
+	// interrupt guards inflight, the JavaScript reader a read is waiting on, if any, so that Close can cancel it without
+	// waiting for the lock the read holds. interrupted is set once it has, and cancelled and cancelledClosed are then the
+	// cancel's promise and the reader's closed promise, for Close to wait on once it has the lock.
+	interrupt       sync.Mutex
+	inflight        js.Value
+	interrupted     bool
+	cancelled       js.Value
+	cancelledClosed js.Value
+
+	// ended is set once a read has returned data that came along with done, so that the next one reports the end of the
+	// stream without reading again.
+	ended bool
+
+	// byobProbed is set once SupportsBYOB has found out whether the stream supports BYOB readers, which is kept in byob.
+	byobProbed bool
+	byob       bool
+
+	bytesRead atomic.Int64
+	readRate  rateMeter
+
+	// expectedLength is the length of the whole stream, as given to SetExpectedLength, or nil if it hasn't been.
+	expectedLength atomic.Pointer[int64]
 }
 
 // Read reads up to len(p) bytes into p. It returns the number of bytes read (0 <= n <= len(p)) and any error encountered.
 // This implementation of Read does not use scratch space if n < len(p). If some data is available but not len(p) bytes,
 // Read conventionally returns what is available instead of waiting for more. Note: Read will block until data is available,
-// meaning in a WASM environment, you must use a goroutine to call Read.
+// meaning in a WASM environment, you must use a goroutine to call Read. Data left over from an earlier read, such as the
+// rest of a chunk too large for its buffer, is returned straight away, without waiting on JavaScript. Once the stream has
+// been closed, Read returns io.ErrClosedPipe.
 func (r *ReadableStream) Read(p []byte) (n int, err error) {
+	if DebugContractChecks {
+		defer func() {
+			if violation := checkStreamRead(r, p, n, err); violation != nil {
+				panic(violation)
+			}
+		}()
+	}
+	if r.strict {
+		if !r.reading.CompareAndSwap(false, true) {
+			return 0, ErrStreamBusy
+		}
+		defer r.reading.Store(false)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// Data already read from the stream is served on the spot, without awaiting anything.
+	if !r.closed.Load() && len(p) > 0 {
+		if n, ok := r.readBuffered(p); ok {
+			return n, nil
+		}
+	}
+	return r.readHeld(p)
+}
+
+// checkStreamRead is checkRead for ReadableStream.Read, which also never returns data along with io.EOF.
+func checkStreamRead(r *ReadableStream, p []byte, n int, err error) error {
+	if err == io.EOF && n > 0 {
+		return fmt.Errorf("%w: %T.Read returned io.EOF with %d bytes of data", ErrContractViolation, r, n)
+	}
+	return checkRead(r, p, n)
+}
+
+// readHeld reads up to len(p) bytes into p, exactly like Read, for a caller that already holds the stream's lock.
+func (r *ReadableStream) readHeld(p []byte) (n int, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+		if Logger != nil && err != nil && err != io.EOF {
+			Logger(EventError, map[string]interface{}{"stream": "readable", "error": err})
+		}
+	}()
+
+	if r.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	return r.fill(p, r.readLocked)
+}
+
+// readChunkHeld reads whatever a single chunk of the stream holds into p, like readHeld, but ignoring the stream's
+// FillMode, so that it returns as soon as any data has arrived. The caller must hold the stream's lock.
+func (r *ReadableStream) readChunkHeld(p []byte) (n int, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	if r.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+
+	n, err = r.readLocked(p)
+	r.addBytesRead(int64(n))
+	return n, err
+}
+
+// fill calls read once to read into p, or, if the stream's FillMode is FillComplete, as many times as it takes to fill p
+// or reach the end of the stream. The caller must hold the stream's lock.
+func (r *ReadableStream) fill(p []byte, read func([]byte) (int, error)) (n int, err error) {
+	defer func() {
+		r.addBytesRead(int64(n))
+	}()
+
+	if r.fillMode != FillComplete {
+		return read(p)
+	}
+
+	for n < len(p) && err == nil {
+		var filled int
+		filled, err = read(p[n:])
+		n += filled
+	}
+	if err == io.EOF && n > 0 {
+		// The end of the stream will be reported again by the next read.
+		err = nil
+	}
+	return n, err
+}
+
+// readLocked reads up to len(p) bytes into p, serving any leftover data first. p must not be empty, and the caller must
+// hold the stream's lock.
+func (r *ReadableStream) readLocked(p []byte) (n int, err error) {
+	if len(r.leftover) > 0 {
+		return r.readLeftover(p), nil
+	}
+
+	// If a reader has been acquired explicitly, we read through it, otherwise we hold one just for this read, which is a
+	// BYOB reader if the stream supports one.
+	reader := r.reader
+	if reader == nil {
+		mode := ReaderModeDefault
+		if r.supportsBYOB() {
+			mode = ReaderModeBYOB
+		}
+		reader, err = r.acquireReader(mode)
+		if err != nil {
+			return 0, err
+		}
+		defer reader.releaseLock()
+	}
+
+	return reader.read(p)
+}
+
+// unread puts data back at the front of the stream, to be returned by the next read. The caller must hold the stream's
+// lock.
+func (r *ReadableStream) unread(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	leftover := append(append([]byte(nil), data...), r.leftover...)
+	r.releaseScratch()
+	r.leftover = leftover
+	r.addBytesRead(-int64(len(data)))
+}
+
+// byteReadAhead is how many bytes ReadByte reads at once, keeping the rest for the following calls.
+const byteReadAhead = 512
+
+// ReadByte implements io.ByteReader, reading a single byte from the stream. Rather than crossing into JavaScript for
+// every byte, it reads up to 512 bytes ahead and serves the following calls from them, so it is suitable for parsers that
+// consume a byte at a time, such as binary.ReadUvarint. The bytes read ahead are returned by Read as usual.
+func (r *ReadableStream) ReadByte() (b byte, err error) {
 	defer func() {
 		recovered := recover()
 		if recovered != nil {
@@ -28,56 +243,317 @@ func (r *ReadableStream) Read(p []byte) (n int, err error) {
 		}
 	}()
 
+	if r.strict {
+		if !r.reading.CompareAndSwap(false, true) {
+			return 0, ErrStreamBusy
+		}
+		defer r.reading.Store(false)
+	}
+
 	r.lock.Lock()
-	var waitGroup sync.WaitGroup
-	waitGroup.Add(1)
+	defer r.lock.Unlock()
+
+	if r.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
 
-	reader := r.stream.Call("getReader", map[string]interface{}{"mode": "byob"})
+	if len(r.leftover) == 0 {
+		ahead := make([]byte, byteReadAhead)
+		n, err := r.readLocked(ahead)
+		if n == 0 {
+			return 0, err
+		}
 
-	resultBuffer := js.Global().Get("Uint8Array").New(len(p))
-	readResult := reader.Call("read", resultBuffer)
+		// A default reader may have left part of a large chunk behind, which comes after what we read ahead.
+		leftover := append(ahead[1:n], r.leftover...)
+		r.releaseScratch()
+		r.leftover = leftover
+		r.addBytesRead(1)
+		return ahead[0], nil
+	}
 
-	readResult.Call("then", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		defer waitGroup.Done()
-		if args[0].Get("done").Bool() || args[0].Get("value").Length() == 0 {
-			err = io.EOF
-			return nil
+	var one [1]byte
+	r.readLeftover(one[:])
+	r.addBytesRead(1)
+	return one[0], nil
+}
+
+// Locked reports whether the underlying JavaScript ReadableStream is locked to a reader, in which case it can't be read
+// from by anything other than that reader. Streams are only locked by this package while a Read is in progress, or while
+// a Reader acquired with AcquireReader is held.
+func (r *ReadableStream) Locked() bool {
+	if r.stream.IsUndefined() {
+		// The stream was created from a reader, which holds its lock.
+		return true
+	}
+	return r.stream.Get("locked").Bool()
+}
+
+// SupportsBYOB reports whether the stream supports BYOB readers, which only byte streams do. The Streams specification
+// doesn't expose a stream's type, so the first call finds out by briefly acquiring a BYOB reader, and releasing it
+// straight away, which neither reads anything nor leaves the stream locked, and the result is kept for later calls. A
+// stream that is locked to a reader that isn't ours can't be probed, so SupportsBYOB returns false, without keeping the
+// result, until it is unlocked. Read uses the result to read through a default reader when BYOB isn't supported.
+func (r *ReadableStream) SupportsBYOB() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.supportsBYOB()
+}
+
+// supportsBYOB does the work of SupportsBYOB. The caller must hold the stream's lock.
+func (r *ReadableStream) supportsBYOB() bool {
+	if r.byobProbed {
+		return r.byob
+	}
+	if r.reader != nil {
+		// We can't probe the stream while we hold a reader for it, but that reader tells us just as well.
+		return r.reader.mode == ReaderModeBYOB
+	}
+	if r.stream.IsUndefined() || r.stream.Get("locked").Bool() {
+		return false
+	}
+
+	r.byob = probeBYOB(r.stream)
+	r.byobProbed = true
+	return r.byob
+}
+
+// probeBYOB reports whether stream, which must not be locked, supports BYOB readers, by acquiring one and releasing it.
+func probeBYOB(stream js.Value) (ok bool) {
+	defer func() {
+		// getReader throws a TypeError if the stream isn't a byte stream.
+		if recover() != nil {
+			ok = false
 		}
-		data := args[0].Get("value")
-		js.CopyBytesToGo(p, data)
-		n = data.Length()
-		return nil
-	}))
+	}()
 
-	readResult.Call("catch", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		defer waitGroup.Done()
-		err = errors.New(args[0].Get("message").String())
-		return nil
-	}))
+	getReader(stream, ReaderModeBYOB).Call("releaseLock")
+	return true
+}
 
-	waitGroup.Wait()
-	reader.Call("releaseLock")
-	r.lock.Unlock()
+// JSValue returns the underlying JavaScript ReadableStream, so that it can be handed to JavaScript APIs. It returns
+// undefined for a stream created with NewReadableStreamFromReader, as only its reader is known.
+func (r *ReadableStream) JSValue() js.Value {
+	return r.stream
+}
 
-	return n, err
+// BytesRead returns the total number of bytes read from the stream so far, by Read, ReadByte, ReadIntoJS or a Reader.
+// It is safe to call at any time, including while a read is in progress, to report progress or collect metrics.
+func (r *ReadableStream) BytesRead() int64 {
+	return r.bytesRead.Load()
+}
+
+// Buffered returns the number of bytes that have already been received from the JavaScript stream but not yet read,
+// which the next Read returns without waiting on JavaScript. Data is only buffered when a default reader hands over a
+// chunk that is larger than the slice it was read into.
+func (r *ReadableStream) Buffered() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return len(r.leftover)
 }
 
-// Close closes the ReadableStream. If the stream is already closed, Close does nothing.
-func (r *ReadableStream) Close() (err error) {
+// ErrNotTypedArray is returned by ReadIntoJS if the provided view is not a TypedArray or DataView.
+var ErrNotTypedArray = errors.New("view must be a TypedArray or DataView")
+
+// ReadIntoJS reads from the stream directly into the provided JavaScript TypedArray, without copying the data through Go
+// memory, returning the number of bytes read and a view containing them. This is useful when the data is destined for
+// a JavaScript API anyway, such as WebGL or Web Audio. As with any BYOB read, the buffer backing view is transferred to
+// the returned view, so view itself must not be used afterwards. If the stream has ended, ReadIntoJS returns io.EOF.
+func (r *ReadableStream) ReadIntoJS(view js.Value) (n int, filled js.Value, err error) {
 	defer func() {
-		// We don't want any errors to be thrown if the stream is already closed.
-		recovery := recover()
-		if !strings.Contains(fmt.Sprint(recovery), "Can not close stream after closing or error") {
-			if recovery != nil {
-				err = fmt.Errorf("panic: %v", recovery)
-			}
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
 		}
 	}()
 
+	if view.Type() != js.TypeObject || !js.Global().Get("ArrayBuffer").Call("isView", view).Bool() {
+		return 0, js.Undefined(), ErrNotTypedArray
+	}
+
 	r.lock.Lock()
-	r.stream.Call("cancel")
-	r.lock.Unlock()
-	return nil
+	defer r.lock.Unlock()
+
+	if r.closed.Load() {
+		return 0, js.Undefined(), io.ErrClosedPipe
+	}
+
+	reader := r.reader
+	if reader == nil {
+		reader, err = r.acquireReader(ReaderModeBYOB)
+		if err != nil {
+			return 0, js.Undefined(), err
+		}
+		defer reader.releaseLock()
+	} else if reader.mode != ReaderModeBYOB {
+		return 0, js.Undefined(), ErrReaderAcquired
+	}
+
+	result, err := await(reader.reader.Call("read", view))
+	if err != nil {
+		return 0, js.Undefined(), err
+	}
+
+	filled = result.Get("value")
+	if result.Get("done").Bool() {
+		return 0, filled, io.EOF
+	}
+
+	n = filled.Get("byteLength").Int()
+	r.addBytesRead(int64(n))
+	return n, filled, nil
+}
+
+// alreadyClosedMessage is the message of the error thrown when closing a stream that has already been closed or has
+// errored, which Close treats as success, as the stream has finished either way.
+const alreadyClosedMessage = "Can not close stream after closing or error"
+
+// closeRecovery converts a value recovered during Close into the error Close should return. Nothing having been
+// recovered, or a panic whose message is exactly alreadyClosedMessage, is not an error. Any other panic is, whether its
+// value is a JavaScript error, a Go error, a string or anything else, and a Go error is wrapped so that it can still be
+// inspected with errors.Is and errors.As.
+func closeRecovery(recovered interface{}) error {
+	switch value := recovered.(type) {
+	case nil:
+		return nil
+	case js.Error:
+		if value.Type() == js.TypeObject && value.Get("message").String() == alreadyClosedMessage {
+			return nil
+		}
+		return fmt.Errorf("panic: %w", value)
+	case error:
+		if value.Error() == alreadyClosedMessage {
+			return nil
+		}
+		return fmt.Errorf("panic: %w", value)
+	case string:
+		if value == alreadyClosedMessage {
+			return nil
+		}
+	}
+	return fmt.Errorf("panic: %v", recovered)
+}
+
+// Close closes the ReadableStream, cancelling the underlying JavaScript stream, and blocks until its source has finished
+// cancelling, so that whatever the source tears down on cancel, such as a connection, is gone once Close returns. If the
+// source fails to cancel, or the stream is locked by a reader acquired by something else, so it can't be cancelled, the
+// error is returned, but the stream is closed all the same. Closing a stream that has already errored is not an error.
+// A Read waiting on the stream when Close is called is interrupted, returning io.ErrClosedPipe, rather than holding
+// Close up until it settles. If the stream is already closed, Close does nothing. It is safe to call Close multiple
+// times, including concurrently, and the underlying JavaScript stream will only be cancelled once.
+func (r *ReadableStream) Close() error {
+	return r.cancel()
+}
+
+// cancel closes the stream, exactly like Close, cancelling the JavaScript stream with reason, if one is given.
+func (r *ReadableStream) cancel(reason ...interface{}) (err error) {
+	defer func() {
+		// We don't want any errors to be thrown if the stream was already closed by something other than us.
+		if recoveryErr := closeRecovery(recover()); recoveryErr != nil {
+			err = recoveryErr
+		}
+	}()
+
+	// A read waiting on the stream holds the lock until it settles, which for a stalled stream is never, so it is
+	// cancelled first, which settles it.
+	r.interruptRead(reason...)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.cancelLocked(reason...)
+}
+
+// interruptRead cancels the JavaScript reader a read is waiting on, if there is one, making the read return
+// io.ErrClosedPipe. It doesn't need the stream's lock.
+func (r *ReadableStream) interruptRead(reason ...interface{}) {
+	r.interrupt.Lock()
+	defer r.interrupt.Unlock()
+
+	if r.inflight.IsUndefined() || r.interrupted || r.closed.Load() {
+		return
+	}
+	r.interrupted = true
+	r.cancelled = r.inflight.Call("cancel", reason...)
+	r.cancelledClosed = r.inflight.Get("closed")
+}
+
+// setInflight records reader as the JavaScript reader a read is waiting on, or, if it is undefined, that none is, and
+// reports whether the read has been interrupted.
+func (r *ReadableStream) setInflight(reader js.Value) (interrupted bool) {
+	r.interrupt.Lock()
+	defer r.interrupt.Unlock()
+
+	r.inflight = reader
+	return r.interrupted
+}
+
+// cancelLocked cancels the stream, exactly like cancel, for a caller that already holds the stream's lock.
+func (r *ReadableStream) cancelLocked(reason ...interface{}) error {
+	if r.closed.Load() {
+		return nil
+	}
+
+	r.closed.Store(true)
+	r.releaseScratch()
+	r.finished.finish(nil)
+	if Logger != nil {
+		Logger(EventClose, map[string]interface{}{"stream": "readable"})
+	}
+
+	r.interrupt.Lock()
+	interrupted, cancelled, closed := r.interrupted, r.cancelled, r.cancelledClosed
+	r.interrupt.Unlock()
+	if interrupted {
+		// The stream has already been cancelled, to interrupt a read, so we only need to wait for that.
+		if r.reader != nil {
+			reader := r.reader
+			r.reader = nil
+			defer reader.releaseLock()
+		}
+		return awaitCancel(cancelled, closed)
+	}
+
+	if r.reader != nil {
+		// The stream is locked by the reader, so it can only be cancelled through it.
+		reader := r.reader
+		r.reader = nil
+		defer reader.releaseLock()
+		return cancelReader(reader.reader, reason...)
+	}
+	if r.stream.Get("locked").Bool() {
+		// The stream is locked by something else, so only it can cancel the stream, and cancel rejects.
+		_, err := await(r.stream.Call("cancel", reason...))
+		return err
+	}
+
+	reader := getReader(r.stream, ReaderModeDefault)
+	defer reader.Call("releaseLock")
+	return cancelReader(reader, reason...)
+}
+
+// cancelReader cancels a stream through reader, with reason, if one is given, waiting for its source to finish
+// cancelling, and returns the error it failed to cancel with, if any. A stream that had already errored rejects the
+// cancel with the error it failed with, which isn't a failure to close, so the error is only returned if the reader's
+// closed promise, which cancelling settles, is fulfilled, showing that the stream was still open until it was cancelled
+// here.
+func cancelReader(reader js.Value, reason ...interface{}) error {
+	return awaitCancel(reader.Call("cancel", reason...), reader.Get("closed"))
+}
+
+// awaitCancel waits for cancelled, the promise of cancelling a stream through a reader, and returns the error it failed
+// to cancel with, if any, unless closed, the reader's closed promise, shows that the stream had already errored, as for
+// cancelReader.
+func awaitCancel(cancelled, closed js.Value) error {
+	_, err := await(cancelled)
+	if err != nil {
+		if _, closedErr := await(closed); closedErr != nil {
+			return nil
+		}
+	}
+	return err
 }
 
 // NewReadableStream creates a new ReadableStream from a JavaScript ReadableStream.
@@ -85,133 +561,1356 @@ func NewReadableStream(stream js.Value) *ReadableStream {
 	return &ReadableStream{stream: stream}
 }
 
+// Reset rebinds the ReadableStream to a different JavaScript ReadableStream, so that the wrapper can be reused, for
+// instance from a pool, rather than allocating a new one per stream. It is meant to be called once the previous stream
+// has been read to its end or closed, as it doesn't cancel it. All state from the previous stream is discarded: the
+// stream is no longer closed, any Reader still held is released, and any leftover data is dropped. Anything waiting in
+// WaitClosed for the previous stream is woken up. Options the wrapper was created with still apply.
+func (r *ReadableStream) Reset(stream js.Value) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.reader != nil && !r.reader.released {
+		r.reader.releaseLock()
+	}
+	r.releaseScratch()
+	r.finished.finish(nil)
+
+	r.stream = stream
+	r.reader = nil
+	r.interrupt.Lock()
+	r.interrupted, r.cancelled, r.cancelledClosed = false, js.Undefined(), js.Undefined()
+	r.interrupt.Unlock()
+	r.byobProbed = false
+	r.ended = false
+	r.closed.Store(false)
+	r.finished = closeNotifier{}
+	r.bytesRead.Store(0)
+	r.readRate.reset()
+	r.expectedLength.Store(nil)
+}
+
+// FillMode decides whether a Read returns as soon as some data is available, or waits until its buffer is full.
+type FillMode int
+
+const (
+	// FillPartial makes Read return whatever data is available as soon as there is some, even if it is less than was
+	// asked for. This is the default, and how io.Reader is conventionally implemented.
+	FillPartial FillMode = iota
+	// FillComplete makes Read keep reading until the buffer passed to it is full, or the stream ends, in which case it
+	// returns what it has, and the next Read returns io.EOF. This makes for fewer, larger reads, like calling ReadFull
+	// every time, except that reaching the end part way through is not an error.
+	FillComplete
+)
+
+// ErrNotReadableStream is returned by NewReadableStreamChecked if the value is not a JavaScript ReadableStream.
+var ErrNotReadableStream = errors.New("value must be a ReadableStream, with a getReader method")
+
+// NewReadableStreamChecked creates a new ReadableStream from a JavaScript ReadableStream, like NewReadableStream, but
+// first checks that stream is an object with a getReader method, returning ErrNotReadableStream if it isn't. This catches
+// a bad value, such as undefined, straight away, rather than with a confusing panic in the first Read.
+func NewReadableStreamChecked(stream js.Value) (*ReadableStream, error) {
+	if !hasMethods(stream, "getReader") {
+		return nil, ErrNotReadableStream
+	}
+	return NewReadableStream(stream), nil
+}
+
+// hasMethods reports whether value is an object with every one of the named methods.
+func hasMethods(value js.Value, methods ...string) bool {
+	if value.Type() != js.TypeObject && value.Type() != js.TypeFunction {
+		return false
+	}
+	for _, method := range methods {
+		if value.Get(method).Type() != js.TypeFunction {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadableStreamOptions configures a ReadableStream created with NewReadableStreamWithOptions.
+type ReadableStreamOptions struct {
+	// ScratchPool, if set, provides the buffers that hold the part of a chunk that didn't fit into the slice passed to
+	// Read, which only happens when reading through a default reader. The pool must return *[]byte values, and can be
+	// shared between streams. A buffer is borrowed when a chunk overflows, and put back in the same Read that hands out
+	// the last of its data, or when the stream is closed, so it is never retained once its data has been consumed, and is
+	// never handed to the caller. Without a pool, a new buffer is allocated for every chunk that overflows.
+	ScratchPool *sync.Pool
+	// BYOBBufferSize, if positive, makes BYOB reads go into a single ArrayBuffer of this size, kept by the stream for its
+	// whole life, instead of a new one allocated for every Read. The stream owns the buffer: it is transferred to the
+	// JavaScript stream for the duration of each read, which hands it back as the buffer of the view it resolves with,
+	// and its contents are copied into the slice passed to Read before it is used again. It is never exposed, so nothing
+	// else can observe it. A Read returns at most BYOBBufferSize bytes. If a read fails, the buffer is lost with it, and a
+	// new one is allocated for the next read.
+	BYOBBufferSize int
+	// SharedBYOBBuffer makes BYOB reads go into a single 32 KiB ArrayBuffer shared by every stream created with this
+	// option, rather than into a buffer allocated for each Read, or one kept by each stream as BYOBBufferSize does, for
+	// memory-constrained applications with many streams. The price is parallelism: a read holds the shared buffer until
+	// it completes, so reads of all of the streams sharing it are serialised, and a read waiting for data holds up every
+	// other one. Only streams whose reads complete promptly should share it, and never two streams where data for one
+	// only arrives once the other has been read, such as the two ends of a pipe or the branches of a tee, as reading them
+	// from different goroutines would then deadlock, until MaxReadWait runs out. A Read returns at most 32 KiB. It takes
+	// precedence over BYOBBufferSize, AllocBuffer and ReleaseBuffer.
+	SharedBYOBBuffer bool
+	// AllocBuffer, if set, is asked for the Uint8Array each BYOB read is made into, with the number of bytes the read
+	// wants, rather than a new one being allocated, so that reads can draw on a pool of buffers managed elsewhere. A view
+	// larger than n is only read into up to n bytes, and a smaller one makes the read return fewer bytes. A BYOB read
+	// transfers the buffer of the view it is given, detaching it, so the buffer can't be used again through that view,
+	// or any other made before the read. AllocBuffer is ignored if BYOBBufferSize is set.
+	AllocBuffer func(n int) js.Value
+	// ReleaseBuffer, if set, is called with the view each BYOB read resolved with, once its contents have been copied
+	// out, whether the view came from AllocBuffer or not. Its buffer is the one the read was given, transferred to a new
+	// ArrayBuffer, so this is where a pool filled by AllocBuffer gets its buffers back. The view may be empty once the
+	// stream has ended.
+	ReleaseBuffer func(view js.Value)
+	// TreatEmptyChunkAsEOF makes Read report io.EOF when it receives an empty chunk, as if the stream had ended, for
+	// sources that signal their end that way instead of closing. The stream itself carries on, so its done flag is never
+	// seen, and the next Read reads the chunk after the empty one, if there is one. By default, empty chunks are skipped,
+	// and only done ends the stream, which is what the Streams specification intends and is recommended. Only streams
+	// read with a default reader can yield empty chunks, as byte streams don't allow them to be enqueued.
+	TreatEmptyChunkAsEOF bool
+	// FillMode decides whether Read returns as soon as any data arrives, which is the default, or waits until it has
+	// filled its buffer.
+	FillMode FillMode
+	// CloseOnFinalize sets a finalizer on the ReadableStream that closes it, cancelling the JavaScript stream, if it is
+	// garbage collected without having been closed, and logs EventLeaked. This is a safety net against leaking a stream's
+	// source, not a replacement for calling Close: there is no telling when, or even whether, the finalizer runs, and a
+	// stream that still holds a Reader is never finalized, as the two refer to each other.
+	CloseOnFinalize bool
+	// StrictSingleReader makes Read and ReadByte fail straight away with ErrStreamBusy if another read of the stream is
+	// already in progress, rather than waiting for it to finish. Reads are always serialised, so they never interleave
+	// part way through, but which of two concurrent reads gets the next chunk is down to chance. For a protocol that
+	// expects a single consumer, this surfaces two goroutines reading at once as an error instead. Reads that follow one
+	// another are not affected, whichever goroutine makes them.
+	StrictSingleReader bool
+	// MaxReadWait is the longest a single read waits for the JavaScript stream before giving up on it, as a safety net
+	// against a broken source whose read promise never settles, which would otherwise leave the reading goroutine, and
+	// anything waiting on the stream's lock, blocked forever. A read that runs into it releases its reader, after
+	// cancelling it, which settles the read if the source is at all well-behaved, and fails with ErrReadStalled. This is
+	// not a deadline for slow sources: a source that is merely waiting on data should be read with ReadContext instead.
+	// If MaxReadWait is 0, DefaultMaxReadWait is used, and if it is negative, reads wait for as long as it takes.
+	MaxReadWait time.Duration
+}
+
+// DefaultMaxReadWait is how long a read waits for the JavaScript stream before failing with ErrReadStalled, unless the
+// stream was created with a different ReadableStreamOptions.MaxReadWait. It is long enough that no source that is
+// working, however slowly, runs into it.
+const DefaultMaxReadWait = 10 * time.Minute
+
+// ErrReadStalled is returned by a read that waited longer than MaxReadWait for the JavaScript stream.
+var ErrReadStalled = errors.New("read did not settle within the maximum wait")
+
+// NewReadableStreamWithOptions creates a new ReadableStream from a JavaScript ReadableStream, configured by options.
+func NewReadableStreamWithOptions(stream js.Value, options ReadableStreamOptions) *ReadableStream {
+	r := &ReadableStream{
+		stream:        stream,
+		scratch:       options.ScratchPool,
+		byobSize:      options.BYOBBufferSize,
+		sharedBYOB:    options.SharedBYOBBuffer,
+		allocBuffer:   options.AllocBuffer,
+		releaseBuffer: options.ReleaseBuffer,
+		emptyIsEOF:    options.TreatEmptyChunkAsEOF,
+		fillMode:      options.FillMode,
+		strict:        options.StrictSingleReader,
+		maxReadWait:   options.MaxReadWait,
+	}
+	if options.CloseOnFinalize {
+		runtime.SetFinalizer(r, finalizeReadableStream)
+	}
+	return r
+}
+
+// finalizeReadableStream closes r if it was garbage collected without having been closed, as CloseOnFinalize.
+func finalizeReadableStream(r *ReadableStream) {
+	if r.closed.Load() {
+		return
+	}
+	if Logger != nil {
+		Logger(EventLeaked, map[string]interface{}{"stream": "readable"})
+	}
+	_ = r.Close()
+}
+
 // WritableStream implements io.WriteCloser for a JavaScript WritableStream.
 type WritableStream struct {
-	stream js.Value
-	lock   sync.Mutex
+	stream       js.Value
+	lock         sync.Mutex
+	closed       atomic.Bool
+	finished     closeNotifier
+	bytesWritten atomic.Int64
+	writeRate    rateMeter
+
+	// autoFlush makes every write wait for the stream to be ready again, as AutoFlush.
+	autoFlush bool
+	// flush is the Flush method of the Go writer behind the stream, if it has one.
+	flush func() error
+	// fileSystem is set for a stream created by FileSystemWriter, whose sink takes the commands of SeekFile and
+	// TruncateFile as well as data.
+	fileSystem bool
+	// writer is the writer passed to NewWritableStreamFromWriter, for a stream created from one, whose stream is then
+	// undefined.
+	writer js.Value
+
+	// reuseBuffer makes writes copy each chunk into writeBuffer, rather than a new Uint8Array, as ReuseBuffer.
+	reuseBuffer bool
+	writeBuffer js.Value
+
+	// async makes Write return without waiting for the chunk to be written, as AsyncWrite. asyncWriter is the writer held
+	// for those writes until the stream is closed, onWritten and onFailed are called as each of them settles, asyncErr is
+	// the error the first of them to fail failed with, guarded by asyncLock, and errs delivers it.
+	async       bool
+	asyncWriter js.Value
+	onWritten   js.Func
+	onFailed    js.Func
+	asyncLock   sync.Mutex
+	asyncErr    error
+	errs        chan error
 }
 
 // Write writes len(p) bytes from p to the underlying data stream. It returns the number of bytes written from p (0 <= n <= len(p))
 // and any error encountered that caused the write to stop early. Write must return a non-nil error if it returns n < len(p).
-// Write must not modify the slice data, even temporarily.
+// Write must not modify the slice data, even temporarily. Once the stream has been closed, Write returns io.ErrClosedPipe.
 func (w *WritableStream) Write(p []byte) (n int, err error) {
+	if DebugContractChecks {
+		// This is deferred first, so that the panic isn't recovered below.
+		defer func() {
+			if violation := checkWrite(w, p, n, err); violation != nil {
+				panic(violation)
+			}
+		}()
+	}
 	defer func() {
 		recovered := recover()
 		if recovered != nil {
 			err = fmt.Errorf("panic: %v", recovered)
 		}
+		if Logger != nil && err != nil {
+			Logger(EventError, map[string]interface{}{"stream": "writable", "error": err})
+		}
 	}()
 
 	w.lock.Lock()
-	var waitGroup sync.WaitGroup
-	waitGroup.Add(2)
-
-	writer := w.stream.Call("getWriter")
-
-	writer.Get("ready").Call("then", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		defer waitGroup.Done()
-
-		buffer := js.Global().Get("Uint8Array").New(len(p))
-		js.CopyBytesToJS(buffer, p)
+	defer w.lock.Unlock()
 
-		writeResult := writer.Call("write", buffer)
-
-		writeResult.Call("then", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-			defer waitGroup.Done()
-			n = len(p)
-			return nil
-		}))
-
-		writeResult.Call("catch", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-			defer waitGroup.Done()
-			err = errors.New(args[0].Get("message").String())
-			return nil
-		}))
+	if w.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if w.async {
+		return w.writeAsync(p)
+	}
 
-		return nil
-	}))
+	writer, err := w.getWriter()
+	if err != nil {
+		return 0, err
+	}
+	defer w.releaseWriter(writer)
 
-	waitGroup.Wait()
-	writer.Call("releaseLock")
-	w.lock.Unlock()
+	err = w.write(writer, p)
+	if err != nil {
+		// A write is only rejected if the stream has errored.
+		w.finished.finish(err)
+		return 0, err
+	}
+	w.addBytesWritten(int64(len(p)))
 
-	return n, err
+	return len(p), nil
 }
 
-// Close closes the WritableStream. If the stream is already closed, Close does nothing.
-func (w *WritableStream) Close() (err error) {
+// ReadFrom implements io.ReaderFrom, writing everything read from src to the stream, one chunk at a time, until src
+// returns io.EOF. It holds a single writer for the whole copy, waiting for the stream to be ready before each chunk. It
+// returns the number of bytes successfully written to the stream, so if a write fails, n only covers the chunks before
+// it. io.Copy uses ReadFrom automatically when copying to a WritableStream.
+func (w *WritableStream) ReadFrom(src io.Reader) (n int64, err error) {
 	defer func() {
-		// We don't want any errors to be thrown if the stream is already closed.
-		recovery := recover()
-		if !strings.Contains(fmt.Sprint(recovery), "Can not close stream after closing or error") {
-			if recovery != nil {
-				err = fmt.Errorf("panic: %v", recovery)
-			}
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+		if Logger != nil && err != nil {
+			Logger(EventError, map[string]interface{}{"stream": "writable", "error": err})
 		}
 	}()
 
 	w.lock.Lock()
-	w.stream.Call("close")
-	w.lock.Unlock()
+	defer w.lock.Unlock()
 
-	return nil
-}
+	if w.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
 
-// NewWritableStream creates a new WritableStream. If a JavaScript WritableStream is provided, it will be used.
-// Otherwise, a new WritableStream will be created.
-func NewWritableStream(stream ...js.Value) *WritableStream {
-	if len(stream) > 0 {
-		return &WritableStream{stream: stream[0]}
-	} else {
-		stream := js.Global().Get("WritableStream").New()
-		return &WritableStream{stream: stream}
+	writer, err := w.getWriter()
+	if err != nil {
+		return 0, err
+	}
+	defer w.releaseWriter(writer)
+
+	buffer := make([]byte, defaultChunkSize)
+	for {
+		read, readErr := src.Read(buffer)
+		if read > 0 {
+			err = w.write(writer, buffer[:read])
+			if err != nil {
+				w.finished.finish(err)
+				return n, err
+			}
+			n += int64(read)
+			w.addBytesWritten(int64(read))
+		}
+		if readErr == io.EOF {
+			return n, nil
+		}
+		if readErr != nil {
+			return n, readErr
+		}
 	}
 }
 
-// Now we do the vice versa: Reader to ReadableStream and Writer to WritableStream.
+// writeAsync hands a copy of p to the stream without waiting for it to be written, as AsyncWrite, unless the stream is
+// applying backpressure, in which case it waits for the stream to be ready first. The caller must hold the stream's lock.
+func (w *WritableStream) writeAsync(p []byte) (int, error) {
+	if err := w.asyncError(); err != nil {
+		return 0, err
+	}
 
-// ReaderToReadableStream converts an io.Reader to a JavaScript ReadableStream.
-func ReaderToReadableStream(r io.Reader) js.Value {
-	return js.Global().Get("ReadableStream").New(map[string]interface{}{
-		"pull": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-			readController := args[0]
-			return js.Global().Get("Promise").New(js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-				var buffer []byte
-				buffer, err := io.ReadAll(r)
-				if err != nil {
-					panic(err.Error())
-				}
-				if len(buffer) == 0 {
-					readController.Call("close")
-					return nil
-				}
-				jsBuffer := js.Global().Get("Uint8Array").New(len(buffer))
-				js.CopyBytesToJS(jsBuffer, buffer)
-				readController.Call("enqueue", jsBuffer)
-				readController.Call("close")
-				args[0].Invoke()
+	if w.asyncWriter.IsUndefined() {
+		writer, err := w.getWriter()
+		if err != nil {
+			return 0, err
+		}
+		w.asyncWriter = writer
+	}
+
+	desiredSize := w.asyncWriter.Get("desiredSize")
+	if desiredSize.Type() != js.TypeNumber || desiredSize.Float() <= 0 {
+		if _, err := await(w.asyncWriter.Get("ready")); err != nil {
+			w.failAsync(err)
+			return 0, err
+		}
+	}
+
+	// The chunk is still queued once we return, so it can't be the reused buffer.
+	chunk := js.Global().Get("Uint8Array").New(len(p))
+	js.CopyBytesToJS(chunk, p)
+	w.asyncWriter.Call("write", chunk).Call("then", w.onWritten.Call("bind", nil, len(p)), w.onFailed)
+	return len(p), nil
+}
+
+// asyncError returns the error the first asynchronous write to fail failed with, if any have.
+func (w *WritableStream) asyncError() error {
+	w.asyncLock.Lock()
+	defer w.asyncLock.Unlock()
+
+	return w.asyncErr
+}
+
+// failAsync records that an asynchronous write failed with err, delivering it to Errors if it is the first to fail.
+func (w *WritableStream) failAsync(err error) {
+	w.asyncLock.Lock()
+	defer w.asyncLock.Unlock()
+
+	if w.asyncErr != nil {
+		return
+	}
+	w.asyncErr = err
+	w.errs <- err
+	w.finished.finish(err)
+	if Logger != nil {
+		Logger(EventError, map[string]interface{}{"stream": "writable", "error": err})
+	}
+}
+
+// Errors returns a channel that receives the error the first failed write failed with, for a stream created with
+// AsyncWrite, whose writes return before they have been written, so can't return the error themselves. Only the first
+// error is delivered, as a stream that has failed fails every write after it, and the channel is never closed. For any
+// other stream, Errors returns nil, as every error is returned by the Write it happened in.
+func (w *WritableStream) Errors() <-chan error {
+	return w.errs
+}
+
+// getWriter acquires a writer for the stream, returning ErrStreamLocked if the stream is already locked to another one,
+// or returns the stream's writer if it was created from one, or the writer held for asynchronous writes, if there is
+// one. The caller must hold the stream's lock, and release the writer with releaseWriter once it's done.
+func (w *WritableStream) getWriter() (js.Value, error) {
+	if w.stream.IsUndefined() {
+		return w.writer, nil
+	}
+	if !w.asyncWriter.IsUndefined() {
+		return w.asyncWriter, nil
+	}
+	if w.stream.Get("locked").Bool() {
+		return js.Undefined(), ErrStreamLocked
+	}
+	return w.stream.Call("getWriter"), nil
+}
+
+// releaseWriter releases the lock of a writer acquired by getWriter, unless it is the writer the stream was created
+// from, whose lock isn't ours to release, or the writer held for asynchronous writes, which is only released by Close.
+func (w *WritableStream) releaseWriter(writer js.Value) {
+	if !w.stream.IsUndefined() && !writer.Equal(w.asyncWriter) {
+		writer.Call("releaseLock")
+	}
+}
+
+// write writes p to writer as a single chunk with writeChunk, then, if the stream has AutoFlush set, waits for writer to
+// be ready again.
+func (w *WritableStream) write(writer js.Value, p []byte) error {
+	defer acquireOp()()
+
+	err := writeChunk(writer, w.chunk(p))
+	if err != nil || !w.autoFlush {
+		return err
+	}
+
+	_, err = await(writer.Get("ready"))
+	return err
+}
+
+// maxRetainedWriteBuffer is the largest buffer a WritableStream with ReuseBuffer keeps through Reset. A bigger one is
+// dropped, so that a single large write doesn't pin its buffer for as long as the wrapper sits in a pool.
+const maxRetainedWriteBuffer = 4 * defaultChunkSize
+
+// chunk copies p into JavaScript memory, to be written to the sink. The chunk is always a copy, never a view of p,
+// because the sink is free to modify or transfer the chunk it is given, and Write must not modify p. Normally it is a
+// new Uint8Array for every write, but with ReuseBuffer, it is a view of the stream's buffer, which is allocated once and
+// only replaced when a write doesn't fit, or when the sink transferred it, which leaves it empty. The caller must hold
+// the stream's lock, and be done with the previous chunk.
+func (w *WritableStream) chunk(p []byte) js.Value {
+	if !w.reuseBuffer {
+		chunk := js.Global().Get("Uint8Array").New(len(p))
+		js.CopyBytesToJS(chunk, p)
+		return chunk
+	}
+
+	if w.writeBuffer.IsUndefined() || w.writeBuffer.Get("byteLength").Int() < len(p) {
+		w.writeBuffer = js.Global().Get("Uint8Array").New(max(len(p), defaultChunkSize))
+	}
+	chunk := w.writeBuffer.Call("subarray", 0, len(p))
+	js.CopyBytesToJS(chunk, p)
+	return chunk
+}
+
+// writeChunk waits for writer to be ready, then writes chunk to it, waiting for the write to complete. If the stream has
+// room for more data, its ready promise has already resolved, so it isn't waited on, which spares a trip through the
+// event loop on every write to a stream that isn't backpressured.
+func writeChunk(writer js.Value, chunk js.Value) error {
+	// The desired size is null if the stream has errored, in which case waiting on ready gives us its error.
+	desiredSize := writer.Get("desiredSize")
+	if desiredSize.Type() != js.TypeNumber || desiredSize.Float() <= 0 {
+		_, err := await(writer.Get("ready"))
+		if err != nil {
+			return err
+		}
+	}
+	if Logger != nil {
+		Logger(EventWriterReady, map[string]interface{}{"stream": "writable"})
+	}
+
+	_, err := await(writer.Call("write", chunk))
+	if err != nil {
+		return err
+	}
+	if Logger != nil {
+		Logger(EventWriteComplete, map[string]interface{}{"stream": "writable", "size": chunk.Length()})
+	}
+
+	return nil
+}
+
+// JSValue returns the underlying JavaScript WritableStream, so that it can be handed to JavaScript APIs.
+func (w *WritableStream) JSValue() js.Value {
+	return w.stream
+}
+
+// BytesWritten returns the total number of bytes written to the stream so far, counting only writes that succeeded. It
+// is safe to call at any time, including while a write is in progress, to report progress or collect metrics.
+func (w *WritableStream) BytesWritten() int64 {
+	return w.bytesWritten.Load()
+}
+
+// Locked reports whether the underlying JavaScript WritableStream is locked to a writer, in which case it can't be written
+// to by anything other than that writer. Streams are only locked by this package while a Write is in progress.
+func (w *WritableStream) Locked() bool {
+	if w.stream.IsUndefined() {
+		// The stream was created from a writer, which holds its lock.
+		return true
+	}
+	return w.stream.Get("locked").Bool()
+}
+
+// Reset rebinds the WritableStream to a different JavaScript WritableStream, so that the wrapper can be reused, for
+// instance from a WritableStreamPool, rather than allocating a new one per stream. It is meant to be called once the
+// previous stream has been closed, as it doesn't close it. All state from the previous stream is discarded: the stream
+// is no longer closed, BytesWritten starts again from 0, and with AsyncWrite, any failed write is forgotten, and Errors
+// returns a new channel. Anything waiting in WaitClosed for the previous stream is woken up. Options the wrapper was
+// created with still apply, and with ReuseBuffer, its buffer is kept.
+func (w *WritableStream) Reset(stream js.Value) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.finished.finish(nil)
+	if !w.writeBuffer.IsUndefined() && w.writeBuffer.Get("byteLength").Int() > maxRetainedWriteBuffer {
+		w.writeBuffer = js.Undefined()
+	}
+
+	w.stream = stream
+	w.writer = js.Undefined()
+	w.flush = nil
+	w.fileSystem = false
+	w.asyncWriter = js.Undefined()
+	if w.async {
+		w.asyncLock.Lock()
+		w.asyncErr = nil
+		w.errs = make(chan error, 1)
+		w.asyncLock.Unlock()
+	}
+	w.closed.Store(false)
+	w.finished = closeNotifier{}
+	w.bytesWritten.Store(0)
+	w.writeRate.reset()
+}
+
+// Close closes the WritableStream, blocking until everything written to it has been flushed to the underlying sink and the
+// sink has closed. It returns an error if the sink fails to close. If the stream is already closed, Close does nothing.
+// It is safe to call Close multiple times, including concurrently, and the underlying JavaScript stream will only be
+// closed once.
+func (w *WritableStream) Close() (err error) {
+	defer func() {
+		// We don't want any errors to be thrown if the stream was already closed by something other than us.
+		if recoveryErr := closeRecovery(recover()); recoveryErr != nil {
+			err = recoveryErr
+		}
+	}()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed.Load() {
+		return nil
+	}
+
+	// Closing through a writer lets us wait until the sink has finished with everything written before it.
+	writer, err := w.getWriter()
+	if err != nil {
+		return err
+	}
+	defer w.releaseWriter(writer)
+
+	w.closed.Store(true)
+	if Logger != nil {
+		Logger(EventClose, map[string]interface{}{"stream": "writable"})
+	}
+
+	_, err = await(writer.Call("close"))
+	if err != nil {
+		// If the stream had already been closed by something other than us, its writer's closed promise is fulfilled,
+		// otherwise the stream has errored and the close genuinely failed.
+		if _, closedErr := await(writer.Get("closed")); closedErr == nil {
+			err = nil
+		}
+	}
+	if !w.asyncWriter.IsUndefined() {
+		// Closing waited for every asynchronous write, so nothing is using the writer any more.
+		w.asyncWriter.Call("releaseLock")
+		w.asyncWriter = js.Undefined()
+		if asyncErr := w.asyncError(); asyncErr != nil {
+			err = asyncErr
+		}
+	}
+	w.finished.finish(err)
+
+	return err
+}
+
+// abort aborts the WritableStream with reason, discarding anything written to it that the sink hasn't taken yet, rather
+// than flushing it as Close does, and marks the stream as finished with err, reason's counterpart in Go. If the stream is
+// already closed, abort does nothing.
+func (w *WritableStream) abort(reason js.Value, err error) (abortErr error) {
+	defer func() {
+		if recoveryErr := closeRecovery(recover()); recoveryErr != nil {
+			abortErr = recoveryErr
+		}
+	}()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed.Load() {
+		return nil
+	}
+
+	writer, abortErr := w.getWriter()
+	if abortErr != nil {
+		return abortErr
+	}
+	defer w.releaseWriter(writer)
+
+	w.closed.Store(true)
+	if Logger != nil {
+		Logger(EventClose, map[string]interface{}{"stream": "writable"})
+	}
+
+	_, abortErr = await(writer.Call("abort", reason))
+	if !w.asyncWriter.IsUndefined() {
+		w.asyncWriter.Call("releaseLock")
+		w.asyncWriter = js.Undefined()
+	}
+	w.finished.finish(err)
+	return abortErr
+}
+
+// DesiredSize returns the amount of data the WritableStream's internal queue can accept before it is considered full,
+// which may be negative if the queue is overfull. It returns false if the stream is errored or its desired size is
+// otherwise unavailable. The desired size is read at the time of the call and isn't guaranteed to remain accurate.
+func (w *WritableStream) DesiredSize() (size int, ok bool) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			size, ok = 0, false
+		}
+	}()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	writer, err := w.getWriter()
+	if err != nil {
+		return 0, false
+	}
+	desiredSize := writer.Get("desiredSize")
+	w.releaseWriter(writer)
+
+	if desiredSize.IsNull() || desiredSize.IsUndefined() {
+		return 0, false
+	}
+
+	return desiredSize.Int(), true
+}
+
+// Ready blocks until the WritableStream is ready to accept more data, that is, until its desired size is positive. It
+// returns an error if the stream errors while waiting. Like Write, Ready must be called from a goroutine in a WASM
+// environment.
+func (w *WritableStream) Ready() (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	writer, err := w.getWriter()
+	if err != nil {
+		return err
+	}
+	_, err = await(writer.Get("ready"))
+	w.releaseWriter(writer)
+	if err != nil {
+		// The ready promise only rejects if the stream has errored.
+		w.finished.finish(err)
+	}
+
+	return err
+}
+
+// NewWritableStream creates a new WritableStream. If a JavaScript WritableStream is provided, it will be used.
+// Otherwise, a new WritableStream will be created, with no sink and the default queuing strategy, which has a
+// highWaterMark of 1 chunk. Use NewWritableStreamWithStrategy to create a stream with a different highWaterMark.
+func NewWritableStream(stream ...js.Value) *WritableStream {
+	if len(stream) > 0 {
+		return &WritableStream{stream: stream[0]}
+	} else {
+		stream := js.Global().Get("WritableStream").New()
+		return &WritableStream{stream: stream}
+	}
+}
+
+// ErrNotWritableStream is returned by NewWritableStreamChecked if the value is not a JavaScript WritableStream.
+var ErrNotWritableStream = errors.New("value must be a WritableStream, with getWriter and close methods")
+
+// NewWritableStreamChecked creates a new WritableStream from a JavaScript WritableStream, like NewWritableStream, but
+// first checks that stream is an object with getWriter and close methods, returning ErrNotWritableStream if it isn't.
+// This catches a bad value, such as undefined, straight away, rather than with a confusing panic in the first Write.
+func NewWritableStreamChecked(stream js.Value) (*WritableStream, error) {
+	if !hasMethods(stream, "getWriter", "close") {
+		return nil, ErrNotWritableStream
+	}
+	return NewWritableStream(stream), nil
+}
+
+// ErrNotWriter is returned by NewWritableStreamFromWriter if the value is not a WritableStreamDefaultWriter.
+var ErrNotWriter = errors.New("value must be a WritableStreamDefaultWriter")
+
+// NewWritableStreamFromWriter creates a new WritableStream from a JavaScript WritableStreamDefaultWriter that has already
+// been acquired, for when the stream itself isn't available, such as when integrating with code that hands out writers.
+// Every Write goes through the writer directly, without acquiring a writer of its own. The writer's lock is never
+// released, as it belongs to whoever acquired it, Locked always returns true, and JSValue returns undefined. If writer is
+// not a writer, ErrNotWriter is returned.
+func NewWritableStreamFromWriter(writer js.Value) (*WritableStream, error) {
+	if writer.Type() != js.TypeObject || !isInstance(writer, "WritableStreamDefaultWriter") {
+		return nil, ErrNotWriter
+	}
+	return &WritableStream{stream: js.Undefined(), writer: writer}, nil
+}
+
+// ErrNegativeHighWaterMark is returned by NewWritableStreamWithStrategy if the provided highWaterMark is negative.
+var ErrNegativeHighWaterMark = errors.New("highWaterMark must not be negative")
+
+// WritableStreamOptions configures a WritableStream created with NewWritableStreamWithOptions.
+type WritableStreamOptions struct {
+	// AutoFlush makes every Write, and every chunk written by ReadFrom, wait until the stream is ready for more data before
+	// returning, on top of waiting for the sink to accept the chunk, which every write does. When a Write returns, the
+	// chunk has then not only been committed to the sink, but nothing is left in the stream's queue and the stream isn't
+	// applying backpressure, which suits interactive output, such as server-sent events, where each write should be
+	// delivered before the next is produced. The cost is throughput, as every write waits for an extra Promise, and a
+	// Write on a stream under backpressure doesn't return until it is relieved, rather than leaving the wait to the next
+	// Write. Without AutoFlush, a Write returns as soon as the sink has accepted the chunk.
+	AutoFlush bool
+	// CloseOnFinalize sets a finalizer on the WritableStream that closes it, if it is garbage collected without having
+	// been closed, and logs EventLeaked, as ReadableStreamOptions.CloseOnFinalize does. The close happens in the
+	// background, as it has to wait for the sink, so its error, if any, is lost.
+	CloseOnFinalize bool
+	// ReuseBuffer makes writes copy each chunk into a Uint8Array kept by the WritableStream, and hand the sink a view of
+	// it, rather than allocating a new one for every write. The buffer survives Reset, unless it has grown past 128 KiB,
+	// so a wrapper reused through a WritableStreamPool allocates it once, rather than once per write to every stream.
+	// This is only safe with a sink that is done with each chunk once its write has completed, such as one that copies
+	// the data out or sends it straight away, as the next write overwrites it. A sink that keeps chunks, or hands them on
+	// to be processed later, such as the sink of a TransformStream, sees them change under it. A sink that transfers the
+	// buffer is fine, as a new one is allocated after it.
+	ReuseBuffer bool
+	// AsyncWrite makes Write hand each chunk to the stream and return len(p), nil straight away, without waiting for the
+	// sink to accept it, for throughput-bound output where waiting on every write is too costly, such as fire-and-forget
+	// logging. This breaks the io.Writer contract: a Write that returns nil may still fail. Such a failure is delivered
+	// by Errors, returned by every later Write, and returned by Close, which waits for every chunk to be written, so
+	// Close is the only point at which the data is known to have been written. Write still waits while the stream is
+	// applying backpressure, so the queue can't grow without bound. The stream is locked for as long as it is open, and
+	// ReadFrom is unaffected, as it already waits for each chunk in turn. ReuseBuffer doesn't apply to these writes, as
+	// their chunks are still queued after Write returns.
+	AsyncWrite bool
+}
+
+// NewWritableStreamWithOptions creates a new WritableStream from a JavaScript WritableStream, configured by options.
+func NewWritableStreamWithOptions(stream js.Value, options WritableStreamOptions) *WritableStream {
+	w := &WritableStream{stream: stream, autoFlush: options.AutoFlush, reuseBuffer: options.ReuseBuffer}
+	if options.AsyncWrite {
+		w.startAsync()
+	}
+	if options.CloseOnFinalize {
+		runtime.SetFinalizer(w, finalizeWritableStream)
+	}
+	return w
+}
+
+// startAsync sets w up for AsyncWrite.
+func (w *WritableStream) startAsync() {
+	w.async = true
+	w.errs = make(chan error, 1)
+	w.onWritten = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		// The size of the chunk is bound as the first argument.
+		w.addBytesWritten(int64(args[0].Int()))
+		return nil
+	})
+	w.onFailed = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		w.failAsync(jsErrorToGo(args[0]))
+		return nil
+	})
+}
+
+// finalizeWritableStream closes w if it was garbage collected without having been closed, as CloseOnFinalize. Finalizers
+// must not block, so the close carries on in its own goroutine.
+func finalizeWritableStream(w *WritableStream) {
+	if w.closed.Load() {
+		return
+	}
+	if Logger != nil {
+		Logger(EventLeaked, map[string]interface{}{"stream": "writable"})
+	}
+	go w.Close()
+}
+
+// NewWritableStreamWithStrategy creates a new JavaScript WritableStream with the given underlying sink, which may be
+// js.Undefined() for a stream with no sink, and a queuing strategy with the given highWaterMark. The highWaterMark is the
+// number of chunks the stream will queue before applying backpressure, which is reported through DesiredSize and Ready.
+// A highWaterMark of 0 applies backpressure as soon as anything is written.
+func NewWritableStreamWithStrategy(sink js.Value, highWaterMark float64) (*WritableStream, error) {
+	if highWaterMark < 0 {
+		return nil, ErrNegativeHighWaterMark
+	}
+
+	stream := js.Global().Get("WritableStream").New(sink, map[string]interface{}{
+		"highWaterMark": highWaterMark,
+	})
+	return &WritableStream{stream: stream}, nil
+}
+
+// Now we do the vice versa: Reader to ReadableStream and Writer to WritableStream.
+
+// ReaderToReadableStream converts an io.Reader to a JavaScript ReadableStream. The reader is read lazily, in chunks of
+// up to 32 KiB, as the JavaScript side pulls from the stream. If the JavaScript side cancels the stream, the provided
+// cancel function is called, so that any resources held by the reader can be released. If no cancel function is provided
+// and r implements io.Closer, r is closed instead, which unblocks a Read of r that a pull is stuck in, if closing r does
+// so, as it does for an io.PipeReader, rather than leaving its goroutine waiting for data that will never be consumed.
+func ReaderToReadableStream(r io.Reader, cancel ...func()) js.Value {
+	return ReaderToReadableStreamSize(r, defaultChunkSize, cancel...)
+}
+
+// ReaderToReadableStreamSize converts an io.Reader to a JavaScript ReadableStream, like ReaderToReadableStream, but reads
+// up to chunkSize bytes per pull. Smaller chunks reduce latency, whereas larger chunks reduce the number of round trips
+// between Go and JavaScript. If chunkSize is not positive, the default of 32 KiB is used. Each pull reads from r in a
+// separate goroutine, so r is free to block without stalling the JavaScript event loop.
+func ReaderToReadableStreamSize(r io.Reader, chunkSize int, cancel ...func()) js.Value {
+	return readerToReadableStream(context.Background(), r, chunkSize, cancel, nil, nil, 0)
+}
+
+// ReaderToReadableStreamContext converts an io.Reader to a JavaScript ReadableStream, like ReaderToReadableStream, but
+// ties the stream to ctx. Once ctx is done, r is no longer read from, the stream is errored with ctx's error, and the
+// reader is released as if the stream had been cancelled, which unblocks a pending Read if closing r does so.
+func ReaderToReadableStreamContext(ctx context.Context, r io.Reader, cancel ...func()) js.Value {
+	return readerToReadableStream(ctx, r, defaultChunkSize, cancel, nil, nil, 0)
+}
+
+// StaticReadableStream creates a JavaScript ReadableStream that yields data as a single chunk and then closes, for data
+// that is already fully available, such as a response body built in memory. Unlike ReaderToReadableStream, nothing
+// happens on demand: the chunk is enqueued and the stream closed as soon as it starts, so there is no pull to race with
+// the stream being closed or cancelled. data is copied, so it can be modified once StaticReadableStream returns. If data
+// is empty, the stream closes without yielding anything.
+func StaticReadableStream(data []byte) js.Value {
+	return js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			controller := args[0]
+			// A byte stream throws if it is asked to enqueue an empty chunk.
+			if len(data) > 0 {
+				chunk := js.Global().Get("Uint8Array").New(len(data))
+				js.CopyBytesToJS(chunk, data)
+				controller.Call("enqueue", chunk)
+			}
+			controller.Call("close")
+			return nil
+		}),
+		"type": "bytes",
+	})
+}
+
+// ErrCancelled is passed to the error handler of a stream converted from a Go reader or writer if JavaScript cancels or
+// aborts the stream without giving a reason.
+var ErrCancelled = errors.New("stream was cancelled")
+
+// ReaderToReadableStreamWithHandler converts an io.Reader to a JavaScript ReadableStream, like ReaderToReadableStream, but
+// calls onError if the stream ends in anything other than reaching the end of r, which would otherwise only be seen by
+// JavaScript. onError is called with the error r returned, with the reason JavaScript cancelled the stream for, or with
+// ErrCancelled if it gave none, so that the Go side can log the failure or clean up after it. onError is called at most
+// once, from its own goroutine, and r is still closed when the stream is cancelled if it implements io.Closer.
+func ReaderToReadableStreamWithHandler(r io.Reader, onError func(error)) js.Value {
+	return readerToReadableStream(context.Background(), r, defaultChunkSize, nil, onError, nil, 0)
+}
+
+// ReaderToReadableStreamWithCancel converts an io.Reader to a JavaScript ReadableStream, like ReaderToReadableStream, but
+// calls onCancel with the reason if JavaScript cancels the stream, so that the Go side can tell why, for instance a user
+// aborting a download from a network failure. The reason is the message of the Error JavaScript cancelled the stream
+// with, prefixed by its name if it is more specific than Error, such as "AbortError: The operation was aborted", the
+// reason converted to a string if it isn't an Error, or empty if JavaScript gave none. onCancel is called from its own
+// goroutine, and r is closed after it returns, if it implements io.Closer.
+func ReaderToReadableStreamWithCancel(r io.Reader, onCancel func(reason string)) js.Value {
+	return readerToReadableStream(context.Background(), r, defaultChunkSize, nil, nil, onCancel, 0)
+}
+
+// cancelReason converts the reason JavaScript cancelled or aborted a stream with, if any, to an error.
+func cancelReason(args []js.Value) error {
+	if len(args) == 0 || args[0].IsUndefined() {
+		return ErrCancelled
+	}
+	return jsErrorToGo(args[0])
+}
+
+// cancelReasonString describes the reason JavaScript cancelled a stream with, as it would appear in the error returned by
+// cancelReason, such as "AbortError: The operation was aborted". It is empty if no reason was given.
+func cancelReasonString(args []js.Value) string {
+	if len(args) == 0 || args[0].IsUndefined() {
+		return ""
+	}
+	return jsErrorToGo(args[0]).Error()
+}
+
+// errorHandler wraps onError, which may be nil, so that it is called at most once, in its own goroutine, so that it can't
+// hold up the JavaScript event loop.
+func errorHandler(onError func(error)) func(error) {
+	var once sync.Once
+	return func(err error) {
+		if onError == nil {
+			return
+		}
+		once.Do(func() {
+			go onError(err)
+		})
+	}
+}
+
+// ErrTimeout is the error a stream converted from a Go reader or writer with a timeout fails with if a read or write takes
+// longer than the timeout.
+var ErrTimeout = errors.New("read or write timed out")
+
+// ReaderToReadableStreamTimeout converts an io.Reader to a JavaScript ReadableStream, like ReaderToReadableStream, but
+// errors the stream with ErrTimeout if a single Read of r takes longer than timeout, so that Go-side I/O that is stuck
+// is reported to the JavaScript consumer instead of leaving it waiting forever. The stuck Read can't be interrupted, so
+// r is released as if the stream had been cancelled, which unblocks it if closing r does so. A timeout that is not
+// positive means reads never time out.
+func ReaderToReadableStreamTimeout(r io.Reader, timeout time.Duration, cancel ...func()) js.Value {
+	return readerToReadableStream(context.Background(), r, defaultChunkSize, cancel, nil, nil, timeout)
+}
+
+// withTimeout calls f, giving up with ErrTimeout if it hasn't returned within timeout, in which case it carries on in
+// the background and its result is discarded. A timeout that is not positive means f is simply called.
+func withTimeout(timeout time.Duration, f func() (int, error)) (int, error) {
+	if timeout <= 0 {
+		return f()
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := f()
+		done <- result{n, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		return res.n, res.err
+	case <-timer.C:
+		return 0, ErrTimeout
+	}
+}
+
+// readerToReadableStream converts an io.Reader to a JavaScript ReadableStream, reading up to chunkSize bytes per pull
+// until ctx is done, giving up on a read that takes longer than timeout, if it is positive, calling onError, if it isn't
+// nil, if the stream fails or is cancelled, and calling onCancel, if it isn't nil, with the reason if it is cancelled.
+func readerToReadableStream(ctx context.Context, r io.Reader, chunkSize int, cancel []func(), onError func(error), onCancel func(reason string), timeout time.Duration) js.Value {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	fail := errorHandler(onError)
+
+	// Pulls never overlap, so the same buffer can be used for all of them.
+	buffer := make([]byte, chunkSize)
+
+	var releaseOnce, stopOnce sync.Once
+	release := func() {
+		releaseOnce.Do(func() {
+			if len(cancel) > 0 {
+				cancel[0]()
+			} else if closer, ok := r.(io.Closer); ok {
+				_ = closer.Close()
+			}
+		})
+	}
+	// stopped is closed once the stream has finished, so that we stop watching ctx.
+	stopped := make(chan struct{})
+	stop := func() {
+		stopOnce.Do(func() { close(stopped) })
+	}
+
+	return js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			if ctx.Done() == nil {
 				return nil
-			}))
+			}
+
+			readController := args[0]
+			go func() {
+				select {
+				case <-ctx.Done():
+					stop()
+					readController.Call("error", goErrorToJS(ctx.Err()))
+					fail(ctx.Err())
+					release()
+				case <-stopped:
+				}
+			}()
+			return nil
+		}),
+		"cancel": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			stop()
+			fail(cancelReason(args))
+			promise, resolve, _ := newPromise()
+			go func() {
+				if onCancel != nil {
+					onCancel(cancelReasonString(args))
+				}
+				release()
+				resolve.Invoke()
+			}()
+			return promise
+		}),
+		"pull": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			readController := args[0]
+			promise, resolve, reject := newPromise()
+			go func() {
+				defer func() {
+					// The stream may have been cancelled while we were reading, in which case enqueue and close throw.
+					recovered := recover()
+					if recovered != nil {
+						fail(fmt.Errorf("panic: %v", recovered))
+						reject.Invoke(js.Global().Get("Error").New(fmt.Sprint(recovered)))
+					}
+				}()
+
+				// Each pull enqueues a chunk, and keeps going only while the stream's desired size says it wants more than
+				// it has queued, so r is never read further ahead than the consumer's backpressure allows. The stream has
+				// the default highWaterMark of 0, so in practice it only pulls when a read is waiting, and each pull
+				// enqueues a single chunk.
+				for {
+					// An enqueued chunk can't be reused, as enqueueing transfers its ArrayBuffer to the stream, which
+					// hands it on to the consumer, so every chunk costs a new one. A BYOB read waiting on the stream,
+					// though, comes with a view of the consumer's own buffer, which we read straight into instead,
+					// reading no more than fits.
+					target := buffer
+					request := readController.Get("byobRequest")
+					var view js.Value
+					if request.IsNull() || request.IsUndefined() {
+						request = js.Undefined()
+					} else {
+						view = request.Get("view")
+						target = buffer[:min(len(buffer), view.Get("byteLength").Int())]
+					}
+
+					// The stream won't pull again until something is enqueued, so we have to keep reading until we get
+					// data, unless it has been cancelled, after which a closed reader may never return any.
+					var n int
+					var err error
+					for n == 0 && err == nil {
+						select {
+						case <-stopped:
+							resolve.Invoke()
+							return
+						default:
+						}
+						err = ctx.Err()
+						if err == nil {
+							n, err = withTimeout(timeout, func() (int, error) {
+								n, err := r.Read(target)
+								if DebugContractChecks {
+									if violation := checkRead(r, target, n); violation != nil {
+										return 0, violation
+									}
+								}
+								return n, err
+							})
+						}
+					}
+
+					// If the stream was cancelled, or ctx was done, while we were reading, the stream has already been
+					// closed or errored, so enqueueing or closing it would throw. Whatever we read is dropped, and the
+					// pull settles normally.
+					select {
+					case <-stopped:
+						resolve.Invoke()
+						return
+					default:
+					}
+
+					// The stream sees the data, then the end of the stream or its error, and only then does the pull
+					// settle, so that no other pull can start in between.
+					if n > 0 && ctx.Err() == nil {
+						if !request.IsUndefined() {
+							js.CopyBytesToJS(view, buffer[:n])
+							request.Call("respond", n)
+						} else {
+							jsBuffer := js.Global().Get("Uint8Array").New(n)
+							js.CopyBytesToJS(jsBuffer, buffer[:n])
+							readController.Call("enqueue", jsBuffer)
+						}
+					}
+					if err == io.EOF {
+						stop()
+						closeController(readController)
+						break
+					} else if err != nil {
+						stop()
+						fail(err)
+						jsError := goErrorToJS(err)
+						readController.Call("error", jsError)
+						reject.Invoke(jsError)
+						if err == ErrTimeout {
+							// The read is stuck, so r is released in the hope that it unblocks.
+							release()
+						}
+						return
+					}
+
+					desiredSize := readController.Get("desiredSize")
+					if desiredSize.Type() != js.TypeNumber || desiredSize.Float() <= 0 {
+						break
+					}
+				}
+				resolve.Invoke()
+			}()
+			return promise
 		}),
 		"type": "bytes",
 	})
 }
 
-// WriterToWritableStream converts an io.Writer to a JavaScript WritableStream.
+// WriterToWritableStream converts an io.Writer to a JavaScript WritableStream. Chunks written to the stream may be any
+// TypedArray, a DataView, an ArrayBuffer or a Blob.
 func WriterToWritableStream(w io.Writer) js.Value {
-	return js.Global().Get("WritableStream").New(map[string]interface{}{
-		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-			writeBuffer := args[0]
-			return js.Global().Get("Promise").New(js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-				buffer := make([]byte, writeBuffer.Length())
-				js.CopyBytesToGo(buffer, writeBuffer)
-				_, err := w.Write(buffer)
-				if err != nil {
-					panic(err.Error())
+	return writerToWritableStream(context.Background(), w, nil, nil, 0)
+}
+
+// WriterToWritableStreamContext converts an io.Writer to a JavaScript WritableStream, like WriterToWritableStream, but
+// ties the stream to ctx. Once ctx is done, nothing more is written to w, and the stream is errored with ctx's error.
+func WriterToWritableStreamContext(ctx context.Context, w io.Writer) js.Value {
+	return writerToWritableStream(ctx, w, nil, nil, 0)
+}
+
+// WriterToWritableStreamWithHandler converts an io.Writer to a JavaScript WritableStream, like WriterToWritableStream,
+// but calls onError if the stream fails or is aborted, which would otherwise only be seen by JavaScript. onError is called
+// with the error w returned, with the reason JavaScript aborted the stream for, or with ErrCancelled if it gave none. It
+// is called at most once, from its own goroutine.
+func WriterToWritableStreamWithHandler(w io.Writer, onError func(error)) js.Value {
+	return writerToWritableStream(context.Background(), w, nil, onError, 0)
+}
+
+// WriterToWritableStreamTimeout converts an io.Writer to a JavaScript WritableStream, like WriterToWritableStream, but
+// rejects a write, erroring the stream, with ErrTimeout if a single Write to w takes longer than timeout, so that Go-side
+// I/O that is stuck is reported to the JavaScript producer instead of leaving it waiting forever. The stuck Write can't
+// be interrupted, and carries on in the background. A timeout that is not positive means writes never time out.
+func WriterToWritableStreamTimeout(w io.Writer, timeout time.Duration) js.Value {
+	return writerToWritableStream(context.Background(), w, nil, nil, timeout)
+}
+
+// PumpReaderToStream writes everything read from r to the JavaScript WritableStream jsWritable, in chunks of up to
+// chunkSize bytes, then closes it, which is the usual way of uploading a Go reader to a browser sink, such as a file
+// opened with the File System Access API. Each chunk waits for the stream to be ready, so a slow sink holds back reading
+// from r, and r is never read further ahead than a single chunk. If chunkSize is not positive, the default of 32 KiB is
+// used. If reading from r fails, the stream is aborted with the error, so that the sink doesn't mistake what it has for
+// everything, and the error is returned. PumpReaderToStream returns once the sink has closed, and doesn't close r.
+func PumpReaderToStream(r io.Reader, jsWritable js.Value, chunkSize int) error {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	stream := NewWritableStream(jsWritable)
+	buffer := make([]byte, chunkSize)
+	for {
+		n, err := r.Read(buffer)
+		if n > 0 {
+			if _, writeErr := stream.Write(buffer[:n]); writeErr != nil {
+				return writeErr
+			}
+		}
+		if err == io.EOF {
+			return stream.Close()
+		}
+		if err != nil {
+			// The stream is only locked while a write is in progress, so it can be aborted straight away.
+			_, _ = await(jsWritable.Call("abort", goErrorToJS(err)))
+			return err
+		}
+	}
+}
+
+// writerToWritableStream converts an io.Writer to a JavaScript WritableStream, writing to it until ctx is done, giving
+// up on a write that takes longer than timeout, if it is positive, calling closeWriter, if it isn't nil, when the stream
+// is closed, and calling onError, if it isn't nil, if the stream fails or is aborted.
+func writerToWritableStream(ctx context.Context, w io.Writer, closeWriter func() error, onError func(error), timeout time.Duration) js.Value {
+	fail := errorHandler(onError)
+	// stopped is closed once the stream has finished, so that we stop watching ctx.
+	var stopOnce sync.Once
+	stopped := make(chan struct{})
+	stop := func() {
+		stopOnce.Do(func() { close(stopped) })
+	}
+
+	writeChunk := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		promise, resolve, reject := newPromise()
+		if ctx.Err() != nil {
+			reject.Invoke(goErrorToJS(ctx.Err()))
+			return promise
+		}
+
+		writeBuffer, ok := toUint8Array(args[0])
+		if !ok {
+			reject.Invoke(js.Global().Get("TypeError").New("chunk must be a BufferSource or a Blob"))
+			return promise
+		}
+
+		buffer := make([]byte, writeBuffer.Length())
+		js.CopyBytesToGo(buffer, writeBuffer)
+		go func() {
+			_, err := withTimeout(timeout, func() (int, error) {
+				n, err := w.Write(buffer)
+				if DebugContractChecks {
+					if violation := checkWrite(w, buffer, n, err); violation != nil {
+						return 0, violation
+					}
 				}
-				args[0].Invoke()
+				return n, err
+			})
+			if err != nil {
+				fail(err)
+				reject.Invoke(goErrorToJS(err))
+				return
+			}
+			resolve.Invoke()
+		}()
+		return promise
+	})
+
+	sink := map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			if ctx.Done() == nil {
 				return nil
-			}))
+			}
+
+			writeController := args[0]
+			go func() {
+				select {
+				case <-ctx.Done():
+					stop()
+					writeController.Call("error", goErrorToJS(ctx.Err()))
+					fail(ctx.Err())
+				case <-stopped:
+				}
+			}()
+			return nil
 		}),
+		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			// A Blob's contents can only be read asynchronously, so we wait for them before writing.
+			if args[0].InstanceOf(js.Global().Get("Blob")) {
+				return args[0].Call("arrayBuffer").Call("then", writeChunk)
+			}
+			return writeChunk.Invoke(args[0])
+		}),
+	}
+	sink["abort"] = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		stop()
+		fail(cancelReason(args))
+		return nil
+	})
+	sink["close"] = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		stop()
+		if closeWriter == nil {
+			return nil
+		}
+
+		promise, resolve, reject := newPromise()
+		go func() {
+			err := closeWriter()
+			if err != nil {
+				fail(err)
+				reject.Invoke(goErrorToJS(err))
+				return
+			}
+			resolve.Invoke()
+		}()
+		return promise
 	})
+
+	return js.Global().Get("WritableStream").New(sink)
+}
+
+// toUint8Array returns a Uint8Array over the same bytes as value, which may be an ArrayBuffer, a SharedArrayBuffer, a
+// TypedArray or a DataView. It returns false if value is none of those. Bytes in a SharedArrayBuffer are copied into a
+// regular ArrayBuffer instead, as some runtimes refuse to copy shared memory into Go, and it may be changed by another
+// thread while we read it.
+func toUint8Array(value js.Value) (js.Value, bool) {
+	uint8Array := js.Global().Get("Uint8Array")
+	var view js.Value
+	switch {
+	case value.InstanceOf(uint8Array):
+		view = value
+	case value.InstanceOf(js.Global().Get("ArrayBuffer")) || isInstance(value, "SharedArrayBuffer"):
+		view = uint8Array.New(value)
+	case js.Global().Get("ArrayBuffer").Call("isView", value).Bool():
+		view = uint8Array.New(value.Get("buffer"), value.Get("byteOffset"), value.Get("byteLength"))
+	default:
+		return js.Undefined(), false
+	}
+
+	if isInstance(view.Get("buffer"), "SharedArrayBuffer") {
+		// Constructing a Uint8Array from another TypedArray copies its bytes into a new, unshared, ArrayBuffer.
+		view = uint8Array.New(view)
+	}
+	return view, true
+}
+
+// newGoReadableStream creates a ReadableStream backed by a Go io.ReadCloser, which is closed if the stream is cancelled.
+func newGoReadableStream(source io.ReadCloser) *ReadableStream {
+	return NewReadableStream(ReaderToReadableStream(source))
+}
+
+// newGoReadableStreamSize creates a ReadableStream backed by a Go io.ReadCloser, like newGoReadableStream, reading up to
+// chunkSize bytes from it at a time, so that every chunk is whatever a single Read of source returned.
+func newGoReadableStreamSize(source io.ReadCloser, chunkSize int) *ReadableStream {
+	return NewReadableStream(ReaderToReadableStreamSize(source, chunkSize))
+}
+
+// newGoWritableStream creates a WritableStream backed by a Go io.WriteCloser, which is closed when the stream is closed.
+func newGoWritableStream(sink io.WriteCloser) *WritableStream {
+	w := NewWritableStream(writerToWritableStream(context.Background(), sink, sink.Close, nil, 0))
+	if flusher, ok := sink.(interface{ Flush() error }); ok {
+		w.flush = flusher.Flush
+	}
+	return w
+}
+
+// closeController closes a ReadableByteStreamController. Closing does not settle a pending BYOB read by itself, so if
+// there is one it is responded to with zero bytes, which resolves it with done set to true.
+func closeController(controller js.Value) {
+	controller.Call("close")
+	byobRequest := controller.Get("byobRequest")
+	if !byobRequest.IsUndefined() && !byobRequest.IsNull() {
+		byobRequest.Call("respond", 0)
+	}
+}
+
+// promiseExecutor is the executor shared by every Promise created by newPromise, which hands the resolve and reject
+// functions it is called with over to newPromise. Reusing a single executor means a pull or write doesn't have to create
+// and release a Go function every time it returns a Promise. The executor is Array.prototype.push bound to resolvers, so
+// it runs entirely in JavaScript: a Go executor would be a callback into Go in the middle of constructing the Promise,
+// and another goroutine calling into JavaScript at that point, such as a write whose sink is backed by Go, would stack its
+// own callback on top, which deadlocks if that callback then waits on the lock. The executor is called synchronously while
+// the Promise is constructed, so the lock is held across construction to keep concurrent calls from mixing up their
+// functions.
+var promiseExecutor struct {
+	lock      sync.Mutex
+	resolvers js.Value
+	executor  js.Value
+}
+
+func init() {
+	promiseExecutor.resolvers = js.Global().Get("Array").New()
+	promiseExecutor.executor = promiseExecutor.resolvers.Get("push").Call("bind", promiseExecutor.resolvers)
+}
+
+// newPromise creates a new JavaScript Promise and returns it along with its resolve and reject functions.
+func newPromise() (promise js.Value, resolve js.Value, reject js.Value) {
+	promiseExecutor.lock.Lock()
+	defer promiseExecutor.lock.Unlock()
+
+	promise = js.Global().Get("Promise").New(promiseExecutor.executor)
+	resolve, reject = promiseExecutor.resolvers.Index(0), promiseExecutor.resolvers.Index(1)
+	promiseExecutor.resolvers.Set("length", 0)
+	return promise, resolve, reject
+}
+
+// ignoreRejectionFunc is the rejection handler used by ignoreRejection. It is shared by every call, so it is never
+// released.
+var ignoreRejectionFunc = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+	return nil
+})
+
+// ignoreRejection handles a rejection of promise by doing nothing, for promises we don't wait on. Otherwise, a rejection,
+// such as cancelling a stream that has already errored, goes unhandled, which some runtimes treat as a fatal error.
+func ignoreRejection(promise js.Value) {
+	promise.Call("then", js.Undefined(), ignoreRejectionFunc)
 }
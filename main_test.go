@@ -0,0 +1,1597 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// newTestReadableStream creates a JavaScript byte ReadableStream that yields each of chunks in turn and then closes.
+func newTestReadableStream(chunks ...[]byte) js.Value {
+	return js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			for _, chunk := range chunks {
+				buffer := js.Global().Get("Uint8Array").New(len(chunk))
+				js.CopyBytesToJS(buffer, chunk)
+				args[0].Call("enqueue", buffer)
+			}
+			args[0].Call("close")
+			return nil
+		}),
+		"type": "bytes",
+	})
+}
+
+// testSink records everything written to the JavaScript WritableStream created by newTestWritableStream.
+type testSink struct {
+	chunks [][]byte
+	closed bool
+}
+
+func (s *testSink) bytes() []byte {
+	return bytes.Join(s.chunks, nil)
+}
+
+// newTestWritableStream creates a JavaScript WritableStream that records every chunk written to it into the returned sink.
+func newTestWritableStream() (js.Value, *testSink) {
+	sink := &testSink{}
+	stream := js.Global().Get("WritableStream").New(map[string]interface{}{
+		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			chunk := make([]byte, args[0].Length())
+			js.CopyBytesToGo(chunk, args[0])
+			sink.chunks = append(sink.chunks, chunk)
+			return nil
+		}),
+		"close": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			sink.closed = true
+			return nil
+		}),
+	})
+	return stream, sink
+}
+
+func TestReadableStreamRead(t *testing.T) {
+	stream := NewReadableStream(newTestReadableStream([]byte("Hello, "), []byte("world!")))
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, want %q", data, "Hello, world!")
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+}
+
+func TestReadableStreamReadShort(t *testing.T) {
+	stream := NewReadableStream(newTestReadableStream([]byte("Hello, world!")))
+
+	buffer := make([]byte, 5)
+	n, err := stream.Read(buffer)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if string(buffer[:n]) != "Hello" {
+		t.Fatalf("Read returned %q, want %q", buffer[:n], "Hello")
+	}
+
+	rest, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if string(rest) != ", world!" {
+		t.Fatalf("ReadAll returned %q, want %q", rest, ", world!")
+	}
+}
+
+func TestWritableStreamWrite(t *testing.T) {
+	jsStream, sink := newTestWritableStream()
+	stream := NewWritableStream(jsStream)
+
+	for _, chunk := range []string{"Hello, ", "world!"} {
+		n, err := stream.Write([]byte(chunk))
+		if err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		if n != len(chunk) {
+			t.Fatalf("Write returned %d, want %d", n, len(chunk))
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if string(sink.bytes()) != "Hello, world!" {
+		t.Fatalf("sink received %q, want %q", sink.bytes(), "Hello, world!")
+	}
+}
+
+func TestNewWritableStreamFromWriter(t *testing.T) {
+	jsStream, sink := newTestWritableStream()
+	writer := jsStream.Call("getWriter")
+	stream, err := NewWritableStreamFromWriter(writer)
+	if err != nil {
+		t.Fatalf("NewWritableStreamFromWriter returned error: %v", err)
+	}
+	if !stream.Locked() {
+		t.Fatal("Locked returned false for a stream created from a writer")
+	}
+
+	for _, chunk := range []string{"Hello, ", "world!"} {
+		if _, err := stream.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if _, err := stream.ReadFrom(strings.NewReader(" Bye.")); err != nil {
+		t.Fatalf("ReadFrom returned error: %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if string(sink.bytes()) != "Hello, world! Bye." || !sink.closed {
+		t.Fatalf("sink received %q and closed %v, want %q and true", sink.bytes(), sink.closed, "Hello, world! Bye.")
+	}
+
+	// The writer still holds the stream's lock, as it was never ours to release.
+	if !jsStream.Get("locked").Bool() {
+		t.Fatal("the writer's lock was released")
+	}
+
+	for _, value := range []js.Value{js.Undefined(), jsStream, js.Global().Get("Object").New()} {
+		if _, err := NewWritableStreamFromWriter(value); err != ErrNotWriter {
+			t.Fatalf("NewWritableStreamFromWriter(%v) returned %v, want %v", value, err, ErrNotWriter)
+		}
+	}
+}
+
+func TestReaderToReadableStream(t *testing.T) {
+	stream := NewReadableStream(ReaderToReadableStream(bytes.NewReader([]byte("Hello, world!"))))
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, want %q", data, "Hello, world!")
+	}
+}
+
+func TestWriterToWritableStream(t *testing.T) {
+	var buffer bytes.Buffer
+	stream := NewWritableStream(WriterToWritableStream(&buffer))
+
+	if _, err := stream.Write([]byte("Hello, world!")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if buffer.String() != "Hello, world!" {
+		t.Fatalf("writer received %q, want %q", buffer.String(), "Hello, world!")
+	}
+}
+
+func TestWriterToWritableStreamChunkTypes(t *testing.T) {
+	// Every chunk holds "data", in a different kind of JavaScript buffer, some of which only view part of theirs.
+	chunks := js.Global().Get("Function").New(`
+		const bytes = new TextEncoder().encode("xxdataxx");
+		return [
+			["Uint8Array", bytes.subarray(2, 6)],
+			["ArrayBuffer", bytes.slice(2, 6).buffer],
+			["DataView", new DataView(bytes.buffer, 2, 4)],
+			["Int16Array", new Int16Array(bytes.buffer, 2, 2)],
+			["Float32Array", new Float32Array(bytes.slice(2, 6).buffer)],
+			["Blob", new Blob(["da", "ta"])],
+		];
+	`).Invoke()
+
+	var buffer bytes.Buffer
+	writer := WriterToWritableStream(&buffer).Call("getWriter")
+	for i := 0; i < chunks.Length(); i++ {
+		name, chunk := chunks.Index(i).Index(0).String(), chunks.Index(i).Index(1)
+		buffer.Reset()
+		if _, err := await(writer.Call("write", chunk)); err != nil {
+			t.Fatalf("writing a %s returned error: %v", name, err)
+		}
+		if buffer.String() != "data" {
+			t.Fatalf("writing a %s gave the writer %q, want %q", name, buffer.String(), "data")
+		}
+	}
+
+	// Anything else is rejected.
+	if _, err := await(writer.Call("write", "data")); err == nil || !strings.Contains(err.Error(), "BufferSource") {
+		t.Fatalf("writing a string returned %v, want a TypeError", err)
+	}
+}
+
+func TestReadableStreamReadAfterClose(t *testing.T) {
+	stream := NewReadableStream(newTestReadableStream([]byte("Hello, world!")))
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := stream.Read(make([]byte, 5)); err != io.ErrClosedPipe {
+		t.Fatalf("Read after Close returned %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestWritableStreamWriteAfterClose(t *testing.T) {
+	jsStream, sink := newTestWritableStream()
+	stream := NewWritableStream(jsStream)
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := stream.Write([]byte("Hello, world!")); err != io.ErrClosedPipe {
+		t.Fatalf("Write after Close returned %v, want %v", err, io.ErrClosedPipe)
+	}
+	if len(sink.chunks) != 0 {
+		t.Fatalf("sink received %d chunks after Close, want 0", len(sink.chunks))
+	}
+}
+
+func TestReadableStreamCloseAwaitsCancel(t *testing.T) {
+	// The source takes a while to tear down once cancelled, and either finishes or fails to.
+	newSlowCancelStream := func(fail bool) (js.Value, func() bool) {
+		state := js.Global().Get("Function").New("fail", `
+			const state = { done: false };
+			state.stream = new ReadableStream({
+				cancel() {
+					return new Promise((resolve, reject) => setTimeout(() => {
+						state.done = true;
+						fail ? reject(new Error("teardown failed")) : resolve();
+					}, 50));
+				},
+			});
+			return state;
+		`).Invoke(fail)
+		return state.Get("stream"), func() bool { return state.Get("done").Bool() }
+	}
+
+	jsStream, done := newSlowCancelStream(false)
+	stream := NewReadableStream(jsStream)
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if !done() {
+		t.Fatal("Close returned before the source finished cancelling")
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("second Close returned error: %v", err)
+	}
+
+	// The same goes for a stream locked to a Reader.
+	jsStream, done = newSlowCancelStream(true)
+	stream = NewReadableStream(jsStream)
+	if _, err := stream.AcquireReader(ReaderModeDefault); err != nil {
+		t.Fatalf("AcquireReader returned error: %v", err)
+	}
+	if err := stream.Close(); err == nil || err.Error() != "teardown failed" {
+		t.Fatalf("Close returned %v, want %q", err, "teardown failed")
+	}
+	if !done() || stream.Locked() {
+		t.Fatal("Close returned before the source finished cancelling, or left the stream locked")
+	}
+
+	// Closing a stream that has already errored is not an error.
+	errored := js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			args[0].Call("error", js.Global().Get("Error").New("source failed"))
+			return nil
+		}),
+	})
+	if err := NewReadableStream(errored).Close(); err != nil {
+		t.Fatalf("Close of an errored stream returned error: %v", err)
+	}
+}
+
+func TestReadableStreamCloseInterruptsRead(t *testing.T) {
+	// The source never produces anything, so a Read on it can only end by being interrupted.
+	state := js.Global().Get("Function").New(`
+		const state = { cancelled: false };
+		state.stream = new ReadableStream({
+			cancel() {
+				state.cancelled = true;
+			},
+		}, { highWaterMark: 0 });
+		return state;
+	`).Invoke()
+	stream := NewReadableStream(state.Get("stream"))
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := stream.Read(make([]byte, 16))
+		read <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- stream.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked behind the stalled Read")
+	}
+	select {
+	case err := <-read:
+		if err != io.ErrClosedPipe {
+			t.Fatalf("Read returned %v, want %v", err, io.ErrClosedPipe)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read wasn't interrupted by Close")
+	}
+	if !state.Get("cancelled").Bool() {
+		t.Fatal("Close didn't cancel the source")
+	}
+	if _, err := stream.Read(make([]byte, 16)); err != io.ErrClosedPipe {
+		t.Fatalf("Read after Close returned %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestReadableStreamCloseConcurrent(t *testing.T) {
+	var cancelled int
+	stream := NewReadableStream(js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"cancel": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			cancelled++
+			return nil
+		}),
+		"type": "bytes",
+	}))
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if err := stream.Close(); err != nil {
+				t.Errorf("Close returned error: %v", err)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	if cancelled != 1 {
+		t.Fatalf("stream was cancelled %d times, want 1", cancelled)
+	}
+}
+
+func TestWritableStreamCloseConcurrent(t *testing.T) {
+	var closed int
+	stream := NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+		"close": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			closed++
+			return nil
+		}),
+	}))
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if err := stream.Close(); err != nil {
+				t.Errorf("Close returned error: %v", err)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	if closed != 1 {
+		t.Fatalf("stream was closed %d times, want 1", closed)
+	}
+}
+
+func TestReaderToReadableStreamSize(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	// DataErrReader returns io.EOF alongside the final bytes, which must still be enqueued before the stream closes.
+	stream := NewReadableStream(ReaderToReadableStreamSize(iotest.DataErrReader(bytes.NewReader(data)), 7))
+
+	// Each chunk is read into a buffer larger than the chunk size, so every Read but the last should see a full chunk.
+	buffer := make([]byte, 16)
+	var received []byte
+	for {
+		n, err := stream.Read(buffer)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+		if n > 7 {
+			t.Fatalf("Read returned %d bytes, want at most 7", n)
+		}
+		received = append(received, buffer[:n]...)
+	}
+
+	if !bytes.Equal(received, data) {
+		t.Fatalf("stream yielded %q, want %q", received, data)
+	}
+}
+
+func TestReaderToReadableStreamBackpressure(t *testing.T) {
+	source := &countingReader{}
+	reader := ReaderToReadableStreamSize(source, 16).Call("getReader")
+
+	// The consumer reads slowly, and the source is only ever read for the chunk it asks for, rather than drained ahead.
+	for i := 1; i <= 3; i++ {
+		if _, err := await(reader.Call("read")); err != nil {
+			t.Fatalf("read returned error: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if reads := source.reads.Load(); reads > int64(i) {
+			t.Fatalf("source was read %d times for %d reads, want it read no further ahead", reads, i)
+		}
+	}
+}
+
+func TestStrictSingleReader(t *testing.T) {
+	source := &lateReader{data: "Hello, world!", delay: 100 * time.Millisecond}
+	stream := NewReadableStreamWithOptions(ReaderToReadableStream(source), ReadableStreamOptions{StrictSingleReader: true})
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := stream.Read(make([]byte, 16))
+		first <- err
+	}()
+
+	// The first read is still waiting on the source, so a second one at the same time is rejected.
+	time.Sleep(20 * time.Millisecond)
+	if _, err := stream.Read(make([]byte, 16)); err != ErrStreamBusy {
+		t.Fatalf("concurrent Read returned %v, want %v", err, ErrStreamBusy)
+	}
+	if _, err := stream.ReadByte(); err != ErrStreamBusy {
+		t.Fatalf("concurrent ReadByte returned %v, want %v", err, ErrStreamBusy)
+	}
+	if err := <-first; err != nil {
+		t.Fatalf("first Read returned error: %v", err)
+	}
+
+	// Once it has finished, reading again is fine.
+	if _, err := stream.Read(make([]byte, 16)); err != io.EOF {
+		t.Fatalf("Read after the first had finished returned %v, want io.EOF", err)
+	}
+}
+
+func TestNewWritableStreamWithStrategy(t *testing.T) {
+	stream, err := NewWritableStreamWithStrategy(js.Undefined(), 4)
+	if err != nil {
+		t.Fatalf("NewWritableStreamWithStrategy returned error: %v", err)
+	}
+	if size, ok := stream.DesiredSize(); !ok || size != 4 {
+		t.Fatalf("DesiredSize returned %d, %v, want 4, true", size, ok)
+	}
+
+	if _, err := NewWritableStreamWithStrategy(js.Undefined(), -1); err != ErrNegativeHighWaterMark {
+		t.Fatalf("NewWritableStreamWithStrategy returned %v, want %v", err, ErrNegativeHighWaterMark)
+	}
+}
+
+func TestReadableStreamReadIntoJS(t *testing.T) {
+	stream := NewReadableStream(newTestReadableStream([]byte("Hello, world!")))
+
+	n, filled, err := stream.ReadIntoJS(js.Global().Get("Uint8Array").New(5))
+	if err != nil {
+		t.Fatalf("ReadIntoJS returned error: %v", err)
+	}
+	data := make([]byte, n)
+	js.CopyBytesToGo(data, filled)
+	if string(data) != "Hello" {
+		t.Fatalf("ReadIntoJS read %q, want %q", data, "Hello")
+	}
+
+	if _, _, err := stream.ReadIntoJS(js.Global().Get("ArrayBuffer").New(5)); err != ErrNotTypedArray {
+		t.Fatalf("ReadIntoJS with an ArrayBuffer returned %v, want %v", err, ErrNotTypedArray)
+	}
+}
+
+func TestZeroLengthReadWrite(t *testing.T) {
+	readable := NewReadableStream(newTestReadableStream([]byte("Hello")))
+	if n, err := readable.Read(nil); n != 0 || err != nil {
+		t.Fatalf("Read(nil) returned %d, %v, want 0, nil", n, err)
+	}
+	if data, err := io.ReadAll(readable); err != nil || string(data) != "Hello" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello")
+	}
+
+	jsStream, sink := newTestWritableStream()
+	writable := NewWritableStream(jsStream)
+	if n, err := writable.Write([]byte{}); n != 0 || err != nil {
+		t.Fatalf("Write([]byte{}) returned %d, %v, want 0, nil", n, err)
+	}
+	if err := writable.Ready(); err != nil {
+		t.Fatalf("Ready returned error: %v", err)
+	}
+	if len(sink.chunks) != 0 {
+		t.Fatalf("sink received %d chunks, want 0", len(sink.chunks))
+	}
+}
+
+func TestWritableStreamCloseFlushes(t *testing.T) {
+	// The sink takes a while to commit each chunk, so Close must wait for all of them before returning.
+	var committed []byte
+	var sinkClosed bool
+	delay := func(then func()) js.Value {
+		promise, resolve, _ := newPromise()
+		var callback js.Func
+		callback = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			then()
+			resolve.Invoke()
+			callback.Release()
+			return nil
+		})
+		js.Global().Call("setTimeout", callback, 10)
+		return promise
+	}
+	stream := NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			chunk := make([]byte, args[0].Length())
+			js.CopyBytesToGo(chunk, args[0])
+			return delay(func() {
+				committed = append(committed, chunk...)
+			})
+		}),
+		"close": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return delay(func() {
+				sinkClosed = true
+			})
+		}),
+	}, map[string]interface{}{"highWaterMark": 8}))
+
+	// Writing directly through a writer queues the chunks without waiting for them to be committed.
+	writer := stream.stream.Call("getWriter")
+	for _, chunk := range []string{"Hello, ", "world!"} {
+		buffer := js.Global().Get("Uint8Array").New(len(chunk))
+		js.CopyBytesToJS(buffer, []byte(chunk))
+		writer.Call("write", buffer)
+	}
+	writer.Call("releaseLock")
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if !sinkClosed || string(committed) != "Hello, world!" {
+		t.Fatalf("after Close, sink closed is %v and committed %q, want true and %q", sinkClosed, committed, "Hello, world!")
+	}
+}
+
+func TestWritableStreamCloseAlreadyClosed(t *testing.T) {
+	jsStream, _ := newTestWritableStream()
+	if _, err := await(jsStream.Call("close")); err != nil {
+		t.Fatalf("closing the JavaScript stream returned error: %v", err)
+	}
+
+	if err := NewWritableStream(jsStream).Close(); err != nil {
+		t.Fatalf("Close of an already closed stream returned error: %v", err)
+	}
+}
+
+func TestWritableStreamCloseErrored(t *testing.T) {
+	stream := NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return js.Global().Get("Promise").Call("reject", js.Global().Get("Error").New("sink failed"))
+		}),
+	}))
+
+	if err := stream.Close(); err == nil || err.Error() != "sink failed" {
+		t.Fatalf("Close of an errored stream returned error %v, want %q", err, "sink failed")
+	}
+}
+
+func TestWritableStreamWaitClosedAborted(t *testing.T) {
+	jsStream, _ := newTestWritableStream()
+	stream := NewWritableStream(jsStream)
+	ignoreRejection(jsStream.Call("abort", js.Global().Get("Error").New("aborted by the page")))
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- stream.WaitClosed()
+	}()
+
+	if _, err := stream.Write([]byte("Hello")); err == nil {
+		t.Fatal("Write to an aborted stream succeeded")
+	}
+	if err := <-closed; err == nil || err.Error() != "aborted by the page" {
+		t.Fatalf("WaitClosed returned %v, want %q", err, "aborted by the page")
+	}
+}
+
+func TestWriterToWritableStreamWithHandler(t *testing.T) {
+	writeErr := errors.New("write failed")
+	handled := make(chan error, 1)
+	stream := NewWritableStream(WriterToWritableStreamWithHandler(&recordingSink{err: writeErr}, func(err error) {
+		handled <- err
+	}))
+	if _, err := stream.Write([]byte("Hello")); err == nil || err.Error() != writeErr.Error() {
+		t.Fatalf("Write returned %v, want %v", err, writeErr)
+	}
+	if err := <-handled; err != writeErr {
+		t.Fatalf("handler was called with %v, want %v", err, writeErr)
+	}
+}
+
+func TestWritableStreamAutoFlush(t *testing.T) {
+	jsStream, sink := newTestWritableStream()
+	stream := NewWritableStreamWithOptions(jsStream, WritableStreamOptions{AutoFlush: true})
+
+	// Each write has been committed to the sink, and the stream is ready for the next, by the time Write returns.
+	var want []byte
+	for _, chunk := range []string{"data: one\n\n", "data: two\n\n", "data: three\n\n"} {
+		if _, err := stream.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		want = append(want, chunk...)
+		if !bytes.Equal(sink.bytes(), want) {
+			t.Fatalf("after Write, sink received %q, want %q", sink.bytes(), want)
+		}
+		if size, ok := stream.DesiredSize(); !ok || size <= 0 {
+			t.Fatalf("after Write, DesiredSize returned %d, %v, want a positive size", size, ok)
+		}
+	}
+
+	if n, err := stream.ReadFrom(strings.NewReader("data: four\n\n")); err != nil || n != int64(len("data: four\n\n")) {
+		t.Fatalf("ReadFrom returned %d, %v, want %d, nil", n, err, len("data: four\n\n"))
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+}
+
+func TestWritableStreamAsyncWrite(t *testing.T) {
+	// The sink fails the chunk "fail", and records the rest.
+	var received []string
+	newSink := func() js.Value {
+		return js.Global().Get("WritableStream").New(map[string]interface{}{
+			"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				chunk := make([]byte, args[0].Length())
+				js.CopyBytesToGo(chunk, args[0])
+				if string(chunk) == "fail" {
+					return js.Global().Get("Promise").Call("reject", js.Global().Get("Error").New("sink failed"))
+				}
+				received = append(received, string(chunk))
+				return nil
+			}),
+		}, map[string]interface{}{"highWaterMark": 16})
+	}
+
+	stream := NewWritableStreamWithOptions(newSink(), WritableStreamOptions{AsyncWrite: true})
+	for _, chunk := range []string{"one", "two", "three"} {
+		if n, err := stream.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write returned %d, %v, want %d, nil", n, err, len(chunk))
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if len(received) != 3 || received[0] != "one" || received[1] != "two" || received[2] != "three" {
+		t.Fatalf("sink received %q, want %q", received, []string{"one", "two", "three"})
+	}
+	if stream.BytesWritten() != int64(len("onetwothree")) {
+		t.Fatalf("BytesWritten returned %d, want %d", stream.BytesWritten(), len("onetwothree"))
+	}
+
+	// A failed write returns nil, but the failure is delivered by Errors, and by the next Write and Close.
+	stream = NewWritableStreamWithOptions(newSink(), WritableStreamOptions{AsyncWrite: true})
+	if _, err := stream.Write([]byte("fail")); err != nil {
+		t.Fatalf("Write returned %v before the write failed", err)
+	}
+	select {
+	case err := <-stream.Errors():
+		if err == nil || err.Error() != "sink failed" {
+			t.Fatalf("Errors delivered %v, want %q", err, "sink failed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Errors delivered nothing")
+	}
+	if _, err := stream.Write([]byte("late")); err == nil || err.Error() != "sink failed" {
+		t.Fatalf("Write after a failed write returned %v, want %q", err, "sink failed")
+	}
+	if err := stream.Close(); err == nil || err.Error() != "sink failed" {
+		t.Fatalf("Close after a failed write returned %v, want %q", err, "sink failed")
+	}
+
+	if NewWritableStream().Errors() != nil {
+		t.Fatal("Errors returned a channel for a stream without AsyncWrite")
+	}
+}
+
+func TestPumpReaderToStream(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	jsStream, sink := newTestWritableStream()
+	if err := PumpReaderToStream(bytes.NewReader(data), jsStream, 64*1024); err != nil {
+		t.Fatalf("PumpReaderToStream returned error: %v", err)
+	}
+	if !bytes.Equal(sink.bytes(), data) {
+		t.Fatalf("sink received %d bytes, want %d", len(sink.bytes()), len(data))
+	}
+	for _, chunk := range sink.chunks {
+		if len(chunk) > 64*1024 {
+			t.Fatalf("sink received a chunk of %d bytes, want at most %d", len(chunk), 64*1024)
+		}
+	}
+	if !sink.closed {
+		t.Fatal("PumpReaderToStream did not close the stream")
+	}
+
+	// A failed read aborts the stream, rather than closing it.
+	readErr := errors.New("read failed")
+	jsStream, sink = newTestWritableStream()
+	source := io.MultiReader(strings.NewReader("Hello"), iotest.ErrReader(readErr))
+	if err := PumpReaderToStream(source, jsStream, 0); err != readErr {
+		t.Fatalf("PumpReaderToStream returned %v, want %v", err, readErr)
+	}
+	if sink.closed || string(sink.bytes()) != "Hello" {
+		t.Fatalf("sink received %q, and closed is %v, want %q and false", sink.bytes(), sink.closed, "Hello")
+	}
+	if _, err := NewWritableStream(jsStream).Write([]byte("late")); err == nil || err.Error() != readErr.Error() {
+		t.Fatalf("Write to the aborted stream returned %v, want %v", err, readErr)
+	}
+}
+
+func TestFinalizeStreams(t *testing.T) {
+	var events []string
+	Logger = func(event string, detail map[string]interface{}) {
+		if event == EventLeaked {
+			events = append(events, detail["stream"].(string))
+		}
+	}
+	defer func() {
+		Logger = nil
+	}()
+
+	var cancelled bool
+	readable := NewReadableStreamWithOptions(js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"cancel": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			cancelled = true
+			return nil
+		}),
+		"type": "bytes",
+	}), ReadableStreamOptions{CloseOnFinalize: true})
+	finalizeReadableStream(readable)
+	if !cancelled || !readable.closed.Load() {
+		t.Fatalf("after finalizing, cancelled is %v and closed is %v, want both true", cancelled, readable.closed.Load())
+	}
+
+	jsStream, sink := newTestWritableStream()
+	writable := NewWritableStreamWithOptions(jsStream, WritableStreamOptions{CloseOnFinalize: true})
+	finalizeWritableStream(writable)
+	if err := writable.WaitClosed(); err != nil || !sink.closed {
+		t.Fatalf("after finalizing, WaitClosed returned %v with the sink closed %v, want nil and true", err, sink.closed)
+	}
+
+	// Streams that have been closed properly are left alone.
+	finalizeReadableStream(readable)
+	finalizeWritableStream(writable)
+	if !reflect.DeepEqual(events, []string{"readable", "writable"}) {
+		t.Fatalf("logged leaks of %q, want %q", events, []string{"readable", "writable"})
+	}
+}
+
+func TestCloseRecovery(t *testing.T) {
+	goErr := errors.New("something else went wrong")
+	tests := []struct {
+		name      string
+		recovered interface{}
+		wantErr   bool
+	}{
+		{"nothing", nil, false},
+		{"already closed string", alreadyClosedMessage, false},
+		{"already closed Go error", errors.New(alreadyClosedMessage), false},
+		{"already closed JavaScript error", js.Error{Value: js.Global().Get("TypeError").New(alreadyClosedMessage)}, false},
+		{"other string", "something else went wrong", true},
+		{"string containing the message", alreadyClosedMessage + " and something else", true},
+		{"other Go error", goErr, true},
+		{"other JavaScript error", js.Error{Value: js.Global().Get("TypeError").New("something else went wrong")}, true},
+		{"JavaScript error that isn't an object", js.Error{Value: js.ValueOf(alreadyClosedMessage)}, true},
+		{"other value", 42, true},
+	}
+
+	for _, test := range tests {
+		err := closeRecovery(test.recovered)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: closeRecovery returned error %v, want error %v", test.name, err, test.wantErr)
+		}
+	}
+
+	if err := closeRecovery(goErr); !errors.Is(err, goErr) {
+		t.Errorf("closeRecovery of a Go error returned %v, which doesn't wrap it", err)
+	}
+}
+
+func TestWritableStreamReadFromPartialFailure(t *testing.T) {
+	// The sink accepts the first chunk, then fails.
+	var received []byte
+	stream := NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			if received != nil {
+				return js.Global().Get("Promise").Call("reject", js.Global().Get("Error").New("sink failed"))
+			}
+			received = make([]byte, args[0].Length())
+			js.CopyBytesToGo(received, args[0])
+			return nil
+		}),
+	}))
+
+	n, err := stream.ReadFrom(io.MultiReader(bytes.NewReader([]byte("first")), bytes.NewReader([]byte("second"))))
+	if err == nil || err.Error() != "sink failed" {
+		t.Fatalf("ReadFrom returned error %v, want %q", err, "sink failed")
+	}
+	if n != int64(len("first")) || string(received) != "first" {
+		t.Fatalf("ReadFrom returned %d and sink received %q, want %d and %q", n, received, len("first"), "first")
+	}
+}
+
+func TestStreamLocked(t *testing.T) {
+	readable := newTestReadableStream([]byte("Hello"))
+	reader := readable.Call("getReader")
+	if _, err := NewReadableStream(readable).Read(make([]byte, 5)); err != ErrStreamLocked {
+		t.Fatalf("Read of a locked stream returned %v, want %v", err, ErrStreamLocked)
+	}
+	reader.Call("releaseLock")
+
+	writable, _ := newTestWritableStream()
+	writer := writable.Call("getWriter")
+	if _, err := NewWritableStream(writable).Write([]byte("Hello")); err != ErrStreamLocked {
+		t.Fatalf("Write to a locked stream returned %v, want %v", err, ErrStreamLocked)
+	}
+	writer.Call("releaseLock")
+}
+
+// endlessReader yields zeros forever.
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// blockingReader blocks every Read until it is closed.
+type blockingReader chan struct{}
+
+func (b blockingReader) Read(p []byte) (int, error) {
+	<-b
+	return 0, io.ErrClosedPipe
+}
+
+func (b blockingReader) Close() error {
+	close(b)
+	return nil
+}
+
+func TestReaderToReadableStreamContext(t *testing.T) {
+	t.Run("endless", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := NewReadableStream(ReaderToReadableStreamContext(ctx, endlessReader{}))
+
+		buffer := make([]byte, 1024)
+		if _, err := stream.Read(buffer); err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+
+		cancel()
+		for i := 0; ; i++ {
+			_, err := stream.Read(buffer)
+			if err != nil {
+				if !strings.Contains(err.Error(), context.Canceled.Error()) {
+					t.Fatalf("Read returned %v, want %v", err, context.Canceled)
+				}
+				break
+			}
+			if i > 100 {
+				t.Fatal("Read kept returning data after the context was cancelled")
+			}
+		}
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := NewReadableStream(ReaderToReadableStreamContext(ctx, make(blockingReader)))
+
+		go cancel()
+		if _, err := stream.Read(make([]byte, 1024)); err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+			t.Fatalf("Read returned %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestReaderToReadableStreamWithHandler(t *testing.T) {
+	readErr := errors.New("read failed")
+	handled := make(chan error, 1)
+	stream := NewReadableStream(ReaderToReadableStreamWithHandler(iotest.ErrReader(readErr), func(err error) {
+		handled <- err
+	}))
+	if _, err := io.ReadAll(stream); err == nil || err.Error() != readErr.Error() {
+		t.Fatalf("ReadAll returned %v, want %v", err, readErr)
+	}
+	if err := <-handled; err != readErr {
+		t.Fatalf("handler was called with %v, want %v", err, readErr)
+	}
+
+	// Cancelling the stream is reported too, with the reason if there is one.
+	for _, test := range []struct {
+		reason []interface{}
+		want   string
+	}{
+		{nil, ErrCancelled.Error()},
+		{[]interface{}{js.Global().Get("Error").New("no longer needed")}, "no longer needed"},
+	} {
+		handled := make(chan error, 1)
+		jsStream := ReaderToReadableStreamWithHandler(strings.NewReader("Hello"), func(err error) {
+			handled <- err
+		})
+		if _, err := await(jsStream.Call("cancel", test.reason...)); err != nil {
+			t.Fatalf("cancel returned error: %v", err)
+		}
+		if err := <-handled; err == nil || err.Error() != test.want {
+			t.Fatalf("handler was called with %v, want %q", err, test.want)
+		}
+	}
+}
+
+func TestReaderToReadableStreamWithCancel(t *testing.T) {
+	for _, test := range []struct {
+		reason []interface{}
+		want   string
+	}{
+		{nil, ""},
+		{[]interface{}{"user aborted"}, "user aborted"},
+		{[]interface{}{js.Global().Get("Error").New("network error")}, "network error"},
+		{[]interface{}{js.Global().Get("DOMException").New("The operation was aborted", "AbortError")},
+			"AbortError: The operation was aborted"},
+	} {
+		cancelled := make(chan string, 1)
+		closed := make(chan struct{})
+		jsStream := ReaderToReadableStreamWithCancel(&closeRecorder{strings.NewReader("Hello"), closed},
+			func(reason string) {
+				cancelled <- reason
+			})
+		if _, err := await(jsStream.Call("cancel", test.reason...)); err != nil {
+			t.Fatalf("cancel returned error: %v", err)
+		}
+		if reason := <-cancelled; reason != test.want {
+			t.Fatalf("handler was called with %q, want %q", reason, test.want)
+		}
+		// The reader is still closed once the handler has run.
+		<-closed
+	}
+}
+
+func TestReaderToReadableStreamSingleChunk(t *testing.T) {
+	// The reader returns all of its data along with io.EOF, so a single pull enqueues the chunk and closes the stream.
+	for _, mode := range []string{ReaderModeBYOB, ReaderModeDefault} {
+		stream := NewReadableStream(ReaderToReadableStream(iotest.DataErrReader(strings.NewReader("Hello, world!"))))
+		reader, err := stream.AcquireReader(mode)
+		if err != nil {
+			t.Fatalf("%s: AcquireReader returned error: %v", mode, err)
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil || string(data) != "Hello, world!" {
+			t.Fatalf("%s: ReadAll returned %q, %v, want %q, nil", mode, data, err, "Hello, world!")
+		}
+	}
+}
+
+func TestReaderToReadableStreamCancelDuringPull(t *testing.T) {
+	handled := make(chan error, 2)
+	jsStream := ReaderToReadableStreamWithHandler(&lateReader{data: "Hello", delay: 20 * time.Millisecond}, func(err error) {
+		handled <- err
+	})
+
+	// The read starts a pull, which is still reading when the stream is cancelled. Once it has read its data, it must
+	// leave the cancelled stream alone rather than enqueue into it.
+	reader := jsStream.Call("getReader")
+	read := reader.Call("read")
+	if _, err := await(reader.Call("cancel")); err != nil {
+		t.Fatalf("cancel returned error: %v", err)
+	}
+	if result, err := await(read); err != nil || !result.Get("done").Bool() {
+		t.Fatalf("read resolved with %v, want done", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := <-handled; err != ErrCancelled {
+		t.Fatalf("handler was called with %v, want %v", err, ErrCancelled)
+	}
+	select {
+	case err := <-handled:
+		t.Fatalf("handler was called again with %v", err)
+	default:
+	}
+}
+
+// returnRecorder sends the error of every Read of its io.ReadCloser to returned, once the Read has returned.
+type returnRecorder struct {
+	io.ReadCloser
+	returned chan error
+}
+
+func (r *returnRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.returned <- err
+	return n, err
+}
+
+func TestReaderToReadableStreamCancelUnblocksPull(t *testing.T) {
+	// Nothing is ever written to the pipe, so the pull blocks in Read until the pipe is closed by cancelling the stream.
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeWriter.Close()
+	source := &returnRecorder{pipeReader, make(chan error, 1)}
+
+	reader := ReaderToReadableStream(source).Call("getReader")
+	read := reader.Call("read")
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case err := <-source.returned:
+		t.Fatalf("Read of an empty pipe returned %v before the stream was cancelled", err)
+	default:
+	}
+
+	if _, err := await(reader.Call("cancel")); err != nil {
+		t.Fatalf("cancel returned error: %v", err)
+	}
+	select {
+	case err := <-source.returned:
+		if err != io.ErrClosedPipe {
+			t.Fatalf("blocked Read returned %v, want %v", err, io.ErrClosedPipe)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked Read did not return once the stream was cancelled")
+	}
+	if result, err := await(read); err != nil || !result.Get("done").Bool() {
+		t.Fatalf("read resolved with %v, want done", err)
+	}
+}
+
+// emptyAfterCloseReader blocks every Read until it is closed, after which it returns (0, nil), counting those reads. It
+// gives up with io.EOF after maxEmptyReads of them, so that a pull which never stops doesn't hang the test.
+type emptyAfterCloseReader struct {
+	closeBlockingReader
+	reads atomic.Int64
+}
+
+const maxEmptyReads = 1000
+
+func (e *emptyAfterCloseReader) Read(p []byte) (int, error) {
+	<-e.closed
+	if e.reads.Add(1) >= maxEmptyReads {
+		return 0, io.EOF
+	}
+	return 0, nil
+}
+
+func TestReaderToReadableStreamCancelStopsPull(t *testing.T) {
+	source := &emptyAfterCloseReader{closeBlockingReader: *newCloseBlockingReader()}
+	reader := ReaderToReadableStream(source).Call("getReader")
+	read := reader.Call("read")
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := await(reader.Call("cancel")); err != nil {
+		t.Fatalf("cancel returned error: %v", err)
+	}
+	if result, err := await(read); err != nil || !result.Get("done").Bool() {
+		t.Fatalf("read resolved with %v, want done", err)
+	}
+
+	// The pull gives up as soon as it sees the stream was cancelled, rather than reading the closed source forever.
+	time.Sleep(50 * time.Millisecond)
+	if reads := source.reads.Load(); reads > 1 {
+		t.Fatalf("the closed source was read %d times, want no more than 1", reads)
+	}
+}
+
+func TestStaticReadableStream(t *testing.T) {
+	for _, mode := range []string{ReaderModeBYOB, ReaderModeDefault} {
+		data := []byte("Hello, world!")
+		stream := NewReadableStream(StaticReadableStream(data))
+		// The chunk is a copy, so changing data afterwards doesn't change the stream.
+		data[0] = 'J'
+
+		reader, err := stream.AcquireReader(mode)
+		if err != nil {
+			t.Fatalf("%s: AcquireReader returned error: %v", mode, err)
+		}
+		read, err := io.ReadAll(reader)
+		if err != nil || string(read) != "Hello, world!" {
+			t.Fatalf("%s: ReadAll returned %q, %v, want %q, nil", mode, read, err, "Hello, world!")
+		}
+	}
+
+	// The whole of the data is a single chunk, followed by the end of the stream.
+	reader := StaticReadableStream([]byte("Hello")).Call("getReader")
+	if result, err := await(reader.Call("read")); err != nil || result.Get("value").Length() != len("Hello") {
+		t.Fatalf("first read resolved with %v, want a single chunk of %d bytes", err, len("Hello"))
+	}
+	if result, err := await(reader.Call("read")); err != nil || !result.Get("done").Bool() {
+		t.Fatalf("second read resolved with %v, want done", err)
+	}
+
+	if data, err := io.ReadAll(NewReadableStream(StaticReadableStream(nil))); err != nil || len(data) != 0 {
+		t.Fatalf("ReadAll of an empty stream returned %q, %v, want nothing and nil", data, err)
+	}
+}
+
+func TestReadableStreamAsyncIterator(t *testing.T) {
+	// for await (const chunk of stream) works through stream[Symbol.asyncIterator].
+	for name, stream := range map[string]js.Value{
+		"ReaderToReadableStream": ReaderToReadableStream(strings.NewReader("Hello, world!")),
+		"StaticReadableStream":   StaticReadableStream([]byte("Hello, world!")),
+	} {
+		asyncIterator := js.Global().Get("Reflect").Call("get", stream, js.Global().Get("Symbol").Get("asyncIterator"))
+		if asyncIterator.Type() != js.TypeFunction {
+			t.Fatalf("%s: stream has no async iterator", name)
+		}
+
+		iterator := asyncIterator.Call("call", stream)
+		var data []byte
+		for {
+			result, err := await(iterator.Call("next"))
+			if err != nil {
+				t.Fatalf("%s: next returned error: %v", name, err)
+			}
+			if result.Get("done").Bool() {
+				break
+			}
+			chunk := make([]byte, result.Get("value").Length())
+			js.CopyBytesToGo(chunk, result.Get("value"))
+			data = append(data, chunk...)
+		}
+		if string(data) != "Hello, world!" {
+			t.Fatalf("%s: iterating the stream gave %q, want %q", name, data, "Hello, world!")
+		}
+	}
+}
+
+func TestReaderToReadableStreamTimeout(t *testing.T) {
+	reader := make(blockingReader)
+	stream := NewReadableStream(ReaderToReadableStreamTimeout(reader, 20*time.Millisecond))
+
+	if _, err := stream.Read(make([]byte, 16)); err == nil || err.Error() != ErrTimeout.Error() {
+		t.Fatalf("Read returned %v, want %v", err, ErrTimeout)
+	}
+	// The reader is released, which unblocks the stuck Read.
+	select {
+	case <-reader:
+	case <-time.After(time.Second):
+		t.Fatal("the reader was not released after the read timed out")
+	}
+
+	// Reads that return in time are unaffected.
+	data, err := io.ReadAll(NewReadableStream(ReaderToReadableStreamTimeout(strings.NewReader("Hello"), time.Second)))
+	if err != nil || string(data) != "Hello" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello")
+	}
+}
+
+// blockingWriter blocks every Write until it is closed.
+type blockingWriter chan struct{}
+
+func (b blockingWriter) Write(p []byte) (int, error) {
+	<-b
+	return 0, io.ErrClosedPipe
+}
+
+func TestWriterToWritableStreamTimeout(t *testing.T) {
+	writer := make(blockingWriter)
+	defer close(writer)
+	stream := NewWritableStream(WriterToWritableStreamTimeout(writer, 20*time.Millisecond))
+
+	if _, err := stream.Write([]byte("Hello")); err == nil || err.Error() != ErrTimeout.Error() {
+		t.Fatalf("Write returned %v, want %v", err, ErrTimeout)
+	}
+	if err := stream.WaitClosed(); err == nil || err.Error() != ErrTimeout.Error() {
+		t.Fatalf("WaitClosed returned %v, want %v", err, ErrTimeout)
+	}
+}
+
+func TestWriterToWritableStreamContext(t *testing.T) {
+	var buffer bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := NewWritableStream(WriterToWritableStreamContext(ctx, &buffer))
+
+	if _, err := stream.Write([]byte("Hello")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	cancel()
+	if _, err := stream.Write([]byte(", world!")); err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Fatalf("Write after cancel returned %v, want %v", err, context.Canceled)
+	}
+	if buffer.String() != "Hello" {
+		t.Fatalf("writer received %q, want %q", buffer.String(), "Hello")
+	}
+}
+
+func TestReaderToReadableStreamFuncsBounded(t *testing.T) {
+	// Every js.FuncOf goes through the Go instance's _makeFuncWrapper, so counting calls to it tells us how many Go
+	// functions were created.
+	count := js.Global().Get("Function").New(`
+		const makeFuncWrapper = Go.prototype._makeFuncWrapper;
+		let count = 0;
+		Go.prototype._makeFuncWrapper = function (id) {
+			count++;
+			return makeFuncWrapper.call(this, id);
+		};
+		return () => count;
+	`).Invoke()
+	defer js.Global().Get("Function").New("makeFuncWrapper", "Go.prototype._makeFuncWrapper = makeFuncWrapper").
+		Invoke(js.Global().Get("Go").Get("prototype").Get("_makeFuncWrapper"))
+
+	const pulls = 1000
+	data := bytes.Repeat([]byte("a"), pulls*16)
+	before := count.Invoke().Int()
+
+	// The stream is consumed entirely by JavaScript, so that the only functions created are the stream's own.
+	stream := ReaderToReadableStreamSize(bytes.NewReader(data), 16)
+	buffer, err := await(js.Global().Get("Response").New(stream).Call("arrayBuffer"))
+	if err != nil {
+		t.Fatalf("reading the stream returned error: %v", err)
+	}
+	if buffer.Get("byteLength").Int() != len(data) {
+		t.Fatalf("read %d bytes, want %d", buffer.Get("byteLength").Int(), len(data))
+	}
+
+	if created := count.Invoke().Int() - before; created > 10 {
+		t.Fatalf("%d functions were created for %d pulls, want it to stay bounded", created, pulls)
+	}
+}
+
+func TestWaitReadableFillComplete(t *testing.T) {
+	// The stream holds a single short chunk and then stalls, so a read filling a whole buffer would never return.
+	source, _ := newCancelRecordingStream("Hello")
+	stream := NewReadableStreamWithOptions(source, ReadableStreamOptions{FillMode: FillComplete})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := stream.WaitReadable(ctx); err != nil {
+		t.Fatalf("WaitReadable returned %v, want nil", err)
+	}
+
+	// The chunk WaitReadable read is kept for the next Read.
+	buffer := make([]byte, len("Hello"))
+	if n, err := stream.Read(buffer); err != nil || string(buffer[:n]) != "Hello" {
+		t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, "Hello")
+	}
+	_ = stream.Close()
+}
+
+func TestWriteFrame(t *testing.T) {
+	// An identity TransformStream passes everything written to its writable side through to its readable side.
+	transform := js.Global().Get("TransformStream").New()
+	writable := NewWritableStream(transform.Get("writable"))
+	readable := NewReadableStream(transform.Get("readable"))
+	reader, err := readable.AcquireReader(ReaderModeDefault)
+	if err != nil {
+		t.Fatalf("AcquireReader returned error: %v", err)
+	}
+
+	payloads := []string{"Hello", "", "world!"}
+	go func() {
+		for _, payload := range payloads {
+			if err := writable.WriteFrame([]byte(payload)); err != nil {
+				t.Errorf("WriteFrame returned error: %v", err)
+			}
+		}
+		_ = writable.Close()
+	}()
+
+	for _, want := range payloads {
+		frame, err := readable.ReadFrame()
+		if err != nil {
+			t.Fatalf("ReadFrame returned error: %v", err)
+		}
+		if string(frame) != want {
+			t.Fatalf("ReadFrame returned %q, want %q", frame, want)
+		}
+	}
+	if _, err := readable.ReadFrame(); err != io.EOF {
+		t.Fatalf("final ReadFrame returned %v, want %v", err, io.EOF)
+	}
+	_ = reader.ReleaseLock()
+}
+
+func TestWriteDoesNotModify(t *testing.T) {
+	// The sink scribbles over every chunk it is given, which must not reach the slice passed to Write.
+	var received [][]byte
+	stream := NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			chunk := make([]byte, args[0].Length())
+			js.CopyBytesToGo(chunk, args[0])
+			received = append(received, chunk)
+			args[0].Call("fill", 0)
+			return nil
+		}),
+	}))
+
+	p := []byte("Hello, world!")
+	original := append([]byte(nil), p...)
+
+	if _, err := stream.Write(p); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if !bytes.Equal(p, original) {
+		t.Fatalf("Write modified p to %q, want %q", p, original)
+	}
+
+	if _, err := stream.ReadFrom(bytes.NewReader(p)); err != nil {
+		t.Fatalf("ReadFrom returned error: %v", err)
+	}
+	if !bytes.Equal(p, original) {
+		t.Fatalf("ReadFrom modified its source to %q, want %q", p, original)
+	}
+
+	for i, chunk := range received {
+		if !bytes.Equal(chunk, original) {
+			t.Fatalf("sink received %q in chunk %d, want %q", chunk, i, original)
+		}
+	}
+}
+
+func TestWriteQuotaExceeded(t *testing.T) {
+	stream := NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			quotaErr := js.Global().Get("Error").New("not enough space")
+			quotaErr.Set("name", "QuotaExceededError")
+			return js.Global().Get("Promise").Call("reject", quotaErr)
+		}),
+	}))
+
+	_, err := stream.Write([]byte("Hello"))
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("Write returned %v, want a *StreamError", err)
+	}
+	if streamErr.Name != "QuotaExceededError" || streamErr.Message != "not enough space" {
+		t.Fatalf("Write returned %q, %q, want %q, %q", streamErr.Name, streamErr.Message, "QuotaExceededError", "not enough space")
+	}
+}
+
+func TestReadableStreamReset(t *testing.T) {
+	stream := NewReadableStream(newTestReadableStream([]byte("Hello")))
+	data, err := io.ReadAll(stream)
+	if err != nil || string(data) != "Hello" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello")
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	stream.Reset(newTestReadableStream([]byte("world!")))
+	data, err = io.ReadAll(stream)
+	if err != nil || string(data) != "world!" {
+		t.Fatalf("ReadAll after Reset returned %q, %v, want %q, nil", data, err, "world!")
+	}
+	if err := stream.WaitClosed(); err != nil {
+		t.Fatalf("WaitClosed after Reset returned error: %v", err)
+	}
+}
+
+func TestFillMode(t *testing.T) {
+	tests := []struct {
+		name string
+		mode FillMode
+		want []string
+	}{
+		{"partial", FillPartial, []string{"Hello", ", ", "world!"}},
+		{"complete", FillComplete, []string{"Hello, w", "orld!"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// A default reader hands over a single chunk per read, whereas a BYOB reader could fill the buffer from several.
+			stream := NewReadableStreamWithOptions(newTestDefaultReadableStream([]byte("Hello"), []byte(", "), []byte("world!")),
+				ReadableStreamOptions{FillMode: test.mode})
+			reader, err := stream.AcquireReader(ReaderModeDefault)
+			if err != nil {
+				t.Fatalf("AcquireReader returned error: %v", err)
+			}
+
+			buffer := make([]byte, 8)
+			var reads []string
+			for {
+				n, err := reader.Read(buffer)
+				if n > 0 {
+					reads = append(reads, string(buffer[:n]))
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Read returned error: %v", err)
+				}
+			}
+
+			if len(reads) != len(test.want) {
+				t.Fatalf("Read returned %q, want %q", reads, test.want)
+			}
+			for i := range reads {
+				if reads[i] != test.want[i] {
+					t.Fatalf("Read returned %q, want %q", reads, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNonErrorRejection(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason interface{}
+		want   string
+	}{
+		{"string", "boom", "boom"},
+		{"number", 42, "42"},
+		{"object", map[string]interface{}{}, "[object Object]"},
+		{"undefined", js.Undefined(), "undefined"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			readable := NewReadableStream(js.Global().Get("ReadableStream").New(map[string]interface{}{
+				"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+					args[0].Call("error", test.reason)
+					return nil
+				}),
+				"type": "bytes",
+			}))
+			if _, err := readable.Read(make([]byte, 8)); err == nil || err.Error() != test.want {
+				t.Fatalf("Read returned %v, want %q", err, test.want)
+			}
+
+			writable := NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+				"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+					return js.Global().Get("Promise").Call("reject", test.reason)
+				}),
+			}))
+			if _, err := writable.Write([]byte("Hello")); err == nil || err.Error() != test.want {
+				t.Fatalf("Write returned %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckedConstructors(t *testing.T) {
+	for _, value := range []js.Value{js.Undefined(), js.Null(), js.ValueOf("stream"), js.ValueOf(map[string]interface{}{})} {
+		if _, err := NewReadableStreamChecked(value); err != ErrNotReadableStream {
+			t.Fatalf("NewReadableStreamChecked(%v) returned %v, want %v", value, err, ErrNotReadableStream)
+		}
+		if _, err := NewWritableStreamChecked(value); err != ErrNotWritableStream {
+			t.Fatalf("NewWritableStreamChecked(%v) returned %v, want %v", value, err, ErrNotWritableStream)
+		}
+	}
+
+	if _, err := NewReadableStreamChecked(newTestReadableStream()); err != nil {
+		t.Fatalf("NewReadableStreamChecked returned error for a ReadableStream: %v", err)
+	}
+	jsStream, _ := newTestWritableStream()
+	if _, err := NewWritableStreamChecked(jsStream); err != nil {
+		t.Fatalf("NewWritableStreamChecked returned error for a WritableStream: %v", err)
+	}
+	if _, err := NewReadableStreamChecked(jsStream); err != ErrNotReadableStream {
+		t.Fatalf("NewReadableStreamChecked of a WritableStream returned %v, want %v", err, ErrNotReadableStream)
+	}
+}
+
+func TestWritableStreamWriteBackpressure(t *testing.T) {
+	// The sink holds on to the first chunk until we let it go, so the queue is full when Write starts.
+	var chunks []string
+	var resolveFirst js.Value
+	stream := js.Global().Get("WritableStream").New(map[string]interface{}{
+		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			chunks = append(chunks, js.Global().Get("TextDecoder").New().Call("decode", args[0]).String())
+			if len(chunks) > 1 {
+				return nil
+			}
+			return js.Global().Get("Promise").New(js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				resolveFirst = args[0]
+				return nil
+			}))
+		}),
+	}, map[string]interface{}{"highWaterMark": 1})
+
+	writer := stream.Call("getWriter")
+	ignoreRejection(writer.Call("write", js.Global().Get("TextEncoder").New().Call("encode", "first")))
+	writer.Call("releaseLock")
+
+	writable := NewWritableStream(stream)
+	if size, ok := writable.DesiredSize(); !ok || size > 0 {
+		t.Fatalf("DesiredSize returned %d, %v, want the stream to be backpressured", size, ok)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		resolveFirst.Invoke()
+	}()
+
+	if _, err := writable.Write([]byte("second")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if len(chunks) != 2 || chunks[0] != "first" || chunks[1] != "second" {
+		t.Fatalf("sink received %q, want %q", chunks, []string{"first", "second"})
+	}
+}
+
+// BenchmarkReaderToReadableStream reads a long stream converted from an io.Reader, 32 KiB at a time, through each kind of
+// reader. A default reader gets every chunk in a new ArrayBuffer, as an enqueued chunk is transferred to the consumer and
+// can't be reused, whereas a BYOB reader has each chunk read straight into its own buffer. On Node.js, both cost about
+// 48 Go allocations and 200µs a chunk, the difference being in the JavaScript garbage left behind.
+func BenchmarkReaderToReadableStream(b *testing.B) {
+	for _, mode := range []string{ReaderModeBYOB, ReaderModeDefault} {
+		b.Run(mode, func(b *testing.B) {
+			reader, err := NewReadableStream(ReaderToReadableStream(endlessReader{})).AcquireReader(mode)
+			if err != nil {
+				b.Fatalf("AcquireReader returned error: %v", err)
+			}
+
+			buffer := make([]byte, defaultChunkSize)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(buffer)))
+			for i := 0; i < b.N; i++ {
+				if _, err := io.ReadFull(reader, buffer); err != nil {
+					b.Fatalf("ReadFull returned error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkWritableStreamWrite writes 1 KiB at a time to a stream whose sink accepts every chunk straight away, so that
+// the stream is never backpressured. On Node.js, skipping the wait for ready when the stream has room took each Write from
+// about 140µs to 100µs, and from 36 allocations to 23.
+func BenchmarkWritableStreamWrite(b *testing.B) {
+	stream := NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return nil
+		}),
+	}))
+
+	buffer := make([]byte, 1024)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(buffer)))
+	for i := 0; i < b.N; i++ {
+		if _, err := stream.Write(buffer); err != nil {
+			b.Fatalf("Write returned error: %v", err)
+		}
+	}
+}
+
+func TestContractChecksFromJavaScript(t *testing.T) {
+	enableContractChecks(t)
+
+	// A Go reader or writer breaking the contract errors its stream, rather than panicking under JavaScript.
+	if _, err := newGoReadableStream(io.NopCloser(overstatingReader{})).Read(make([]byte, 4)); err == nil ||
+		!strings.Contains(err.Error(), ErrContractViolation.Error()) {
+		t.Fatalf("Read from an overstating reader returned %v, want %v", err, ErrContractViolation)
+	}
+	if _, err := newGoWritableStream(shortWriter{}).Write(make([]byte, 4)); err == nil ||
+		!strings.Contains(err.Error(), ErrContractViolation.Error()) {
+		t.Fatalf("Write to a short writer returned %v, want %v", err, ErrContractViolation)
+	}
+
+	// Streams that keep to the contract are left alone.
+	stream := NewReadableStreamWithOptions(newTestReadableStream([]byte("Hello, "), []byte("world!")),
+		ReadableStreamOptions{FillMode: FillComplete})
+	if data, err := io.ReadAll(stream); err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+	writable, sink := newTestWritableStream()
+	if _, err := NewWritableStream(writable).Write([]byte("Hello")); err != nil || string(sink.bytes()) != "Hello" {
+		t.Fatalf("Write returned %v, and the sink received %q, want nil and %q", err, sink.bytes(), "Hello")
+	}
+}
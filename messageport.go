@@ -0,0 +1,105 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall/js"
+)
+
+// MessagePortStream adapts a JavaScript MessagePort, such as one end of a MessageChannel shared with a worker, into an
+// io.ReadWriteCloser. Every message received whose data is an ArrayBuffer, a TypedArray or a DataView becomes data to
+// Read, in the order the messages arrived, and other messages are ignored. Every Write is posted as a single message
+// holding an ArrayBuffer, which is transferred rather than copied. The port is started by listening to it through its
+// onmessage property, which replaces any existing handler. Closing the returned stream closes the port.
+func MessagePortStream(port js.Value) io.ReadWriteCloser {
+	var closed bool
+
+	readable := js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			readController := args[0]
+			port.Set("onmessage", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				data, ok := toUint8Array(args[0].Get("data"))
+				if !closed && ok && data.Length() > 0 {
+					// Enqueueing on a byte stream transfers the whole buffer, which other views may share, so a copy is enqueued.
+					readController.Call("enqueue", js.Global().Get("Uint8Array").New(data))
+				}
+				return nil
+			}))
+			return nil
+		}),
+		"cancel": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			closed = true
+			port.Call("close")
+			return nil
+		}),
+		"type": "bytes",
+	})
+
+	writable := js.Global().Get("WritableStream").New(map[string]interface{}{
+		"write": js.FuncOf(func(this js.Value, args []js.Value) (result interface{}) {
+			defer func() {
+				// postMessage throws if the data can't be transferred, which should reject the write rather than crash.
+				recovered := recover()
+				if recovered != nil {
+					result = js.Global().Get("Promise").Call("reject", js.Global().Get("Error").New(fmt.Sprint(recovered)))
+				}
+			}()
+
+			chunk, ok := toUint8Array(args[0])
+			if !ok {
+				return js.Global().Get("Promise").Call("reject", js.Global().Get("TypeError").New("chunk must be a BufferSource"))
+			}
+			// Only a chunk that covers its whole buffer can be transferred without taking anything else with it.
+			if chunk.Get("byteLength").Int() != chunk.Get("buffer").Get("byteLength").Int() {
+				chunk = chunk.Call("slice")
+			}
+
+			buffer := chunk.Get("buffer")
+			port.Call("postMessage", buffer, []interface{}{buffer})
+			return nil
+		}),
+		"close": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			closed = true
+			port.Call("close")
+			return nil
+		}),
+	})
+
+	return NewDuplexStream(readable, writable)
+}
+
+// PostStream sends a JavaScript stream, such as a ReadableStream, to target, which is anything with a postMessage
+// method, such as a Worker or a MessagePort, so that the other side can carry on reading from or writing to it, for
+// instance to offload processing to a worker. The stream is transferred, not copied, so it is listed in the transfer
+// list as well as being the message, and it is locked on this side afterwards, so nothing here can use it any more. An
+// error is returned if the stream can't be transferred, such as when it is locked, or the runtime doesn't support
+// transferring streams.
+func PostStream(target js.Value, stream js.Value) (err error) {
+	defer func() {
+		// postMessage throws a DataCloneError if the stream can't be transferred.
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	target.Call("postMessage", stream, []interface{}{stream})
+	return nil
+}
+
+// ErrNoStreamInMessage is returned by ReceiveStream if the message doesn't hold a ReadableStream.
+var ErrNoStreamInMessage = errors.New("message does not hold a ReadableStream")
+
+// ReceiveStream wraps the ReadableStream sent by PostStream, given the MessageEvent it arrived in, such as the argument
+// of a worker's onmessage handler, so that it can be read from Go. ErrNoStreamInMessage is returned if the message
+// holds anything else.
+func ReceiveStream(event js.Value) (*ReadableStream, error) {
+	data := event.Get("data")
+	if data.Type() != js.TypeObject || !isInstance(data, "ReadableStream") {
+		return nil, ErrNoStreamInMessage
+	}
+	return NewReadableStream(data), nil
+}
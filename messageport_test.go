@@ -0,0 +1,94 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"io"
+	"syscall/js"
+	"testing"
+)
+
+// newEchoPort creates a mock MessagePort that delivers every message posted to it back to its own onmessage handler.
+func newEchoPort() js.Value {
+	return js.Global().Get("Function").New(`
+		return {
+			onmessage: null,
+			closed: false,
+			postMessage(data, transfer) {
+				queueMicrotask(() => this.onmessage({ data }));
+			},
+			close() {
+				this.closed = true;
+			},
+		};
+	`).Invoke()
+}
+
+func TestMessagePortStream(t *testing.T) {
+	port := newEchoPort()
+	stream := MessagePortStream(port)
+
+	for _, message := range []string{"ping", "pong"} {
+		if _, err := stream.Write([]byte(message)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+
+		buffer := make([]byte, 16)
+		n, err := stream.Read(buffer)
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+		if string(buffer[:n]) != message {
+			t.Fatalf("Read returned %q, want %q", buffer[:n], message)
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if !port.Get("closed").Bool() {
+		t.Fatal("Close did not close the port")
+	}
+}
+
+func TestPostStream(t *testing.T) {
+	channel := js.Global().Get("MessageChannel")
+	if channel.Type() != js.TypeFunction {
+		t.Skip("MessageChannel is not supported")
+	}
+	ports := channel.New()
+	defer ports.Get("port1").Call("close")
+	defer ports.Get("port2").Call("close")
+
+	received := make(chan js.Value, 1)
+	ports.Get("port2").Set("onmessage", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		received <- args[0]
+		return nil
+	}))
+
+	stream := newTestReadableStream([]byte("Hello, "), []byte("world!"))
+	if err := PostStream(ports.Get("port1"), stream); err != nil {
+		t.Skipf("transferring streams is not supported: %v", err)
+	}
+	if !stream.Get("locked").Bool() {
+		t.Fatal("the stream is not locked after it was transferred")
+	}
+
+	// Posting a locked stream fails, rather than throwing.
+	if err := PostStream(ports.Get("port1"), stream); err == nil {
+		t.Fatal("PostStream of a locked stream succeeded")
+	}
+
+	event := <-received
+	transferred, err := ReceiveStream(event)
+	if err != nil {
+		t.Fatalf("ReceiveStream returned error: %v", err)
+	}
+	if data, err := io.ReadAll(transferred); err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+
+	if _, err := ReceiveStream(js.ValueOf(map[string]interface{}{"data": "Hello"})); err != ErrNoStreamInMessage {
+		t.Fatalf("ReceiveStream of a message without a stream returned %v, want %v", err, ErrNoStreamInMessage)
+	}
+}
@@ -0,0 +1,48 @@
+package jsStreams
+
+import (
+	"errors"
+	"sync"
+)
+
+// multiWriter writes everything written to it to every one of its sinks at once.
+type multiWriter struct {
+	sinks []*WritableStream
+}
+
+func (m *multiWriter) Write(p []byte) (int, error) {
+	errs := make([]error, len(m.sinks))
+
+	var wait sync.WaitGroup
+	for i, sink := range m.sinks {
+		wait.Add(1)
+		go func(i int, sink *WritableStream) {
+			defer wait.Done()
+			_, errs[i] = sink.Write(p)
+		}(i, sink)
+	}
+	wait.Wait()
+
+	err := errors.Join(errs...)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (m *multiWriter) Close() error {
+	errs := make([]error, len(m.sinks))
+	for i, sink := range m.sinks {
+		errs[i] = sink.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// MultiWritableStream creates a WritableStream that writes every chunk written to it to all of sinks, waiting for each
+// of them to accept it before the write completes, so the returned stream only goes as fast as the slowest sink. It is
+// the writing counterpart to teeing a ReadableStream. If any sink fails, the write fails with the errors of every sink
+// that failed joined together, though the sinks that succeeded will have received the chunk. Closing the returned
+// stream closes every sink.
+func MultiWritableStream(sinks ...*WritableStream) *WritableStream {
+	return newGoWritableStream(&multiWriter{sinks: sinks})
+}
@@ -0,0 +1,63 @@
+package jsStreams
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// recordingSink records everything written to it, and whether it has been closed.
+type recordingSink struct {
+	bytes.Buffer
+	closed bool
+	err    error
+}
+
+func (r *recordingSink) Write(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.Buffer.Write(p)
+}
+
+func (r *recordingSink) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestMultiWritableStream(t *testing.T) {
+	first, second := &recordingSink{}, &recordingSink{}
+	stream := MultiWritableStream(newGoWritableStream(first), newGoWritableStream(second))
+
+	if _, err := io.Copy(stream, strings.NewReader("Hello, world!")); err != nil {
+		t.Fatalf("Copy returned error: %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	for i, sink := range []*recordingSink{first, second} {
+		if sink.String() != "Hello, world!" {
+			t.Fatalf("sink %d received %q, want %q", i, sink.String(), "Hello, world!")
+		}
+		if !sink.closed {
+			t.Fatalf("sink %d was not closed", i)
+		}
+	}
+}
+
+func TestMultiWritableStreamError(t *testing.T) {
+	firstErr, secondErr := errors.New("first failed"), errors.New("second failed")
+	stream := MultiWritableStream(
+		newGoWritableStream(&recordingSink{err: firstErr}),
+		newGoWritableStream(&recordingSink{}),
+		newGoWritableStream(&recordingSink{err: secondErr}),
+	)
+
+	_, err := stream.Write([]byte("Hello"))
+	if err == nil || !strings.Contains(err.Error(), firstErr.Error()) || !strings.Contains(err.Error(), secondErr.Error()) {
+		t.Fatalf("Write returned %v, want both %v and %v", err, firstErr, secondErr)
+	}
+}
@@ -0,0 +1,120 @@
+package jsStreams
+
+import (
+	"bufio"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// MultipartWriter writes a multipart/form-data body to a WritableStream, one part at a time, such as for uploading files
+// along with some metadata. Files are streamed in from an io.Reader, so no file is ever held in memory whole. Writes are
+// gathered into chunks of up to 32 KiB, rather than each piece of framing costing a write of its own. Its methods are
+// safe to call from any goroutine, and a part is always written whole, before the next.
+type MultipartWriter struct {
+	stream *WritableStream
+	lock   sync.Mutex
+	buffer *bufio.Writer
+	writer *multipart.Writer
+	// err is the error the body failed with, if any, which every later call returns.
+	err error
+}
+
+// NewMultipartWriter creates a MultipartWriter that writes to w, separating its parts with boundary. If boundary is
+// empty, a random one is used, and if it isn't a valid boundary, every method returns the error saying why.
+func NewMultipartWriter(w *WritableStream, boundary string) *MultipartWriter {
+	buffer := bufio.NewWriterSize(w, defaultChunkSize)
+	m := &MultipartWriter{stream: w, buffer: buffer, writer: multipart.NewWriter(buffer)}
+	if boundary != "" {
+		m.err = m.writer.SetBoundary(boundary)
+	}
+	return m
+}
+
+// Boundary returns the boundary separating the parts.
+func (m *MultipartWriter) Boundary() string {
+	return m.writer.Boundary()
+}
+
+// FormDataContentType returns the Content-Type of the body, multipart/form-data with its boundary, to be sent along with
+// it, for instance in the headers of a fetch.
+func (m *MultipartWriter) FormDataContentType() string {
+	return m.writer.FormDataContentType()
+}
+
+// WriteField writes a part holding a form field called name, whose value is value.
+func (m *MultipartWriter) WriteField(name, value string) error {
+	_, err := m.writePart(func() (io.Writer, error) {
+		return m.writer.CreateFormField(name)
+	}, strings.NewReader(value))
+	return err
+}
+
+// WriteFile writes a part holding a file called fileName, for the form field called name, of the given MIME type, which
+// defaults to application/octet-stream if it is empty. The file's contents are streamed in from r until it reaches
+// io.EOF, without closing it, and the number of bytes read from r is returned.
+func (m *MultipartWriter) WriteFile(name, fileName, contentType string, r io.Reader) (int64, error) {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return m.writePart(func() (io.Writer, error) {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition",
+			`form-data; name="`+quoteEscaper.Replace(name)+`"; filename="`+quoteEscaper.Replace(fileName)+`"`)
+		header.Set("Content-Type", contentType)
+		return m.writer.CreatePart(header)
+	}, r)
+}
+
+// quoteEscaper escapes the quoted strings of a Content-Disposition header, as mime/multipart does.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// writePart writes a part created by create, holding everything read from r.
+func (m *MultipartWriter) writePart(create func() (io.Writer, error), r io.Reader) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.err != nil {
+		return 0, m.err
+	}
+
+	part, err := create()
+	if err != nil {
+		m.err = err
+		return 0, err
+	}
+	n, err := io.Copy(part, r)
+	if err != nil {
+		m.err = err
+	}
+	return n, err
+}
+
+// Close writes the closing boundary, which ends the body, and flushes everything to the stream. It doesn't close the
+// stream, so more can be written after the body, unless the body is all there is, in which case the stream should be
+// closed afterwards.
+func (m *MultipartWriter) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+
+	m.err = m.writer.Close()
+	if m.err == nil {
+		m.err = m.buffer.Flush()
+	}
+	if m.err == nil {
+		m.err = m.stream.Flush()
+	}
+	if m.err != nil {
+		return m.err
+	}
+
+	// Nothing more can be written to the body.
+	m.err = io.ErrClosedPipe
+	return nil
+}
@@ -0,0 +1,68 @@
+package jsStreams
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+func TestMultipartWriter(t *testing.T) {
+	sink := &recordingSink{}
+	stream := newGoWritableStream(sink)
+	writer := NewMultipartWriter(stream, "test-boundary")
+
+	file := bytes.Repeat([]byte("0123456789"), 10000)
+	if err := writer.WriteField("title", "Hello, world!"); err != nil {
+		t.Fatalf("WriteField returned error: %v", err)
+	}
+	if n, err := writer.WriteFile("upload", `dig"its.txt`, "text/plain", bytes.NewReader(file)); err != nil || n != int64(len(file)) {
+		t.Fatalf("WriteFile returned %d, %v, want %d, nil", n, err, len(file))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if err := writer.WriteField("late", ""); err != io.ErrClosedPipe {
+		t.Fatalf("WriteField after Close returned %v, want %v", err, io.ErrClosedPipe)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	// The body is read back by the standard library, with the boundary from the Content-Type.
+	mediaType, params, err := mime.ParseMediaType(writer.FormDataContentType())
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] != "test-boundary" {
+		t.Fatalf("FormDataContentType returned %q, want multipart/form-data with the boundary", writer.FormDataContentType())
+	}
+	reader := multipart.NewReader(strings.NewReader(sink.String()), params["boundary"])
+
+	part, err := reader.NextPart()
+	if err != nil || part.FormName() != "title" {
+		t.Fatalf("NextPart returned %v, want the title field", err)
+	}
+	if value, err := io.ReadAll(part); err != nil || string(value) != "Hello, world!" {
+		t.Fatalf("title field holds %q, %v, want %q", value, err, "Hello, world!")
+	}
+
+	part, err = reader.NextPart()
+	if err != nil || part.FormName() != "upload" || part.FileName() != `dig"its.txt` ||
+		part.Header.Get("Content-Type") != "text/plain" {
+		t.Fatalf("NextPart returned %v, want the uploaded file", err)
+	}
+	if contents, err := io.ReadAll(part); err != nil || !bytes.Equal(contents, file) {
+		t.Fatalf("file holds %d bytes, %v, want the %d written", len(contents), err, len(file))
+	}
+
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Fatalf("NextPart after the last part returned %v, want %v", err, io.EOF)
+	}
+}
+
+func TestMultipartWriterInvalidBoundary(t *testing.T) {
+	writer := NewMultipartWriter(newGoWritableStream(&recordingSink{}), "not a valid boundary!")
+	if err := writer.WriteField("title", "Hello"); err == nil {
+		t.Fatal("WriteField with an invalid boundary returned no error")
+	}
+}
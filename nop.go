@@ -0,0 +1,33 @@
+package jsStreams
+
+import (
+	"io"
+)
+
+type nopReadCloser struct {
+	*ReadableStream
+}
+
+func (nopReadCloser) Close() error {
+	return nil
+}
+
+// NopReadCloser returns an io.ReadCloser that reads from r, but whose Close method does nothing. This allows a stream
+// to be handed to code that will close it, while keeping the underlying JavaScript ReadableStream open for further use.
+func NopReadCloser(r *ReadableStream) io.ReadCloser {
+	return nopReadCloser{r}
+}
+
+type nopWriteCloser struct {
+	*WritableStream
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// NopWriteCloser returns an io.WriteCloser that writes to w, but whose Close method does nothing. This allows a stream
+// to be handed to code that will close it, while keeping the underlying JavaScript WritableStream open for further use.
+func NopWriteCloser(w *WritableStream) io.WriteCloser {
+	return nopWriteCloser{w}
+}
@@ -0,0 +1,44 @@
+package jsStreams
+
+import (
+	"io"
+	"testing"
+)
+
+func TestNopReadCloser(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	stream := newGoReadableStream(pipeReader)
+
+	if err := NopReadCloser(stream).Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	// The stream is still open, so data written afterwards can be read from it.
+	go pipeWriter.Write([]byte("Hello"))
+	buffer := make([]byte, len("Hello"))
+	if n, err := io.ReadFull(stream, buffer); err != nil || string(buffer[:n]) != "Hello" {
+		t.Fatalf("ReadFull returned %q, %v, want %q, nil", buffer[:n], err, "Hello")
+	}
+	_ = stream.Close()
+}
+
+func TestNopWriteCloser(t *testing.T) {
+	sink := &recordingSink{}
+	stream := newGoWritableStream(sink)
+
+	if err := NopWriteCloser(stream).Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if sink.closed {
+		t.Fatal("Close closed the underlying sink")
+	}
+
+	// The stream is still open, so it can still be written to.
+	if _, err := stream.Write([]byte("Hello")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if sink.String() != "Hello" {
+		t.Fatalf("sink received %q, want %q", sink.String(), "Hello")
+	}
+	_ = stream.Close()
+}
@@ -0,0 +1,46 @@
+package jsStreams
+
+import (
+	"sync"
+)
+
+// closeNotifier records the first time a stream finishes, whether by ending, erroring or being closed, and wakes up
+// anything waiting for that to happen. The zero value is ready to use.
+type closeNotifier struct {
+	init sync.Once
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+func (c *closeNotifier) channel() chan struct{} {
+	c.init.Do(func() {
+		c.done = make(chan struct{})
+	})
+	return c.done
+}
+
+// finish records that the stream has finished with err, which is nil if it finished normally. Only the first call has
+// any effect.
+func (c *closeNotifier) finish(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.channel())
+	})
+}
+
+// wait blocks until finish has been called, returning the error it was called with.
+func (c *closeNotifier) wait() error {
+	<-c.channel()
+	return c.err
+}
+
+// finished reports whether finish has been called, and the error it was called with, without blocking.
+func (c *closeNotifier) finished() (bool, error) {
+	select {
+	case <-c.channel():
+		return true, c.err
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,58 @@
+package jsStreams
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ObjectReadableStream is a stream of values rather than bytes, such as the lines of a text stream, mirroring a
+// JavaScript ReadableStream whose chunks are arbitrary values. Values are read one at a time with ReadValue.
+type ObjectReadableStream struct {
+	next   func() (interface{}, error)
+	close  func() error
+	lock   sync.Mutex
+	closed atomic.Bool
+	err    error
+}
+
+// newObjectReadableStream creates an ObjectReadableStream that gets each value from next, and calls close once when it is
+// closed. Once next returns an error, it is not called again, and every later read returns the same error.
+func newObjectReadableStream(next func() (interface{}, error), close func() error) *ObjectReadableStream {
+	return &ObjectReadableStream{next: next, close: close}
+}
+
+// ReadValue reads the next value from the stream, blocking until one is available. It returns io.EOF once the stream has
+// ended, and io.ErrClosedPipe if the stream has been closed, including while it was waiting.
+func (o *ObjectReadableStream) ReadValue() (interface{}, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.closed.Load() {
+		return nil, io.ErrClosedPipe
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	value, err := o.next()
+	if o.closed.Load() {
+		// Whatever the interrupted read returned, the stream was closed under it.
+		return nil, io.ErrClosedPipe
+	}
+	if err != nil {
+		o.err = err
+		return nil, err
+	}
+	return value, nil
+}
+
+// Close closes the stream, and whatever it reads its values from. It doesn't wait for a ReadValue in progress, which
+// returns io.ErrClosedPipe once whatever it is waiting on has been closed. If the stream is already closed, Close does
+// nothing.
+func (o *ObjectReadableStream) Close() error {
+	if !o.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return o.close()
+}
@@ -0,0 +1,69 @@
+package jsStreams
+
+import (
+	"context"
+	"io"
+)
+
+// PipeBetween copies everything from src to dst, then closes dst, returning any error from either. If the copy fails,
+// or ctx is done before it completes, src is closed, cancelling it, and the first error is returned, which is ctx's
+// error in the case of cancellation. dst is left open on failure, as closing it would make a partial copy look complete
+// to its sink, so it is up to the caller to decide what to do with it. PipeBetween only returns once the copy has
+// stopped, so a cancellation takes effect once any Read of src in progress returns.
+func PipeBetween(ctx context.Context, dst *WritableStream, src *ReadableStream) error {
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(dst, src)
+		copied <- err
+	}()
+
+	select {
+	case err := <-copied:
+		if err != nil {
+			_ = src.Close()
+			return err
+		}
+		return dst.Close()
+	case <-ctx.Done():
+		_ = src.Close()
+		<-copied
+		return ctx.Err()
+	}
+}
+
+// Splice forwards everything from src to dst, one chunk at a time, passing each chunk through transform on the way, and
+// returns the number of bytes written to dst. A nil transform copies the chunks as they are. Each write waits for dst to
+// accept the chunk, so a slow sink holds back reading from src. The chunk passed to transform is only valid until it
+// returns, and a chunk it transforms into nothing is skipped. Splice stops at the first error from src, transform or dst,
+// and returns it, but never closes either stream, so the caller decides what a partial copy means. Reaching the end of
+// src is not an error.
+func Splice(dst *WritableStream, src *ReadableStream, transform func([]byte) ([]byte, error)) (int64, error) {
+	var written int64
+	buffer := make([]byte, defaultChunkSize)
+	for {
+		n, err := src.Read(buffer)
+		if n > 0 {
+			output := buffer[:n]
+			if transform != nil {
+				var transformErr error
+				output, transformErr = transform(output)
+				if transformErr != nil {
+					return written, transformErr
+				}
+			}
+			if len(output) > 0 {
+				wrote, writeErr := dst.Write(output)
+				written += int64(wrote)
+				if writeErr != nil {
+					return written, writeErr
+				}
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package jsStreams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestPipeBetween(t *testing.T) {
+	sink := &recordingSink{}
+	if err := PipeBetween(context.Background(), newGoWritableStream(sink), newStringStream("Hello, world!")); err != nil {
+		t.Fatalf("PipeBetween returned error: %v", err)
+	}
+	if sink.String() != "Hello, world!" {
+		t.Fatalf("sink received %q, want %q", sink.String(), "Hello, world!")
+	}
+	if !sink.closed {
+		t.Fatal("PipeBetween did not close dst")
+	}
+}
+
+// startedReader yields zeros forever, closing started once it has first been read from.
+type startedReader struct {
+	started chan struct{}
+	once    sync.Once
+}
+
+func (s *startedReader) Read(p []byte) (int, error) {
+	s.once.Do(func() { close(s.started) })
+	return len(p), nil
+}
+
+func TestPipeBetweenCancel(t *testing.T) {
+	source := &startedReader{started: make(chan struct{})}
+	src := newGoReadableStream(io.NopCloser(source))
+	sink := &recordingSink{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-source.started
+		cancel()
+	}()
+
+	if err := PipeBetween(ctx, newGoWritableStream(sink), src); err != context.Canceled {
+		t.Fatalf("PipeBetween returned %v, want %v", err, context.Canceled)
+	}
+	if sink.closed {
+		t.Fatal("PipeBetween closed dst after being cancelled")
+	}
+	if _, err := src.Read(make([]byte, 1)); err != io.ErrClosedPipe {
+		t.Fatalf("Read of src returned %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestSplice(t *testing.T) {
+	double := func(chunk []byte) ([]byte, error) {
+		return append(append([]byte(nil), chunk...), chunk...), nil
+	}
+
+	for _, test := range []struct {
+		name      string
+		transform func([]byte) ([]byte, error)
+		want      string
+	}{
+		{"nil", nil, "Hello, world!"},
+		{"identity", func(chunk []byte) ([]byte, error) { return chunk, nil }, "Hello, world!"},
+		{"double", double, "Hello, Hello, world!world!"},
+	} {
+		sink := &recordingSink{}
+		n, err := Splice(newGoWritableStream(sink), newChunkedStream("Hello, ", "world!"), test.transform)
+		if err != nil || n != int64(len(test.want)) {
+			t.Fatalf("%s: Splice returned %d, %v, want %d, nil", test.name, n, err, len(test.want))
+		}
+		if sink.String() != test.want {
+			t.Fatalf("%s: sink received %q, want %q", test.name, sink.String(), test.want)
+		}
+		if sink.closed {
+			t.Fatalf("%s: Splice closed dst", test.name)
+		}
+	}
+}
+
+func TestSpliceTransformError(t *testing.T) {
+	transformErr := errors.New("transform failed")
+	sink := &recordingSink{}
+	n, err := Splice(newGoWritableStream(sink), newChunkedStream("Hello, ", "world!"), func(chunk []byte) ([]byte, error) {
+		if string(chunk) == "world!" {
+			return nil, transformErr
+		}
+		return chunk, nil
+	})
+	if err != transformErr || n != int64(len("Hello, ")) {
+		t.Fatalf("Splice returned %d, %v, want %d, %v", n, err, len("Hello, "), transformErr)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"sync"
+	"syscall/js"
+)
+
+// ErrStreamNotFinished is returned by StreamPool.Put and WritableStreamPool.Put if the stream is still in use.
+var ErrStreamNotFinished = errors.New("stream has not been read to its end or closed")
+
+// StreamPool reuses ReadableStream wrappers, backed by a sync.Pool, for applications that wrap a large number of
+// short-lived streams, such as one per message, and would otherwise allocate a new wrapper for each of them. The zero
+// value is ready to use, and a StreamPool is safe to use from multiple goroutines at once.
+type StreamPool struct {
+	pool sync.Pool
+}
+
+// Get returns a ReadableStream for stream, reusing a wrapper put back into the pool if there is one. The wrapper is in
+// the same state as one returned by NewReadableStream.
+func (p *StreamPool) Get(stream js.Value) *ReadableStream {
+	wrapper, _ := p.pool.Get().(*ReadableStream)
+	if wrapper == nil {
+		return NewReadableStream(stream)
+	}
+	wrapper.Reset(stream)
+	return wrapper
+}
+
+// Put puts r back into the pool once it is no longer needed, resetting all of its state. Only a stream that has been
+// read to its end, has errored, or has been closed can be put back, as it would otherwise still be in use, so Put returns
+// ErrStreamNotFinished for any other stream, which isn't put back. r must not be used again after it has been put back.
+func (p *StreamPool) Put(r *ReadableStream) error {
+	if !r.closed.Load() && r.State() == StateReadable {
+		return ErrStreamNotFinished
+	}
+
+	r.Reset(js.Undefined())
+	p.pool.Put(r)
+	return nil
+}
+
+// WritableStreamPool reuses WritableStream wrappers, as StreamPool does for ReadableStream wrappers, for applications that
+// write to a large number of short-lived streams. Combined with ReuseBuffer, a wrapper's write buffer is kept with it
+// while it sits in the pool, so that the streams share buffers, rather than each allocating its own. The zero value is
+// ready to use, and a WritableStreamPool is safe to use from multiple goroutines at once.
+type WritableStreamPool struct {
+	// Options configures the wrappers the pool creates.
+	Options WritableStreamOptions
+
+	pool sync.Pool
+}
+
+// Get returns a WritableStream for stream, reusing a wrapper put back into the pool if there is one. The wrapper is in
+// the same state as one returned by NewWritableStreamWithOptions with the pool's Options.
+func (p *WritableStreamPool) Get(stream js.Value) *WritableStream {
+	wrapper, _ := p.pool.Get().(*WritableStream)
+	if wrapper == nil {
+		return NewWritableStreamWithOptions(stream, p.Options)
+	}
+	wrapper.Reset(stream)
+	return wrapper
+}
+
+// Put puts w back into the pool once it is no longer needed, resetting all of its state. Only a stream that has been
+// closed, or has failed, can be put back, as it would otherwise still be in use, so Put returns ErrStreamNotFinished for
+// any other stream, which isn't put back. w must not be used again after it has been put back.
+func (p *WritableStreamPool) Put(w *WritableStream) error {
+	if finished, _ := w.finished.finished(); !w.closed.Load() && !finished {
+		return ErrStreamNotFinished
+	}
+
+	w.Reset(js.Undefined())
+	p.pool.Put(w)
+	return nil
+}
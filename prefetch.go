@@ -0,0 +1,106 @@
+package jsStreams
+
+import (
+	"io"
+	"sync"
+)
+
+// prefetchedChunk is a chunk read ahead from a stream, along with the error the read returned.
+type prefetchedChunk struct {
+	data []byte
+	err  error
+}
+
+// prefetchReader reads chunks from a stream ahead of time in the background, and serves reads from them.
+type prefetchReader struct {
+	source  *ReadableStream
+	chunks  chan prefetchedChunk
+	done    chan struct{}
+	once    sync.Once
+	lock    sync.Mutex
+	pending []byte
+	err     error
+}
+
+// prefetch reads from the source until it fails or the reader is closed, queueing each chunk it reads. The queue is
+// bounded, so prefetch stops reading while it is full, and the source doesn't run ahead of the consumer without limit.
+func (p *prefetchReader) prefetch() {
+	defer close(p.chunks)
+	for {
+		buffer := make([]byte, defaultChunkSize)
+		n, err := p.source.Read(buffer)
+		if n > 0 || err != nil {
+			select {
+			case p.chunks <- prefetchedChunk{buffer[:n], err}:
+			case <-p.done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *prefetchReader) Read(b []byte) (int, error) {
+	// Once closed, nothing more is read, even if a Read parked on the queue still holds the lock.
+	select {
+	case <-p.done:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for len(p.pending) == 0 {
+		if p.err != nil {
+			return 0, p.err
+		}
+		select {
+		case chunk, ok := <-p.chunks:
+			if !ok {
+				// The queue is only closed without an error chunk if the reader has been closed.
+				if p.err == nil {
+					p.err = io.ErrClosedPipe
+				}
+				return 0, p.err
+			}
+			p.pending, p.err = chunk.data, chunk.err
+		case <-p.done:
+			return 0, io.ErrClosedPipe
+		}
+	}
+
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+func (p *prefetchReader) Close() error {
+	p.once.Do(func() {
+		close(p.done)
+	})
+	return p.source.Close()
+}
+
+// PrefetchReadableStream creates a ReadableStream that yields the same bytes as r, but reads r ahead of time in a
+// background goroutine, keeping up to bufferChunks chunks queued, so that a Read can usually be served from the queue
+// straight away instead of waiting on a JavaScript Promise. This hides the latency of each read from a slow source, such
+// as one fetched over the network, as long as the consumer has other work to do between reads. Once the queue is full,
+// reading ahead stops until there is room again. If bufferChunks is less than 1, a single chunk is read ahead. Reading
+// ahead starts straight away, and closing the returned stream stops it and closes r. A Read waiting on the queue when the
+// stream is closed returns io.ErrClosedPipe, as does every Read after it.
+func PrefetchReadableStream(r *ReadableStream, bufferChunks int) *ReadableStream {
+	if bufferChunks < 1 {
+		bufferChunks = 1
+	}
+
+	prefetch := &prefetchReader{
+		source: r,
+		chunks: make(chan prefetchedChunk, bufferChunks),
+		done:   make(chan struct{}),
+	}
+	go prefetch.prefetch()
+	return newGoReadableStream(prefetch)
+}
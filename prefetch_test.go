@@ -0,0 +1,137 @@
+package jsStreams
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowReader yields chunks of data, waiting for delay before each one.
+type slowReader struct {
+	chunks []string
+	delay  time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	if len(s.chunks) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(s.delay)
+	n := copy(p, s.chunks[0])
+	s.chunks = s.chunks[1:]
+	return n, nil
+}
+
+func TestPrefetchReadableStream(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	chunks := []string{"Hello", ", ", "world", "!"}
+	stream := PrefetchReadableStream(newGoReadableStream(io.NopCloser(&slowReader{chunks: chunks, delay: delay})), len(chunks))
+
+	// Give the source time to be read ahead in full, after which reading it shouldn't have to wait on it at all.
+	time.Sleep(time.Duration(len(chunks)+2) * delay)
+	start := time.Now()
+	data, err := io.ReadAll(stream)
+	if err != nil || string(data) != strings.Join(chunks, "") {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, strings.Join(chunks, ""))
+	}
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Fatalf("ReadAll took %v, want less than the %v a single read of the source takes", elapsed, delay)
+	}
+}
+
+func TestPrefetchReadableStreamBounded(t *testing.T) {
+	source := &countingReader{}
+	stream := PrefetchReadableStream(newGoReadableStream(io.NopCloser(source)), 2)
+
+	time.Sleep(50 * time.Millisecond)
+	// Two chunks are queued, and a third has been read and is waiting for room, but nothing more.
+	if reads := source.reads.Load(); reads > 4 {
+		t.Fatalf("source was read %d times with nothing consuming it, want no more than 4", reads)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+}
+
+// countingReader yields zeros forever, counting how many times it has been read from.
+type countingReader struct {
+	reads atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads.Add(1)
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// closeBlockingReader blocks every Read until it is closed, after which reads fail with io.ErrClosedPipe.
+type closeBlockingReader struct {
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newCloseBlockingReader() *closeBlockingReader {
+	return &closeBlockingReader{closed: make(chan struct{})}
+}
+
+func (c *closeBlockingReader) Read(p []byte) (int, error) {
+	<-c.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (c *closeBlockingReader) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+func TestPrefetchReadableStreamCloseUnblocksRead(t *testing.T) {
+	stream := PrefetchReadableStream(newGoReadableStream(newCloseBlockingReader()), 1)
+
+	// The source never produces anything, so the Read waits on the empty queue until the stream is closed.
+	read := make(chan error, 1)
+	go func() {
+		_, err := stream.Read(make([]byte, 16))
+		read <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- stream.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked behind the waiting Read")
+	}
+	select {
+	case err := <-read:
+		if err == nil {
+			t.Fatal("Read returned no error once the stream was closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read wasn't unblocked by Close")
+	}
+}
+
+func TestPrefetchReaderClosedQueue(t *testing.T) {
+	// If Close stops prefetching before an error is queued, the queue is closed with no error in it, which a Read
+	// waiting on it may see before it sees the reader closed.
+	prefetch := &prefetchReader{chunks: make(chan prefetchedChunk), done: make(chan struct{})}
+	close(prefetch.chunks)
+	for i := 0; i < 2; i++ {
+		if n, err := prefetch.Read(make([]byte, 16)); n != 0 || err != io.ErrClosedPipe {
+			t.Fatalf("Read %d of a closed queue returned %d, %v, want 0, %v", i+1, n, err, io.ErrClosedPipe)
+		}
+	}
+}
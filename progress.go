@@ -0,0 +1,66 @@
+package jsStreams
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultProgressInterval is how often WithProgress reports progress, unless told otherwise.
+const defaultProgressInterval = 100 * time.Millisecond
+
+// progressReader reports how much of a stream has been read, at most once per interval.
+type progressReader struct {
+	source   *ReadableStream
+	total    int64
+	callback func(done, total int64)
+	interval time.Duration
+	done     int64
+	last     time.Time
+	finished bool
+	lock     sync.Mutex
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	n, err := p.source.Read(b)
+	p.done += int64(n)
+
+	if err != nil {
+		// The final count is always reported, however recently the last one was.
+		if !p.finished {
+			p.finished = true
+			p.callback(p.done, p.total)
+		}
+	} else if n > 0 && time.Since(p.last) >= p.interval {
+		p.last = time.Now()
+		p.callback(p.done, p.total)
+	}
+
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	return p.source.Close()
+}
+
+// WithProgress creates a ReadableStream that yields the same bytes as r, calling cb with the number of bytes read so far
+// and total as it is read, for showing the progress of a download or upload. total is passed through as is, so it can be
+// negative if the size isn't known. Calls are throttled so that cb is called at most once per interval, which defaults to
+// 100 milliseconds, however many reads happen in between, except that cb is always called once the stream ends or fails,
+// with the final count. cb is called from whichever goroutine is reading, and must not block for long. Closing the
+// returned stream closes r.
+func WithProgress(r *ReadableStream, total int64, cb func(done, total int64), interval ...time.Duration) *ReadableStream {
+	reader := &progressReader{
+		source:   r,
+		total:    total,
+		callback: cb,
+		interval: defaultProgressInterval,
+		last:     time.Now(),
+	}
+	if len(interval) > 0 {
+		reader.interval = interval[0]
+	}
+	return newGoReadableStream(reader)
+}
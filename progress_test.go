@@ -0,0 +1,51 @@
+package jsStreams
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWithProgress(t *testing.T) {
+	const data = "Hello, world!"
+
+	var reports []int64
+	stream := WithProgress(newStringStream(data), int64(len(data)), func(done, total int64) {
+		if total != int64(len(data)) {
+			t.Errorf("cb was called with a total of %d, want %d", total, len(data))
+		}
+		reports = append(reports, done)
+	}, 0)
+
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+
+	if len(reports) < 2 {
+		t.Fatalf("cb was called %d times, want one per read", len(reports))
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i] < reports[i-1] {
+			t.Fatalf("cb was called with %v, which is not increasing", reports)
+		}
+	}
+	if last := reports[len(reports)-1]; last != int64(len(data)) {
+		t.Fatalf("cb was last called with %d, want %d", last, len(data))
+	}
+}
+
+func TestWithProgressThrottled(t *testing.T) {
+	var reports []int64
+	stream := WithProgress(newStringStream("Hello, world!"), -1, func(done, total int64) {
+		reports = append(reports, done)
+	}, time.Hour)
+
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+
+	// Every read happens within the interval, so only the final count gets through.
+	if len(reports) != 1 || reports[0] != int64(len("Hello, world!")) {
+		t.Fatalf("cb was called with %v, want only %d", reports, len("Hello, world!"))
+	}
+}
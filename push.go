@@ -0,0 +1,133 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// pushHighWaterMark is how many bytes a stream created by NewPushStream queues before Enqueue blocks.
+const pushHighWaterMark = 4 * defaultChunkSize
+
+// PushController pushes data into a stream created by NewPushStream. Its methods are safe to call from any goroutine.
+type PushController struct {
+	controller js.Value
+	lock       sync.Mutex
+	// done is set once the stream has been closed or errored, by us or by JavaScript cancelling it.
+	done atomic.Bool
+
+	// ready is closed, and replaced, whenever the stream wants more data or finishes, to wake up blocked calls to
+	// Enqueue. It has a lock of its own, which is never held while calling into JavaScript, so that the pull and cancel
+	// callbacks can't deadlock with a call to Enqueue that is holding lock.
+	readyLock sync.Mutex
+	ready     chan struct{}
+}
+
+// NewPushStream creates a ReadableStream that yields whatever is pushed into it through the returned PushController,
+// for Go code that produces data imperatively, such as from an event handler, rather than from an io.Reader. The stream
+// can be read from Go, or handed to JavaScript with JSValue. Chunks are queued until they are read, but once 128KiB is
+// waiting, Enqueue blocks until the consumer catches up, so a fast producer can't run away with memory.
+func NewPushStream() (*ReadableStream, *PushController) {
+	push := &PushController{ready: make(chan struct{})}
+	stream := js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			push.controller = args[0]
+			return nil
+		}),
+		"pull": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			push.wake()
+			return nil
+		}),
+		"cancel": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			push.done.Store(true)
+			push.wake()
+			return nil
+		}),
+	}, js.Global().Get("ByteLengthQueuingStrategy").New(map[string]interface{}{"highWaterMark": pushHighWaterMark}))
+	return NewReadableStream(stream), push
+}
+
+// wake wakes up every call to Enqueue waiting for the stream to want more data.
+func (p *PushController) wake() {
+	p.readyLock.Lock()
+	defer p.readyLock.Unlock()
+
+	close(p.ready)
+	p.ready = make(chan struct{})
+}
+
+// Enqueue pushes a copy of chunk into the stream, so chunk can be reused once Enqueue returns. Empty chunks are skipped.
+// If the stream already has as much queued as it wants, Enqueue blocks until the consumer has read enough of it, so a
+// slow consumer holds back the producer. Enqueue may be called from several goroutines at once; each chunk is pushed
+// whole, and the chunks from any one goroutine are read in the order it pushed them. Once the stream has been closed,
+// errored or cancelled, Enqueue returns io.ErrClosedPipe, including to a call that was blocked.
+func (p *PushController) Enqueue(chunk []byte) error {
+	for {
+		// Take the channel before checking the stream, so that a wake in between isn't missed.
+		p.readyLock.Lock()
+		ready := p.ready
+		p.readyLock.Unlock()
+
+		var queued bool
+		err := p.call(func() {
+			if len(chunk) > 0 && p.controller.Get("desiredSize").Float() <= 0 {
+				return
+			}
+			queued = true
+			if len(chunk) == 0 {
+				return
+			}
+			buffer := js.Global().Get("Uint8Array").New(len(chunk))
+			js.CopyBytesToJS(buffer, chunk)
+			p.controller.Call("enqueue", buffer)
+		}, false)
+		if err != nil || queued {
+			return err
+		}
+
+		<-ready
+	}
+}
+
+// Close closes the stream, which ends once everything pushed into it has been read. Once the stream has been closed,
+// errored or cancelled, Close returns io.ErrClosedPipe.
+func (p *PushController) Close() error {
+	return p.call(func() {
+		p.controller.Call("close")
+	}, true)
+}
+
+// Error errors the stream with err, discarding anything pushed into it that hasn't been read yet, so that the next read
+// fails with err. Once the stream has been closed, errored or cancelled, Error returns io.ErrClosedPipe.
+func (p *PushController) Error(err error) error {
+	return p.call(func() {
+		p.controller.Call("error", goErrorToJS(err))
+	}, true)
+}
+
+// call runs f on the controller, unless the stream is already done, and marks the stream as done if finish is set.
+func (p *PushController) call(f func(), finish bool) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.done.Load() {
+		return io.ErrClosedPipe
+	}
+	if finish {
+		p.done.Store(true)
+		defer p.wake()
+	}
+	f()
+	return nil
+}
@@ -0,0 +1,177 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestNewPushStream(t *testing.T) {
+	stream, push := NewPushStream()
+	for _, chunk := range []string{"Hello", ", ", "", "world!"} {
+		if err := push.Enqueue([]byte(chunk)); err != nil {
+			t.Fatalf("Enqueue returned error: %v", err)
+		}
+	}
+	if err := push.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if err := push.Enqueue([]byte("late")); err != io.ErrClosedPipe {
+		t.Fatalf("Enqueue after Close returned %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	// The chunks are read from the JavaScript side as they were pushed.
+	reader := stream.JSValue().Call("getReader")
+	var chunks []string
+	for {
+		result, err := await(reader.Call("read"))
+		if err != nil {
+			t.Fatalf("read returned error: %v", err)
+		}
+		if result.Get("done").Bool() {
+			break
+		}
+		chunk := make([]byte, result.Get("value").Length())
+		js.CopyBytesToGo(chunk, result.Get("value"))
+		chunks = append(chunks, string(chunk))
+	}
+	if len(chunks) != 3 || chunks[0] != "Hello" || chunks[1] != ", " || chunks[2] != "world!" {
+		t.Fatalf("read %q, want %q", chunks, []string{"Hello", ", ", "world!"})
+	}
+}
+
+func TestNewPushStreamError(t *testing.T) {
+	stream, push := NewPushStream()
+	pushErr := errors.New("producer failed")
+	if err := push.Enqueue([]byte("Hello")); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+	if err := push.Error(pushErr); err != nil {
+		t.Fatalf("Error returned error: %v", err)
+	}
+
+	if _, err := io.ReadAll(stream); err == nil || err.Error() != pushErr.Error() {
+		t.Fatalf("ReadAll returned %v, want %v", err, pushErr)
+	}
+}
+
+func TestNewPushStreamCancel(t *testing.T) {
+	stream, push := NewPushStream()
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if err := push.Enqueue([]byte("Hello")); err != io.ErrClosedPipe {
+		t.Fatalf("Enqueue after the stream was cancelled returned %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestNewPushStreamConcurrentEnqueue(t *testing.T) {
+	const producers, chunks = 4, 64
+	stream, push := NewPushStream()
+
+	var wg sync.WaitGroup
+	for producer := 0; producer < producers; producer++ {
+		wg.Add(1)
+		go func(producer int) {
+			defer wg.Done()
+			// Each chunk is big enough that the producers fill the queue and have to wait for the consumer.
+			for i := 0; i < chunks; i++ {
+				chunk := append([]byte(fmt.Sprintf("%d:%d;", producer, i)), bytes.Repeat([]byte{'.'}, 4*1024)...)
+				if err := push.Enqueue(chunk); err != nil {
+					t.Errorf("Enqueue returned error: %v", err)
+					return
+				}
+			}
+		}(producer)
+	}
+	go func() {
+		wg.Wait()
+		push.Close()
+	}()
+
+	// Every chunk arrives whole, and each producer's chunks arrive in the order it pushed them.
+	reader := stream.JSValue().Call("getReader")
+	next := make([]int, producers)
+	for {
+		result, err := await(reader.Call("read"))
+		if err != nil {
+			t.Fatalf("read returned error: %v", err)
+		}
+		if result.Get("done").Bool() {
+			break
+		}
+		chunk := make([]byte, result.Get("value").Length())
+		js.CopyBytesToGo(chunk, result.Get("value"))
+
+		var producer, i int
+		if _, err := fmt.Sscanf(string(chunk), "%d:%d;", &producer, &i); err != nil {
+			t.Fatalf("read a malformed chunk: %v", err)
+		}
+		if i != next[producer] || len(chunk) != len(fmt.Sprintf("%d:%d;", producer, i))+4*1024 {
+			t.Fatalf("read chunk %d of producer %d, want chunk %d", i, producer, next[producer])
+		}
+		next[producer]++
+	}
+	for producer, n := range next {
+		if n != chunks {
+			t.Fatalf("read %d chunks from producer %d, want %d", n, producer, chunks)
+		}
+	}
+}
+
+func TestNewPushStreamBackpressure(t *testing.T) {
+	stream, push := NewPushStream()
+	if err := push.Enqueue(make([]byte, pushHighWaterMark)); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+
+	// The queue is full, so the next Enqueue waits for the consumer.
+	enqueued := make(chan error, 1)
+	go func() {
+		enqueued <- push.Enqueue([]byte("Hello"))
+	}()
+	select {
+	case err := <-enqueued:
+		t.Fatalf("Enqueue into a full stream returned %v without waiting", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := stream.Read(make([]byte, pushHighWaterMark)); err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	select {
+	case err := <-enqueued:
+		if err != nil {
+			t.Fatalf("Enqueue returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Enqueue did not return once the consumer had read the queue")
+	}
+
+	// Cancelling the stream releases a blocked Enqueue.
+	if err := push.Enqueue(make([]byte, pushHighWaterMark)); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+	go func() {
+		enqueued <- push.Enqueue([]byte("Hello"))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	select {
+	case err := <-enqueued:
+		if err != io.ErrClosedPipe {
+			t.Fatalf("blocked Enqueue returned %v once the stream was cancelled, want %v", err, io.ErrClosedPipe)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked Enqueue did not return once the stream was cancelled")
+	}
+}
@@ -0,0 +1,115 @@
+package jsStreams
+
+import (
+	"sync"
+	"time"
+)
+
+// rateBuckets and rateBucketWidth make up the window Rate is measured over, of 10 buckets of 100ms, so a one-second
+// window that moves on in steps of 100ms.
+const (
+	rateBuckets     = 10
+	rateBucketWidth = 100 * time.Millisecond
+)
+
+// rateMeter keeps the number of bytes transferred in each of the most recent buckets of time, to measure throughput over a
+// sliding window. Recording is a lock and an addition, so it is cheap enough to do on every read or write. The zero value
+// is ready to use.
+type rateMeter struct {
+	lock sync.Mutex
+	// buckets holds the bytes counted in each bucket, and slots the bucket of time, counted from the Unix epoch, that each
+	// of them is counting, so that a bucket left over from a previous lap of the ring is recognised as stale.
+	buckets [rateBuckets]int64
+	slots   [rateBuckets]int64
+	// first is when the first bytes were recorded, so that a stream younger than the window isn't measured over all of
+	// it.
+	first time.Time
+}
+
+// record counts n bytes as transferred now. n may be negative, to take back bytes counted earlier.
+func (m *rateMeter) record(n int64) {
+	if n == 0 {
+		return
+	}
+	now := time.Now()
+	slot := now.UnixNano() / int64(rateBucketWidth)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.first.IsZero() {
+		m.first = now
+	}
+	i := slot % rateBuckets
+	if m.slots[i] != slot {
+		m.slots[i] = slot
+		m.buckets[i] = 0
+	}
+	m.buckets[i] += n
+}
+
+// rate returns the bytes transferred per second over the window.
+func (m *rateMeter) rate() float64 {
+	now := time.Now()
+	slot := now.UnixNano() / int64(rateBucketWidth)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.first.IsZero() {
+		return 0
+	}
+
+	var total int64
+	for i, bucket := range m.buckets {
+		if m.slots[i] > slot-rateBuckets {
+			total += bucket
+		}
+	}
+
+	// The current bucket has only been filling since it started, and the stream may not have been going for the whole
+	// window.
+	window := time.Duration(rateBuckets-1)*rateBucketWidth + time.Duration(now.UnixNano()-slot*int64(rateBucketWidth))
+	if elapsed := now.Sub(m.first); elapsed < window {
+		window = elapsed
+	}
+	if window <= 0 {
+		return 0
+	}
+	return float64(total) / window.Seconds()
+}
+
+// reset forgets everything recorded.
+func (m *rateMeter) reset() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.buckets = [rateBuckets]int64{}
+	m.slots = [rateBuckets]int64{}
+	m.first = time.Time{}
+}
+
+// addBytesRead counts n bytes as read, for BytesRead and Rate.
+func (r *ReadableStream) addBytesRead(n int64) {
+	r.bytesRead.Add(n)
+	r.readRate.record(n)
+}
+
+// addBytesWritten counts n bytes as written, for BytesWritten and Rate.
+func (w *WritableStream) addBytesWritten(n int64) {
+	w.bytesWritten.Add(n)
+	w.writeRate.record(n)
+}
+
+// Rate returns how many bytes per second have been read from the stream over roughly the last second, for showing live
+// throughput, such as in a download progress bar. It is 0 before anything has been read, and falls back to 0 once
+// nothing has been read for a second. It is safe to call at any time, including while a read is in progress.
+func (r *ReadableStream) Rate() (bytesPerSec float64) {
+	return r.readRate.rate()
+}
+
+// Rate returns how many bytes per second have been written to the stream over roughly the last second, counting only
+// writes that succeeded, as Rate does for a ReadableStream.
+func (w *WritableStream) Rate() (bytesPerSec float64) {
+	return w.writeRate.rate()
+}
@@ -0,0 +1,53 @@
+package jsStreams
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// pacedReader yields a chunk of size bytes every interval, forever.
+type pacedReader struct {
+	size     int
+	interval time.Duration
+}
+
+func (p *pacedReader) Read(b []byte) (int, error) {
+	time.Sleep(p.interval)
+	return copy(b, make([]byte, min(p.size, len(b)))), nil
+}
+
+// checkRate fails the test unless rate is within 25% of want.
+func checkRate(t *testing.T, name string, rate, want float64) {
+	t.Helper()
+	if math.Abs(rate-want) > want/4 {
+		t.Fatalf("%s: Rate returned %.0f bytes/s, want about %.0f", name, rate, want)
+	}
+}
+
+func TestRate(t *testing.T) {
+	const size, interval = 500, 20 * time.Millisecond
+	const want = float64(size) / 0.02
+
+	stream := newGoReadableStream(io.NopCloser(&pacedReader{size, interval}))
+	if stream.Rate() != 0 {
+		t.Fatalf("Rate before reading returned %f, want 0", stream.Rate())
+	}
+	buffer := make([]byte, size)
+	for start := time.Now(); time.Since(start) < 1200*time.Millisecond; {
+		if _, err := io.ReadFull(stream, buffer); err != nil {
+			t.Fatalf("ReadFull returned error: %v", err)
+		}
+	}
+	checkRate(t, "ReadableStream", stream.Rate(), want)
+
+	sink := newGoWritableStream(&recordingSink{})
+	for start := time.Now(); time.Since(start) < 1200*time.Millisecond; {
+		time.Sleep(interval)
+		if _, err := sink.Write(buffer); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	checkRate(t, "WritableStream", sink.Rate(), want)
+}
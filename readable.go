@@ -0,0 +1,397 @@
+package jsStreams
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// defaultChunkSize is the size of the chunks read from a Go source when feeding a JavaScript ReadableStream, and of the
+// buffers used when reading from a stream in chunks.
+const defaultChunkSize = 32 * 1024
+
+// ReadFull reads exactly len(p) bytes into p, calling Read as many times as needed. It returns the number of bytes
+// copied and an error if fewer bytes were read. The error is io.EOF only if no bytes were read, and io.ErrUnexpectedEOF
+// if the stream ended after some, but not all, of the bytes were read. This mirrors io.ReadFull, and is useful because
+// Read returns whatever data is available rather than waiting for p to be filled.
+func (r *ReadableStream) ReadFull(p []byte) (int, error) {
+	return io.ReadFull(r, p)
+}
+
+// ReadContext reads up to len(p) bytes into p, like Read, but gives up once ctx is done, returning ctx's error. The read
+// itself carries on in the background, as a JavaScript read can't be taken back, but none of its data is lost: whatever
+// it returns after ReadContext has given up is kept for the next read. Until then, other reads wait for it to finish.
+func (r *ReadableStream) ReadContext(ctx context.Context, p []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	results := make(chan result, 1)
+	buffer := make([]byte, len(p))
+
+	// handoff decides whether the result is handed back to us, or put back into the stream because we gave up on it.
+	var handoff sync.Mutex
+	var abandoned bool
+
+	go func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		n, err := r.readHeld(buffer)
+
+		handoff.Lock()
+		defer handoff.Unlock()
+		if abandoned {
+			r.unread(buffer[:n])
+			return
+		}
+		results <- result{n, err}
+	}()
+
+	select {
+	case res := <-results:
+		return copy(p, buffer[:res.n]), res.err
+	case <-ctx.Done():
+		handoff.Lock()
+		defer handoff.Unlock()
+
+		select {
+		case res := <-results:
+			return copy(p, buffer[:res.n]), res.err
+		default:
+			abandoned = true
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// WaitReadable blocks until a Read of the stream would return straight away, because data is waiting or the stream has
+// ended, or until ctx is done, in which case it returns ctx's error, so that a stream can be fitted into a readiness loop.
+// Nothing is consumed: whatever WaitReadable had to read from the JavaScript stream to find out is kept for the next
+// Read, even if it arrives after WaitReadable has given up. The end of the stream isn't an error, but a failed read is,
+// and is returned.
+func (r *ReadableStream) WaitReadable(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		if len(r.leftover) > 0 {
+			ready <- nil
+			return
+		}
+		// A single chunk is enough to know, so the stream's FillMode isn't waited on.
+		buffer := make([]byte, defaultChunkSize)
+		n, err := r.readChunkHeld(buffer)
+		r.unread(buffer[:n])
+		if err == io.EOF {
+			err = nil
+		}
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ReadUntil reads from the stream until the first occurrence of delim, returning the data up to and including delim if
+// includeDelim is set, or up to but excluding it if not, for protocols whose messages end with a sentinel byte sequence.
+// The delimiter may be split across any number of chunks. Anything read past the delimiter is kept for the next read, so
+// no data is lost. If the stream ends before delim is found, ReadUntil returns the data read so far along with io.EOF,
+// like bufio.Reader.ReadBytes, and with any other error, whatever was read is returned with it.
+func (r *ReadableStream) ReadUntil(delim []byte, includeDelim bool) ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var data []byte
+	var searched int
+	buffer := make([]byte, defaultChunkSize)
+	for {
+		// A read may return data along with an error, such as io.EOF, so the data is searched before the error counts.
+		n, err := r.readHeld(buffer)
+		data = append(data, buffer[:n]...)
+		if i := bytes.Index(data[searched:], delim); i >= 0 {
+			end := searched + i + len(delim)
+			r.unread(data[end:])
+			if includeDelim {
+				return data[:end], nil
+			}
+			return data[:end-len(delim)], nil
+		}
+		if err != nil {
+			return data, err
+		}
+		// Next time, only the new data, and the end of the old that part of the delimiter could be hiding in, is searched.
+		searched = max(len(data)-len(delim)+1, 0)
+	}
+}
+
+// ReadInto fills the buffers in bufs, in order, each one completely, returning how many it filled, for reading fixed-size
+// records into buffers the caller recycles, so that a hot loop doesn't allocate any of its own. The buffers are filled
+// under a single lock, so no other read can take data from between them. If the stream ends cleanly between buffers,
+// ReadInto returns how many it filled along with io.EOF. If it ends part way through a buffer, the partial record is put
+// back into the stream, where the next Read finds it, and ReadInto returns the buffers before it along with
+// io.ErrUnexpectedEOF. With any other error, what was read into the buffer it happened in is put back in the same way.
+func (r *ReadableStream) ReadInto(bufs [][]byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, buf := range bufs {
+		var filled int
+		for filled < len(buf) {
+			n, err := r.readHeld(buf[filled:])
+			filled += n
+			if err == nil || (err == io.EOF && filled == len(buf)) {
+				continue
+			}
+
+			if filled == 0 {
+				return i, err
+			}
+			r.unread(buf[:filled])
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return i, err
+		}
+	}
+	return len(bufs), nil
+}
+
+// Split reads exactly n bytes from the stream, such as a header of known length, and returns them along with a new
+// ReadableStream that yields everything after them, such as the body, to be handed on elsewhere. Anything already read
+// past the first n bytes isn't lost, but is the first thing the new stream yields. The new stream takes over this one,
+// which must not be read from afterwards, and closing the new stream closes this one. If the stream ends or fails before
+// n bytes have been read, Split returns what it read along with io.ErrUnexpectedEOF, or io.EOF if it read nothing, or
+// the error it failed with, and no stream.
+func (r *ReadableStream) Split(n int64) ([]byte, *ReadableStream, error) {
+	head := make([]byte, max(n, 0))
+	read, err := r.ReadFull(head)
+	if err != nil {
+		return head[:read], nil, err
+	}
+	return head, newGoReadableStream(r), nil
+}
+
+// ReadAllContext reads the rest of the stream, like io.ReadAll, but gives up once ctx is done, returning what it has read
+// so far along with ctx's error. Reaching the end of the stream is not an error.
+func (r *ReadableStream) ReadAllContext(ctx context.Context) ([]byte, error) {
+	var data []byte
+	buffer := make([]byte, defaultChunkSize)
+	for {
+		n, err := r.ReadContext(ctx, buffer)
+		data = append(data, buffer[:n]...)
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return data, err
+		}
+	}
+}
+
+// MaxFrameSize is the largest payload ReadFrame accepts, and WriteFrame writes. The length prefix comes from the wire, so
+// without a limit, a single hostile prefix would make the reader allocate up to 4 GiB before any of the payload arrived.
+const MaxFrameSize = 16 << 20
+
+// ReadFrame reads a single length-prefixed frame from the stream, made up of a 4-byte big-endian length followed by that
+// many bytes of payload, and returns the payload. This turns a byte stream carrying a framed binary protocol into a
+// stream of messages, however the frames happen to be split across chunks. It returns io.EOF if the stream ended cleanly
+// between frames, and io.ErrUnexpectedEOF if it ended part way through one. A frame longer than MaxFrameSize is refused
+// as with ReadFrameLimit.
+func (r *ReadableStream) ReadFrame() ([]byte, error) {
+	return r.ReadFrameLimit(MaxFrameSize)
+}
+
+// ReadFrameLimit reads a single length-prefixed frame, like ReadFrame, but refuses one whose payload is longer than limit
+// bytes, returning an error wrapping ErrTooLarge without allocating anything for it. The payload of a refused frame is
+// left unread, so the stream is no longer at a frame boundary, and should be abandoned.
+func (r *ReadableStream) ReadFrameLimit(limit int64) ([]byte, error) {
+	var header [4]byte
+	_, err := r.ReadFull(header[:])
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(binary.BigEndian.Uint32(header[:]))
+	if size > limit {
+		return nil, fmt.Errorf("%w: frame of %d bytes", ErrTooLarge, size)
+	}
+	frame := make([]byte, size)
+	_, err = r.ReadFull(frame)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return frame, nil
+}
+
+// ErrTooLarge is returned by ReadAllLimit if the stream holds more data than the limit allows, and wrapped by the error
+// ReadFrame and ReadFrameLimit return for a frame longer than theirs.
+var ErrTooLarge = errors.New("stream is larger than the limit")
+
+// ReadAllLimit reads the rest of the stream, like io.ReadAll, but stops with ErrTooLarge as soon as it has read more than
+// limit bytes, rather than growing without bound. This protects against running out of memory when reading a stream from
+// an untrusted source. A stream of exactly limit bytes is read successfully. A negative limit is treated as zero.
+func (r *ReadableStream) ReadAllLimit(limit int64) ([]byte, error) {
+	limit = max(limit, 0)
+	if limit == math.MaxInt64 {
+		// Nothing can be larger, and limit+1 would overflow.
+		return io.ReadAll(r)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// Drain reads the rest of the stream and discards it, returning the number of bytes drained. This is useful to free up
+// the source of a stream, such as a connection, without caring about what's left in it. It reads the stream in chunks
+// into a single scratch buffer, so draining a large stream doesn't allocate per chunk. Reaching the end of the stream is
+// not an error.
+func (r *ReadableStream) Drain() (int64, error) {
+	buffer := make([]byte, defaultChunkSize)
+	var drained int64
+	for {
+		n, err := r.Read(buffer)
+		drained += int64(n)
+		if err == io.EOF {
+			return drained, nil
+		}
+		if err != nil {
+			return drained, err
+		}
+	}
+}
+
+// ForEach reads the rest of the stream, calling fn with each chunk as Read returns it, until the stream ends. If fn
+// returns an error, ForEach stops, closes the stream, which cancels a JavaScript stream so that its source can stop
+// producing data, and returns fn's error. The chunk fn is given is only valid until it returns, as the buffer behind it
+// is reused for the next chunk. Reaching the end of the stream is not an error.
+func (r *ReadableStream) ForEach(fn func([]byte) error) error {
+	buffer := make([]byte, defaultChunkSize)
+	for {
+		n, err := r.Read(buffer)
+		if n > 0 {
+			if fnErr := fn(buffer[:n]); fnErr != nil {
+				_ = r.Close()
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// StreamState is the state of a ReadableStream, as returned by State.
+type StreamState int
+
+const (
+	// StateReadable means the stream may still have data to read.
+	StateReadable StreamState = iota
+	// StateClosed means the stream has reached its end, or has been closed.
+	StateClosed
+	// StateErrored means a read from the stream failed because the stream itself has errored.
+	StateErrored
+)
+
+// String returns the name of the state, as used by the Streams specification.
+func (s StreamState) String() string {
+	switch s {
+	case StateReadable:
+		return "readable"
+	case StateClosed:
+		return "closed"
+	case StateErrored:
+		return "errored"
+	default:
+		return "unknown"
+	}
+}
+
+// State returns the state of the stream, for diagnostics. JavaScript streams don't expose their state directly, so it is
+// inferred from what reads made through this package, and Close, have observed, in the same way as WaitClosed. A stream
+// consumed or errored only by JavaScript code stays StateReadable.
+func (r *ReadableStream) State() StreamState {
+	finished, err := r.finished.finished()
+	switch {
+	case !finished:
+		return StateReadable
+	case err != nil:
+		return StateErrored
+	default:
+		return StateClosed
+	}
+}
+
+// WaitClosed blocks until the stream has finished, returning nil if it reached its end or was closed, or the error it
+// failed with. Only reads made through this package, and Close, are observed, so a stream that is consumed entirely by
+// JavaScript code is never seen to finish. WaitClosed doesn't hold a reader, so it doesn't interfere with other reads.
+func (r *ReadableStream) WaitClosed() error {
+	return r.finished.wait()
+}
+
+// Closed returns a channel that receives the result of WaitClosed once the stream has finished, and is then closed.
+func (r *ReadableStream) Closed() <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- r.finished.wait()
+		close(result)
+	}()
+	return result
+}
+
+// SetExpectedLength records n as the length of the whole stream, from its start, when it is known out of band, such as
+// from a Content-Length header, so that RemainingLength can tell how much is left, for instance to show progress. It
+// doesn't limit the stream in any way: reading past n, or reaching the end before it, is not an error.
+func (r *ReadableStream) SetExpectedLength(n int64) {
+	r.expectedLength.Store(&n)
+}
+
+// RemainingLength returns how many bytes are left to read, being the length given to SetExpectedLength less BytesRead,
+// or zero if more than that has been read. ok is false if no expected length has been set.
+func (r *ReadableStream) RemainingLength() (remaining int64, ok bool) {
+	expected := r.expectedLength.Load()
+	if expected == nil {
+		return 0, false
+	}
+	return max(*expected-r.BytesRead(), 0), true
+}
+
+// String describes the stream for debugging, such as "ReadableStream{locked:false, closed:false, bytesRead:1234}". It
+// never blocks, even while a read is in progress, so it is always safe to log a stream.
+func (r *ReadableStream) String() string {
+	return fmt.Sprintf("ReadableStream{locked:%v, closed:%v, bytesRead:%d}", r.Locked(), r.closed.Load(), r.BytesRead())
+}
@@ -0,0 +1,561 @@
+package jsStreams
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// newStringStream creates a ReadableStream that yields s, one byte per Read, to exercise short reads.
+func newStringStream(s string) *ReadableStream {
+	return newGoReadableStream(io.NopCloser(iotest.OneByteReader(strings.NewReader(s))))
+}
+
+func TestReadFull(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		size  int
+		want  string
+		err   error
+	}{
+		{"exact", "Hello", 5, "Hello", nil},
+		{"longer", "Hello, world!", 5, "Hello", nil},
+		{"short", "Hi", 5, "Hi", io.ErrUnexpectedEOF},
+		{"empty", "", 5, "", io.EOF},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := make([]byte, test.size)
+			n, err := newStringStream(test.input).ReadFull(buffer)
+			if err != test.err {
+				t.Fatalf("ReadFull returned error %v, want %v", err, test.err)
+			}
+			if string(buffer[:n]) != test.want {
+				t.Fatalf("ReadFull read %q, want %q", buffer[:n], test.want)
+			}
+		})
+	}
+}
+
+// frames encodes each of payloads as a length-prefixed frame.
+func frames(payloads ...string) string {
+	var data []byte
+	for _, payload := range payloads {
+		data = binary.BigEndian.AppendUint32(data, uint32(len(payload)))
+		data = append(data, payload...)
+	}
+	return string(data)
+}
+
+func TestReadFrame(t *testing.T) {
+	payloads := []string{"Hello", "", "world!"}
+	tests := []struct {
+		name   string
+		stream *ReadableStream
+	}{
+		{"one chunk", newChunkedStream(frames(payloads...))},
+		{"split", newStringStream(frames(payloads...))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, want := range payloads {
+				frame, err := test.stream.ReadFrame()
+				if err != nil {
+					t.Fatalf("ReadFrame returned error: %v", err)
+				}
+				if string(frame) != want {
+					t.Fatalf("ReadFrame returned %q, want %q", frame, want)
+				}
+			}
+			if _, err := test.stream.ReadFrame(); err != io.EOF {
+				t.Fatalf("final ReadFrame returned %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestReadFrameTruncated(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"header", frames("Hello")[:2]},
+		{"payload", frames("Hello")[:6]},
+		{"no payload", frames("Hello")[:4]},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := newStringStream(test.input).ReadFrame(); err != io.ErrUnexpectedEOF {
+				t.Fatalf("ReadFrame returned %v, want %v", err, io.ErrUnexpectedEOF)
+			}
+		})
+	}
+}
+
+func TestReadFrameTooLarge(t *testing.T) {
+	// A prefix claiming an enormous frame is refused without waiting for, or allocating, its payload.
+	if _, err := newStringStream("\xff\xff\xff\xffHello").ReadFrame(); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("ReadFrame returned %v, want %v", err, ErrTooLarge)
+	}
+
+	stream := newStringStream(frames("Hello", "Hello, world!"))
+	if frame, err := stream.ReadFrameLimit(5); err != nil || string(frame) != "Hello" {
+		t.Fatalf("ReadFrameLimit returned %q, %v, want %q, nil", frame, err, "Hello")
+	}
+	if _, err := stream.ReadFrameLimit(5); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("ReadFrameLimit of a longer frame returned %v, want %v", err, ErrTooLarge)
+	}
+}
+
+func TestReadByte(t *testing.T) {
+	values := []uint64{0, 1, 300, 1 << 40}
+	var data []byte
+	for _, value := range values {
+		data = binary.AppendUvarint(data, value)
+	}
+	data = append(data, "Hello"...)
+
+	for name, stream := range map[string]*ReadableStream{
+		"one chunk": newChunkedStream(string(data)),
+		"split":     newStringStream(string(data)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var _ io.ByteReader = stream
+			for _, want := range values {
+				value, err := binary.ReadUvarint(stream)
+				if err != nil {
+					t.Fatalf("ReadUvarint returned error: %v", err)
+				}
+				if value != want {
+					t.Fatalf("ReadUvarint returned %d, want %d", value, want)
+				}
+			}
+
+			// Whatever ReadByte read ahead is still returned by Read.
+			rest, err := io.ReadAll(stream)
+			if err != nil || string(rest) != "Hello" {
+				t.Fatalf("ReadAll returned %q, %v, want %q, nil", rest, err, "Hello")
+			}
+			if _, err := stream.ReadByte(); err != io.EOF {
+				t.Fatalf("ReadByte at the end returned %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestReadAllLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		limit int64
+		want  string
+		err   error
+	}{
+		{"under", "Hello", 8, "Hello", nil},
+		{"exact", "Hello", 5, "Hello", nil},
+		{"over", "Hello, world!", 5, "", ErrTooLarge},
+		{"empty", "", 0, "", nil},
+		{"no limit", "Hello", math.MaxInt64, "Hello", nil},
+		{"negative", "Hello", -1, "", ErrTooLarge},
+		{"negative and empty", "", -1, "", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := newStringStream(test.input).ReadAllLimit(test.limit)
+			if err != test.err {
+				t.Fatalf("ReadAllLimit returned error %v, want %v", err, test.err)
+			}
+			if string(data) != test.want {
+				t.Fatalf("ReadAllLimit returned %q, want %q", data, test.want)
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	stream := newStringStream("Hello, world!")
+
+	drained, err := stream.Drain()
+	if err != nil {
+		t.Fatalf("Drain returned error: %v", err)
+	}
+	if drained != int64(len("Hello, world!")) {
+		t.Fatalf("Drain returned %d, want %d", drained, len("Hello, world!"))
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	stream := newStringStream("Hello")
+	closed := stream.Closed()
+
+	select {
+	case err := <-closed:
+		t.Fatalf("Closed delivered %v before the stream finished", err)
+	default:
+	}
+
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if err := <-closed; err != nil {
+		t.Fatalf("Closed delivered %v, want nil", err)
+	}
+
+	failing := newGoReadableStream(io.NopCloser(iotest.ErrReader(io.ErrUnexpectedEOF)))
+	if _, err := io.ReadAll(failing); err == nil {
+		t.Fatal("ReadAll of a failing stream returned no error")
+	}
+	if err := failing.WaitClosed(); err == nil || err.Error() != io.ErrUnexpectedEOF.Error() {
+		t.Fatalf("WaitClosed returned %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestBytesRead(t *testing.T) {
+	stream := newStringStream("Hello, world!")
+	if _, err := stream.ReadByte(); err != nil {
+		t.Fatalf("ReadByte returned error: %v", err)
+	}
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if read := stream.BytesRead(); read != int64(len("Hello, world!")) {
+		t.Fatalf("BytesRead returned %d, want %d", read, len("Hello, world!"))
+	}
+}
+
+func TestRemainingLength(t *testing.T) {
+	stream := newChunkedStream("Hello, ", "world!")
+	if _, ok := stream.RemainingLength(); ok {
+		t.Fatal("RemainingLength reported a length before one was set")
+	}
+	stream.SetExpectedLength(int64(len("Hello, world!")))
+
+	want := int64(len("Hello, world!"))
+	buffer := make([]byte, 4)
+	for {
+		if remaining, ok := stream.RemainingLength(); remaining != want || !ok {
+			t.Fatalf("RemainingLength returned %d, %v, want %d, true", remaining, ok, want)
+		}
+		n, err := stream.Read(buffer)
+		want -= int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+	}
+	if remaining, ok := stream.RemainingLength(); remaining != 0 || !ok {
+		t.Fatalf("RemainingLength at EOF returned %d, %v, want 0, true", remaining, ok)
+	}
+}
+
+func TestState(t *testing.T) {
+	stream := newStringStream("Hello")
+	if state := stream.State(); state != StateReadable {
+		t.Fatalf("State returned %v before reading, want %v", state, StateReadable)
+	}
+
+	if _, err := stream.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if state := stream.State(); state != StateReadable {
+		t.Fatalf("State returned %v after a read, want %v", state, StateReadable)
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if state := stream.State(); state != StateClosed {
+		t.Fatalf("State returned %v after Close, want %v", state, StateClosed)
+	}
+
+	failing := newGoReadableStream(io.NopCloser(iotest.ErrReader(io.ErrUnexpectedEOF)))
+	if _, err := failing.Read(make([]byte, 1)); err == nil {
+		t.Fatal("Read of a failing stream returned no error")
+	}
+	if state := failing.State(); state != StateErrored {
+		t.Fatalf("State returned %v after an error, want %v", state, StateErrored)
+	}
+	if StateErrored.String() != "errored" {
+		t.Fatalf("String returned %q, want %q", StateErrored.String(), "errored")
+	}
+}
+
+// stallReader blocks forever on every Read, closing stalled when it is first read from.
+type stallReader struct {
+	stalled chan struct{}
+	once    sync.Once
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	s.once.Do(func() { close(s.stalled) })
+	select {}
+}
+
+// lateReader returns its data only after a delay.
+type lateReader struct {
+	data  string
+	delay time.Duration
+}
+
+func (l *lateReader) Read(p []byte) (int, error) {
+	if l.data == "" {
+		return 0, io.EOF
+	}
+	time.Sleep(l.delay)
+	n := copy(p, l.data)
+	l.data = l.data[n:]
+	return n, nil
+}
+
+func TestReadContext(t *testing.T) {
+	stream := newGoReadableStream(io.NopCloser(&lateReader{data: "Hello", delay: 200 * time.Millisecond}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if n, err := stream.ReadContext(ctx, make([]byte, 8)); n != 0 || err != context.DeadlineExceeded {
+		t.Fatalf("ReadContext returned %d, %v, want 0, %v", n, err, context.DeadlineExceeded)
+	}
+
+	// The data arriving after ReadContext gave up is still there for the next read.
+	data, err := io.ReadAll(stream)
+	if err != nil || string(data) != "Hello" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello")
+	}
+}
+
+func TestReadAllContext(t *testing.T) {
+	stall := &stallReader{stalled: make(chan struct{})}
+	stream := newGoReadableStream(io.NopCloser(io.MultiReader(strings.NewReader("Hello"), stall)))
+
+	// The source only stalls once the data before it has been read.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stall.stalled
+		cancel()
+	}()
+	data, err := stream.ReadAllContext(ctx)
+	if err != context.Canceled {
+		t.Fatalf("ReadAllContext returned error %v, want %v", err, context.Canceled)
+	}
+	if string(data) != "Hello" {
+		t.Fatalf("ReadAllContext returned %q, want %q", data, "Hello")
+	}
+}
+
+func TestReadableStreamString(t *testing.T) {
+	stall := &stallReader{stalled: make(chan struct{})}
+	stream := newGoReadableStream(io.NopCloser(io.MultiReader(strings.NewReader("Hello"), stall)))
+	if _, err := stream.ReadFull(make([]byte, 5)); err != nil {
+		t.Fatalf("ReadFull returned error: %v", err)
+	}
+	if s := stream.String(); !strings.Contains(s, "closed:false") || !strings.Contains(s, "bytesRead:5") {
+		t.Fatalf("String returned %q, want it to report closed:false and bytesRead:5", s)
+	}
+
+	// String doesn't wait for a read in progress.
+	go stream.Read(make([]byte, 5))
+	<-stall.stalled
+	if s := fmt.Sprint(stream); !strings.HasPrefix(s, "ReadableStream{") {
+		t.Fatalf("fmt.Sprint returned %q, want it to use String", s)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	var chunks []string
+	err := newChunkedStream("Hello, ", "world!").ForEach(func(chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	})
+	if err != nil || strings.Join(chunks, "|") != "Hello, |world!" {
+		t.Fatalf("ForEach returned %v and saw %q, want nil and %q", err, chunks, []string{"Hello, ", "world!"})
+	}
+}
+
+func TestForEachStop(t *testing.T) {
+	source := &closeRecorder{Reader: &chunkReader{chunks: []string{"one", "two", "three"}}, closed: make(chan struct{})}
+	stream := newGoReadableStream(source)
+
+	stop := errors.New("seen enough")
+	var chunks []string
+	err := stream.ForEach(func(chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		if len(chunks) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("ForEach returned %v, want %v", err, stop)
+	}
+	if strings.Join(chunks, "|") != "one|two" {
+		t.Fatalf("ForEach saw %q, want %q", chunks, []string{"one", "two"})
+	}
+	select {
+	case <-source.closed:
+	case <-time.After(time.Second):
+		t.Fatal("ForEach didn't close the stream after stopping early")
+	}
+}
+
+func TestReadUntil(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		delim  string
+	}{
+		{"single byte", []string{"HELLO\nwor", "ld\n", "rest"}, "\n"},
+		{"multi byte", []string{"HELLO\r\n\r\nwor", "ld\r\n\r\n", "rest"}, "\r\n\r\n"},
+		{"split delimiter", []string{"HELLO\r", "\n\r", "\nworld\r\n", "\r\nrest"}, "\r\n\r\n"},
+	}
+	for _, test := range tests {
+		for _, include := range []bool{true, false} {
+			// The chunk reader consumes the chunks it is given, so each run needs its own copy.
+			stream := newChunkedStream(append([]string(nil), test.chunks...)...)
+
+			for _, want := range []string{"HELLO", "world"} {
+				if include {
+					want += test.delim
+				}
+				message, err := stream.ReadUntil([]byte(test.delim), include)
+				if err != nil || string(message) != want {
+					t.Fatalf("%s: ReadUntil returned %q, %v, want %q, nil", test.name, message, err, want)
+				}
+			}
+
+			// The data after the last delimiter is returned along with the end of the stream.
+			message, err := stream.ReadUntil([]byte(test.delim), include)
+			if err != io.EOF || string(message) != "rest" {
+				t.Fatalf("%s: ReadUntil returned %q, %v, want %q, %v", test.name, message, err, "rest", io.EOF)
+			}
+		}
+	}
+}
+
+func TestReadUntilLeftover(t *testing.T) {
+	// Whatever was read past the delimiter is returned by the next Read.
+	stream := newChunkedStream("key: value\nHello, world!")
+	if line, err := stream.ReadUntil([]byte("\n"), false); err != nil || string(line) != "key: value" {
+		t.Fatalf("ReadUntil returned %q, %v, want %q, nil", line, err, "key: value")
+	}
+	if data, err := io.ReadAll(stream); err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+	if stream.BytesRead() != int64(len("key: value\nHello, world!")) {
+		t.Fatalf("BytesRead returned %d, want %d", stream.BytesRead(), len("key: value\nHello, world!"))
+	}
+}
+
+func TestReadUntilDataWithEOF(t *testing.T) {
+	// The source returns its last data along with io.EOF, which must still be searched for the delimiter.
+	stream := newGoReadableStream(io.NopCloser(iotest.DataErrReader(strings.NewReader("key: value\nHello, world!"))))
+	if line, err := stream.ReadUntil([]byte("\n"), true); err != nil || string(line) != "key: value\n" {
+		t.Fatalf("ReadUntil returned %q, %v, want %q, nil", line, err, "key: value\n")
+	}
+	if rest, err := stream.ReadUntil([]byte("\n"), true); err != io.EOF || string(rest) != "Hello, world!" {
+		t.Fatalf("ReadUntil returned %q, %v, want %q, %v", rest, err, "Hello, world!", io.EOF)
+	}
+}
+
+func TestReadInto(t *testing.T) {
+	stream := newChunkedStream("Hel", "lo, wo", "rld!", "Hello, ", "wo")
+	records := [][]byte{make([]byte, 5), make([]byte, 5), make([]byte, 3)}
+
+	// The records are filled whatever the chunks look like.
+	if n, err := stream.ReadInto(records); n != 3 || err != nil {
+		t.Fatalf("ReadInto returned %d, %v, want 3, nil", n, err)
+	}
+	if string(records[0]) != "Hello" || string(records[1]) != ", wor" || string(records[2]) != "ld!" {
+		t.Fatalf("ReadInto filled %q, want %q", records, []string{"Hello", ", wor", "ld!"})
+	}
+
+	// The stream ends part way through the second buffer, whose partial record is left for the next Read.
+	if n, err := stream.ReadInto(records[:2]); n != 1 || err != io.ErrUnexpectedEOF {
+		t.Fatalf("ReadInto returned %d, %v, want 1, %v", n, err, io.ErrUnexpectedEOF)
+	}
+	if string(records[0]) != "Hello" {
+		t.Fatalf("ReadInto filled %q, want %q", records[0], "Hello")
+	}
+	if data, err := io.ReadAll(stream); err != nil || string(data) != ", wo" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, ", wo")
+	}
+
+	if n, err := stream.ReadInto(records); n != 0 || err != io.EOF {
+		t.Fatalf("ReadInto at the end of the stream returned %d, %v, want 0, %v", n, err, io.EOF)
+	}
+}
+
+func TestWaitReadable(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	stream := newGoReadableStream(pipeReader)
+
+	// Nothing has been written yet, so the wait runs out.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := stream.WaitReadable(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("WaitReadable returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		ready <- stream.WaitReadable(context.Background())
+	}()
+	go pipeWriter.Write([]byte("Hello"))
+	select {
+	case err := <-ready:
+		if err != nil {
+			t.Fatalf("WaitReadable returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitReadable did not return once data was written")
+	}
+
+	// Waiting didn't consume anything.
+	pipeWriter.Close()
+	if data, err := io.ReadAll(stream); err != nil || string(data) != "Hello" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello")
+	}
+	if err := stream.WaitReadable(context.Background()); err != nil {
+		t.Fatalf("WaitReadable at the end of the stream returned error: %v", err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	// The header ends part way through a chunk, so the rest of that chunk must go to the body.
+	content := "HEADER:Hello, world!"
+	header, body, err := newChunkedStream("HEA", "DER:Hel", "lo, ", "world!").Split(int64(len("HEADER:")))
+	if err != nil {
+		t.Fatalf("Split returned error: %v", err)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if string(header) != "HEADER:" || string(header)+string(data) != content {
+		t.Fatalf("Split returned %q and %q, want %q and %q", header, data, "HEADER:", "Hello, world!")
+	}
+
+	if header, body, err := newChunkedStream("HEAD").Split(7); err != io.ErrUnexpectedEOF || string(header) != "HEAD" ||
+		body != nil {
+		t.Fatalf("Split of a short stream returned %q, a stream %v, %v, want %q, no stream, %v", header, body != nil, err,
+			"HEAD", io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,512 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall/js"
+)
+
+// The modes a Reader can be acquired in.
+const (
+	// ReaderModeBYOB acquires a ReadableStreamBYOBReader, which reads directly into a buffer we provide. It is only
+	// supported by byte streams.
+	ReaderModeBYOB = "byob"
+	// ReaderModeDefault acquires a ReadableStreamDefaultReader, which is supported by every stream, but hands us chunks
+	// of whatever size the stream produces.
+	ReaderModeDefault = "default"
+)
+
+var (
+	// ErrReaderAcquired is returned by AcquireReader if a reader has already been acquired and not yet released.
+	ErrReaderAcquired = errors.New("a reader has already been acquired for this stream")
+	// ErrInvalidReaderMode is returned by AcquireReader if the mode is neither ReaderModeBYOB nor ReaderModeDefault.
+	ErrInvalidReaderMode = errors.New("reader mode must be \"byob\" or \"default\"")
+	// ErrReaderReleased is returned by a Reader's methods once its lock has been released.
+	ErrReaderReleased = errors.New("reader has been released")
+	// ErrInvalidChunk is returned by a read if the stream yields a chunk that is not an ArrayBuffer, a TypedArray or a
+	// DataView, such as a string, which can't be read as bytes.
+	ErrInvalidChunk = errors.New("stream yielded a chunk that is not an ArrayBuffer, TypedArray or DataView")
+	// ErrNotReader is returned by NewReadableStreamFromReader if the value is not a JavaScript reader.
+	ErrNotReader = errors.New("value must be a ReadableStreamDefaultReader or ReadableStreamBYOBReader")
+)
+
+// Reader is a JavaScript reader acquired for a ReadableStream, which holds the stream's lock until it is released. While
+// a Reader is held, the ReadableStream's own Read goes through it, so the two can be used interchangeably, and the
+// underlying JavaScript reader can be used by native JavaScript code in between.
+type Reader struct {
+	stream   *ReadableStream
+	reader   js.Value
+	mode     string
+	released bool
+	// pending is set while a read through the JavaScript reader is unsettled.
+	pending bool
+	// external is set for a reader passed to NewReadableStreamFromReader, whose lock isn't ours to release.
+	external bool
+}
+
+// NewReadableStreamFromReader creates a new ReadableStream from a JavaScript reader that has already been acquired, either
+// a ReadableStreamDefaultReader or a ReadableStreamBYOBReader, for when the stream itself isn't available. The kind of
+// reader is detected automatically, and every Read goes through it. The reader's lock is never released, as it belongs to
+// whoever acquired it, so AcquireReader always returns ErrReaderAcquired. If reader is not a reader, ErrNotReader is
+// returned.
+func NewReadableStreamFromReader(reader js.Value) (*ReadableStream, error) {
+	var mode string
+	switch {
+	case reader.Type() != js.TypeObject:
+		return nil, ErrNotReader
+	case isInstance(reader, "ReadableStreamBYOBReader"):
+		mode = ReaderModeBYOB
+	case isInstance(reader, "ReadableStreamDefaultReader"):
+		mode = ReaderModeDefault
+	default:
+		return nil, ErrNotReader
+	}
+
+	stream := &ReadableStream{stream: js.Undefined()}
+	stream.reader = &Reader{stream: stream, reader: reader, mode: mode, external: true}
+	return stream, nil
+}
+
+// isInstance reports whether value is an instance of the named global constructor, which may not exist at all.
+func isInstance(value js.Value, constructor string) bool {
+	global := js.Global().Get(constructor)
+	return global.Type() == js.TypeFunction && value.InstanceOf(global)
+}
+
+// AcquireReader acquires a reader for the ReadableStream in the given mode, either ReaderModeBYOB or ReaderModeDefault,
+// and holds it until ReleaseLock is called. Only one reader can be held at a time, so acquiring a second one returns
+// ErrReaderAcquired. If the stream is locked to a reader acquired by something else, ErrStreamLocked is returned.
+func (r *ReadableStream) AcquireReader(mode string) (reader *Reader, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed.Load() {
+		return nil, io.ErrClosedPipe
+	}
+	if r.reader != nil {
+		return nil, ErrReaderAcquired
+	}
+
+	reader, err = r.acquireReader(mode)
+	if err != nil {
+		return nil, err
+	}
+	r.reader = reader
+
+	return reader, nil
+}
+
+// acquireReader acquires a reader for the stream in the given mode, returning ErrStreamLocked if the stream is already
+// locked to another one. The caller must hold the stream's lock.
+func (r *ReadableStream) acquireReader(mode string) (*Reader, error) {
+	if r.stream.Get("locked").Bool() {
+		return nil, ErrStreamLocked
+	}
+
+	if mode != ReaderModeBYOB && mode != ReaderModeDefault {
+		return nil, ErrInvalidReaderMode
+	}
+	reader := getReader(r.stream, mode)
+
+	if Logger != nil {
+		Logger(EventReaderAcquired, map[string]interface{}{"stream": "readable", "mode": mode})
+	}
+
+	return &Reader{stream: r, reader: reader, mode: mode}, nil
+}
+
+// getReader calls stream's getReader method for a reader in the given mode, which must be a valid one. A default reader is
+// asked for with no arguments at all, rather than an options object without a mode, and a BYOB reader with an options
+// object holding nothing but the mode, as a strict implementation may throw on anything else.
+func getReader(stream js.Value, mode string) js.Value {
+	if mode == ReaderModeBYOB {
+		return stream.Call("getReader", map[string]interface{}{"mode": "byob"})
+	}
+	return stream.Call("getReader")
+}
+
+// Read reads up to len(p) bytes into p through the Reader, behaving exactly like the ReadableStream's Read.
+func (r *Reader) Read(p []byte) (n int, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	r.stream.lock.Lock()
+	defer r.stream.lock.Unlock()
+
+	if r.released {
+		return 0, ErrReaderReleased
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if n, ok := r.stream.readBuffered(p); ok {
+		return n, nil
+	}
+
+	return r.stream.fill(p, func(p []byte) (int, error) {
+		if len(r.stream.leftover) > 0 {
+			return r.stream.readLeftover(p), nil
+		}
+		return r.read(p)
+	})
+}
+
+// read reads a single chunk into p. The caller must hold the stream's lock, and must have already served any leftover
+// data from previous reads.
+func (r *Reader) read(p []byte) (n int, err error) {
+	if r.stream.ended {
+		r.stream.finished.finish(nil)
+		return 0, io.EOF
+	}
+
+	defer acquireOp()()
+
+	if r.mode == ReaderModeBYOB && r.stream.sharedBYOB {
+		// The shared buffer is only free again once we've finished copying out of it.
+		sharedScratch.lock.Lock()
+		defer sharedScratch.lock.Unlock()
+	}
+
+	var data js.Value
+	for {
+		var result js.Value
+		if r.mode == ReaderModeBYOB {
+			result, err = r.await(r.reader.Call("read", r.stream.byobView(len(p))))
+			if err == nil {
+				view := result.Get("value")
+				r.stream.reclaimBYOB(view)
+				if r.stream.releaseBuffer != nil && !r.stream.sharedBYOB && !view.IsUndefined() {
+					// The view is only handed back once we've finished copying out of it.
+					defer r.stream.releaseBuffer(view)
+				}
+			}
+		} else {
+			result, err = r.await(r.reader.Call("read"))
+		}
+		if err != nil {
+			// The read promise only rejects if the stream has errored, unless Close interrupted the read, in which case
+			// Close finishes the stream itself.
+			if err != io.ErrClosedPipe {
+				r.stream.finished.finish(err)
+			}
+			return 0, err
+		}
+
+		if result.Get("done").Bool() {
+			// Some implementations hand over the last of the data along with done, rather than in a result of its own,
+			// in which case it is returned as usual, and the end of the stream is only reported by the next read.
+			final, ok := toUint8Array(result.Get("value"))
+			if ok && final.Length() > 0 {
+				r.stream.ended = true
+				data = final
+				break
+			}
+
+			if Logger != nil {
+				Logger(EventEOF, map[string]interface{}{"stream": "readable"})
+			}
+			r.stream.finished.finish(nil)
+			return 0, io.EOF
+		}
+
+		// A BYOB read should resolve with a Uint8Array over the buffer we gave it, but a source may hand back some other
+		// view, such as a DataView, so it is normalised to a Uint8Array over the same bytes, whatever the view's offset
+		// into its buffer, the same as any chunk from a default reader.
+		var ok bool
+		data, ok = toUint8Array(result.Get("value"))
+		if !ok {
+			return 0, ErrInvalidChunk
+		}
+
+		// Streams other than byte streams are allowed to enqueue empty chunks, which don't mean the stream has ended, so
+		// we keep reading until there is some data to return, unless we've been told that they do.
+		if data.Length() > 0 {
+			break
+		}
+		if r.stream.emptyIsEOF {
+			if Logger != nil {
+				Logger(EventEOF, map[string]interface{}{"stream": "readable"})
+			}
+			return 0, io.EOF
+		}
+	}
+
+	n = data.Length()
+	if n > len(p) {
+		// Only a default reader can hand us more than we asked for, so we keep the rest for the next read.
+		r.stream.leftover = r.stream.borrowScratch(n - len(p))
+		js.CopyBytesToGo(r.stream.leftover, data.Call("subarray", len(p)))
+		n = len(p)
+	}
+	js.CopyBytesToGo(p[:n], data)
+
+	if Logger != nil {
+		Logger(EventChunkRead, map[string]interface{}{"stream": "readable", "size": n})
+	}
+
+	return n, nil
+}
+
+// await waits for a read promise to settle, marking the reader as having a read pending until it does, even if waiting
+// panics. If it hasn't settled within the stream's MaxReadWait, the reader is released, and ErrReadStalled returned.
+// The caller must hold the stream's lock.
+func (r *Reader) await(read js.Value) (result js.Value, err error) {
+	r.pending = true
+	defer func() {
+		r.pending = false
+	}()
+
+	// Close may cancel the reader while we wait, to interrupt the read, which it then reports as io.ErrClosedPipe.
+	if r.stream.setInflight(r.reader) {
+		return js.Undefined(), io.ErrClosedPipe
+	}
+	defer func() {
+		if r.stream.setInflight(js.Undefined()) {
+			result, err = js.Undefined(), io.ErrClosedPipe
+		}
+	}()
+
+	limit := r.stream.maxReadWait
+	if limit == 0 {
+		limit = DefaultMaxReadWait
+	}
+	if limit < 0 {
+		return await(read)
+	}
+
+	// The timer resolves a promise of its own with a marker no read can resolve with, and is cleared as soon as the read
+	// settles, so that it doesn't keep the JavaScript runtime alive.
+	stalled, resolve, _ := newPromise()
+	marker := js.Global().Get("Object").New()
+	timer := js.Global().Call("setTimeout", resolve, limit.Milliseconds(), marker)
+	defer js.Global().Call("clearTimeout", timer)
+
+	result, err = await(js.Global().Get("Promise").Call("race", []interface{}{read, stalled}))
+	if err != nil || !result.Equal(marker) {
+		return result, err
+	}
+
+	if Logger != nil {
+		Logger(EventError, map[string]interface{}{"stream": "readable", "error": ErrReadStalled})
+	}
+	r.abandon()
+	return js.Undefined(), ErrReadStalled
+}
+
+// abandon releases a reader whose read has stalled, which a broken source may make throw, as it can't be relied on to
+// settle the read when cancelled, in which case the reader is only marked as released.
+func (r *Reader) abandon() {
+	defer func() {
+		_ = recover()
+	}()
+
+	if r.stream.reader == r {
+		r.stream.reader = nil
+	}
+	r.releaseLock()
+}
+
+// uint8ArrayConstructor is the global Uint8Array constructor, looked up once rather than on every BYOB read.
+var uint8ArrayConstructor = js.Global().Get("Uint8Array")
+
+// sharedScratchSize is the size of sharedScratch.
+const sharedScratchSize = defaultChunkSize
+
+// sharedScratch is the buffer shared by the BYOB reads of every stream created with SharedBYOBBuffer, while it isn't in
+// the middle of a read, and lock is held for the whole of each of those reads.
+var sharedScratch struct {
+	lock   sync.Mutex
+	buffer js.Value
+}
+
+// byobView returns a view to make a BYOB read of up to size bytes into. If the stream uses the shared buffer, or keeps a
+// persistent buffer of its own, the view is over that buffer, which is handed over to the read until reclaimBYOB takes it
+// back, otherwise it comes from the stream's allocator, if it has one, or is over a new buffer. The caller must hold the
+// stream's lock, and the shared buffer's lock if the stream uses it.
+func (r *ReadableStream) byobView(size int) js.Value {
+	if r.sharedBYOB {
+		if sharedScratch.buffer.IsUndefined() {
+			sharedScratch.buffer = js.Global().Get("ArrayBuffer").New(sharedScratchSize)
+		}
+		view := uint8ArrayConstructor.New(sharedScratch.buffer, 0, min(size, sharedScratchSize))
+		sharedScratch.buffer = js.Undefined()
+		return view
+	}
+	if r.byobSize <= 0 {
+		if r.allocBuffer == nil {
+			return uint8ArrayConstructor.New(size)
+		}
+
+		view := r.allocBuffer(size)
+		if view.Get("byteLength").Int() > size {
+			view = view.Call("subarray", 0, size)
+		}
+		return view
+	}
+
+	if size > r.byobSize {
+		size = r.byobSize
+	}
+	if r.byobBuffer.IsUndefined() {
+		r.byobBuffer = js.Global().Get("ArrayBuffer").New(r.byobSize)
+	}
+	view := uint8ArrayConstructor.New(r.byobBuffer, 0, size)
+	r.byobBuffer = js.Undefined()
+	return view
+}
+
+// reclaimBYOB takes back the persistent buffer, if the stream keeps one, from the view a BYOB read resolved with. A BYOB
+// read transfers the buffer it is given, leaving the ArrayBuffer the view was made over detached, so the buffer can only
+// be reused through the view the read resolved with, and byobView always makes a new view over it. If the read didn't
+// hand a usable buffer back, because it resolved without a view or with one over a buffer that has since been detached or
+// isn't ours, the buffer is dropped and byobView allocates a new one. The caller must hold the stream's lock, and the
+// shared buffer's lock if the stream uses it.
+func (r *ReadableStream) reclaimBYOB(view js.Value) {
+	if r.sharedBYOB {
+		if !view.IsUndefined() && view.Get("buffer").Get("byteLength").Int() == sharedScratchSize {
+			sharedScratch.buffer = view.Get("buffer")
+		}
+		return
+	}
+	if r.byobSize <= 0 || view.IsUndefined() {
+		return
+	}
+
+	// A detached ArrayBuffer has a byteLength of 0, and throws if a view is made over it.
+	buffer := view.Get("buffer")
+	if buffer.Get("byteLength").Int() == r.byobSize {
+		r.byobBuffer = buffer
+	}
+}
+
+// readLeftover copies as much leftover data as fits into p, putting the scratch buffer holding it back into the pool once
+// it has all been read. The caller must hold the stream's lock.
+func (r *ReadableStream) readLeftover(p []byte) int {
+	n := copy(p, r.leftover)
+	r.leftover = r.leftover[n:]
+	if len(r.leftover) == 0 {
+		r.releaseScratch()
+	}
+	return n
+}
+
+// readBuffered serves a read straight from leftover data, without going anywhere near JavaScript, if there is enough of
+// it: any at all, or, if the stream's FillMode is FillComplete, enough to fill p. It reports whether it served the read.
+// p must not be empty, and the caller must hold the stream's lock.
+func (r *ReadableStream) readBuffered(p []byte) (int, bool) {
+	if len(r.leftover) == 0 || (r.fillMode == FillComplete && len(r.leftover) < len(p)) {
+		return 0, false
+	}
+
+	n := r.readLeftover(p)
+	r.addBytesRead(int64(n))
+	return n, true
+}
+
+// borrowScratch returns a buffer of the given size to keep leftover data in, taken from the stream's pool if it has one.
+// The caller must hold the stream's lock, and there must be no leftover data already.
+func (r *ReadableStream) borrowScratch(size int) []byte {
+	if r.scratch == nil {
+		return make([]byte, size)
+	}
+
+	buffer, _ := r.scratch.Get().(*[]byte)
+	if buffer == nil {
+		buffer = new([]byte)
+	}
+	if cap(*buffer) < size {
+		*buffer = make([]byte, size)
+	}
+	r.scratchBuffer = buffer
+	return (*buffer)[:size]
+}
+
+// releaseScratch puts the buffer backing the leftover data back into the stream's pool, discarding any data it still
+// holds. The caller must hold the stream's lock.
+func (r *ReadableStream) releaseScratch() {
+	r.leftover = nil
+	if r.scratchBuffer != nil {
+		r.scratch.Put(r.scratchBuffer)
+		r.scratchBuffer = nil
+	}
+}
+
+// ReleaseLock releases the Reader's lock on the stream, after which the ReadableStream can be read from normally, or
+// another reader acquired. Any data the Reader received but has not returned yet is kept for the next read.
+func (r *Reader) ReleaseLock() (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	r.stream.lock.Lock()
+	defer r.stream.lock.Unlock()
+
+	if r.released {
+		return ErrReaderReleased
+	}
+
+	r.releaseLock()
+	if r.stream.reader == r {
+		r.stream.reader = nil
+	}
+
+	return nil
+}
+
+// Cancel cancels the stream through the Reader with reason, which the stream's source is given, waiting for the source to
+// finish cancelling, then releases the Reader's lock, as releasing the lock and cancelling are separate steps of a
+// JavaScript reader's lifecycle. Any data the Reader received but hasn't returned yet is discarded, and the ReadableStream
+// is closed, just as by its Close. Cancelling a stream that has already errored is not an error. Once the lock has been
+// released, Cancel returns ErrReaderReleased.
+func (r *Reader) Cancel(reason string) (err error) {
+	defer func() {
+		if recoveryErr := closeRecovery(recover()); recoveryErr != nil {
+			err = recoveryErr
+		}
+	}()
+
+	r.stream.lock.Lock()
+	defer r.stream.lock.Unlock()
+
+	if r.released {
+		return ErrReaderReleased
+	}
+	return r.stream.cancelLocked(reason)
+}
+
+// releaseLock releases the underlying JavaScript reader. The caller must hold the stream's lock. Reads and releases are
+// both made under the stream's lock, so a read is never pending when the lock is released, but if one somehow were, the
+// reader is cancelled first, which settles the read, because releasing a reader with a read pending throws in older
+// implementations of the Streams specification, and leaves the read to fail with a TypeError in newer ones. Releasing a
+// reader that has already been released does nothing.
+func (r *Reader) releaseLock() {
+	if r.released {
+		return
+	}
+	r.released = true
+	if r.external {
+		return
+	}
+	if r.pending {
+		ignoreRejection(r.reader.Call("cancel"))
+	}
+	r.reader.Call("releaseLock")
+}
@@ -0,0 +1,928 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// newTestDefaultReadableStream creates a JavaScript ReadableStream that isn't a byte stream, and so only supports default
+// readers, which yields each of chunks in turn and then closes.
+func newTestDefaultReadableStream(chunks ...[]byte) js.Value {
+	return js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			for _, chunk := range chunks {
+				buffer := js.Global().Get("Uint8Array").New(len(chunk))
+				js.CopyBytesToJS(buffer, chunk)
+				args[0].Call("enqueue", buffer)
+			}
+			args[0].Call("close")
+			return nil
+		}),
+	})
+}
+
+func TestSupportsBYOB(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		stream js.Value
+		want   bool
+	}{
+		{"byte stream", newTestReadableStream([]byte("Hello, "), []byte("world!")), true},
+		{"default stream", newTestDefaultReadableStream([]byte("Hello, "), []byte("world!")), false},
+	} {
+		stream := NewReadableStream(test.stream)
+		if got := stream.SupportsBYOB(); got != test.want {
+			t.Fatalf("%s: SupportsBYOB returned %v, want %v", test.name, got, test.want)
+		}
+		if stream.Locked() {
+			t.Fatalf("%s: SupportsBYOB left the stream locked", test.name)
+		}
+		if got := stream.SupportsBYOB(); got != test.want {
+			t.Fatalf("%s: second SupportsBYOB returned %v, want %v", test.name, got, test.want)
+		}
+
+		// Probing doesn't consume anything, and Read picks a reader the stream supports.
+		if data, err := io.ReadAll(stream); err != nil || string(data) != "Hello, world!" {
+			t.Fatalf("%s: ReadAll returned %q, %v, want %q, nil", test.name, data, err, "Hello, world!")
+		}
+	}
+
+	// A stream locked by someone else can't be probed until it is unlocked.
+	jsStream := newTestReadableStream([]byte("Hello"))
+	reader := jsStream.Call("getReader")
+	stream := NewReadableStream(jsStream)
+	if stream.SupportsBYOB() {
+		t.Fatal("SupportsBYOB of a locked stream returned true")
+	}
+	reader.Call("releaseLock")
+	if !stream.SupportsBYOB() {
+		t.Fatal("SupportsBYOB once the stream was unlocked returned false")
+	}
+}
+
+func TestAcquireReader(t *testing.T) {
+	for _, mode := range []string{ReaderModeBYOB, ReaderModeDefault} {
+		t.Run(mode, func(t *testing.T) {
+			stream := NewReadableStream(newTestReadableStream([]byte("Hello, "), []byte("world!")))
+
+			reader, err := stream.AcquireReader(mode)
+			if err != nil {
+				t.Fatalf("AcquireReader returned error: %v", err)
+			}
+			if _, err := stream.AcquireReader(mode); err != ErrReaderAcquired {
+				t.Fatalf("second AcquireReader returned %v, want %v", err, ErrReaderAcquired)
+			}
+
+			// Reads through the reader and the stream itself can be interleaved while the reader is held.
+			buffer := make([]byte, 5)
+			if n, err := reader.Read(buffer); err != nil || string(buffer[:n]) != "Hello" {
+				t.Fatalf("Reader.Read returned %q, %v, want %q, nil", buffer[:n], err, "Hello")
+			}
+			if n, err := stream.Read(buffer[:2]); err != nil || string(buffer[:n]) != ", " {
+				t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, ", ")
+			}
+
+			if err := reader.ReleaseLock(); err != nil {
+				t.Fatalf("ReleaseLock returned error: %v", err)
+			}
+			if _, err := reader.Read(buffer); err != ErrReaderReleased {
+				t.Fatalf("Reader.Read after ReleaseLock returned %v, want %v", err, ErrReaderReleased)
+			}
+
+			if data, err := io.ReadAll(stream); err != nil || string(data) != "world!" {
+				t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "world!")
+			}
+		})
+	}
+}
+
+// newStrictReadableStream wraps stream in a mock that, like a strict implementation, throws unless getReader is called
+// with no arguments at all, or with exactly {mode: "byob"}, and records the arguments of every call.
+func newStrictReadableStream(stream js.Value) (mock js.Value, calls func() []string) {
+	mock = js.Global().Get("Function").New("stream", `
+		const calls = [];
+		return {
+			get locked() {
+				return stream.locked;
+			},
+			getReader(...args) {
+				calls.push(JSON.stringify(args));
+				if (args.length === 0) {
+					return stream.getReader();
+				}
+				if (args.length === 1 && JSON.stringify(args[0]) === '{"mode":"byob"}') {
+					return stream.getReader(args[0]);
+				}
+				throw new TypeError("invalid getReader arguments: " + JSON.stringify(args));
+			},
+			cancel(reason) {
+				return stream.cancel(reason);
+			},
+			calls,
+		};
+	`).Invoke(stream)
+	return mock, func() []string {
+		var recorded []string
+		for i := 0; i < mock.Get("calls").Length(); i++ {
+			recorded = append(recorded, mock.Get("calls").Index(i).String())
+		}
+		return recorded
+	}
+}
+
+func TestReadOffsetView(t *testing.T) {
+	// Whatever view the read resolves with, its bytes are found at its offset into its buffer, and there are as many as its
+	// byteLength, not its length, which for an Int16Array is half as many.
+	for _, view := range []string{"Uint8Array", "DataView", "Int16Array"} {
+		for _, mode := range []string{ReaderModeBYOB, ReaderModeDefault} {
+			mock := js.Global().Get("Function").New("view", `
+				const buffer = new Uint8Array([0, 0, 0, 0, 72, 101, 108, 108, 111, 33, 0, 0]).buffer;
+				let done = false;
+				return {
+					locked: false,
+					getReader() {
+						return {
+							closed: new Promise(() => {}),
+							read() {
+								if (done) {
+									return Promise.resolve({ done: true, value: undefined });
+								}
+								done = true;
+								return Promise.resolve({ done: false, value: new globalThis[view](buffer, 4, view === "Int16Array" ? 3 : 6) });
+							},
+							releaseLock() {},
+							cancel() { return Promise.resolve(); },
+						};
+					},
+					cancel() { return Promise.resolve(); },
+				};
+			`).Invoke(view)
+
+			reader, err := NewReadableStream(mock).AcquireReader(mode)
+			if err != nil {
+				t.Fatalf("%s, %s: AcquireReader returned error: %v", view, mode, err)
+			}
+			if data, err := io.ReadAll(reader); err != nil || string(data) != "Hello!" {
+				t.Fatalf("%s, %s: ReadAll returned %q, %v, want %q, nil", view, mode, data, err, "Hello!")
+			}
+		}
+	}
+}
+
+func TestReaderCancel(t *testing.T) {
+	state := js.Global().Get("Function").New(`
+		const state = { reason: null };
+		state.stream = new ReadableStream({
+			pull(controller) { controller.enqueue(new TextEncoder().encode("Hello, world!")); },
+			cancel(reason) { state.reason = reason; },
+		});
+		return state;
+	`).Invoke()
+	stream := NewReadableStream(state.Get("stream"))
+
+	reader, err := stream.AcquireReader(ReaderModeDefault)
+	if err != nil {
+		t.Fatalf("AcquireReader returned error: %v", err)
+	}
+	buffer := make([]byte, 5)
+	if n, err := reader.Read(buffer); err != nil || string(buffer[:n]) != "Hello" {
+		t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, "Hello")
+	}
+
+	// The source is given the reason, the lock is released, and the rest of the chunk is gone along with the stream.
+	if err := reader.Cancel("no longer needed"); err != nil {
+		t.Fatalf("Cancel returned error: %v", err)
+	}
+	if reason := state.Get("reason"); reason.Type() != js.TypeString || reason.String() != "no longer needed" {
+		t.Fatalf("source was cancelled with %v, want %q", reason, "no longer needed")
+	}
+	if stream.Locked() {
+		t.Fatal("stream is still locked after Cancel")
+	}
+	if _, err := stream.Read(buffer); err != io.ErrClosedPipe {
+		t.Fatalf("Read after Cancel returned %v, want %v", err, io.ErrClosedPipe)
+	}
+	if err := reader.Cancel("again"); err != ErrReaderReleased {
+		t.Fatalf("second Cancel returned %v, want %v", err, ErrReaderReleased)
+	}
+}
+
+func TestAcquireReaderArguments(t *testing.T) {
+	for _, test := range []struct {
+		mode  string
+		calls []string
+	}{
+		{ReaderModeBYOB, []string{`[{"mode":"byob"}]`}},
+		{ReaderModeDefault, []string{`[]`}},
+	} {
+		mock, calls := newStrictReadableStream(newTestReadableStream([]byte("Hello, world!")))
+		reader, err := NewReadableStream(mock).AcquireReader(test.mode)
+		if err != nil {
+			t.Fatalf("%s: AcquireReader returned error: %v", test.mode, err)
+		}
+		if data, err := io.ReadAll(reader); err != nil || string(data) != "Hello, world!" {
+			t.Fatalf("%s: ReadAll returned %q, %v, want %q, nil", test.mode, data, err, "Hello, world!")
+		}
+		if got := calls(); len(got) != len(test.calls) || got[0] != test.calls[0] {
+			t.Fatalf("%s: getReader was called with %v, want %v", test.mode, got, test.calls)
+		}
+	}
+
+	// Reading without acquiring a reader probes for BYOB support, then falls back to a default reader.
+	mock, calls := newStrictReadableStream(newTestDefaultReadableStream([]byte("Hello, world!")))
+	if data, err := io.ReadAll(NewReadableStream(mock)); err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+	if got := calls(); len(got) < 2 || got[0] != `[{"mode":"byob"}]` || got[1] != `[]` {
+		t.Fatalf("getReader was called with %v, want a BYOB probe, then no arguments", got)
+	}
+}
+
+func TestMaxReadWait(t *testing.T) {
+	// The mock stream's reads return a thenable that never settles, even once the reader is cancelled.
+	mock := js.Global().Get("Function").New(`
+		const calls = [];
+		return {
+			locked: false,
+			getReader(options) {
+				if (options !== undefined) {
+					throw new TypeError("not a byte stream");
+				}
+				this.locked = true;
+				return {
+					read() {
+						calls.push("read");
+						return { then() {} };
+					},
+					cancel() {
+						calls.push("cancel");
+						return { then() {} };
+					},
+					releaseLock: () => {
+						calls.push("releaseLock");
+						this.locked = false;
+					},
+				};
+			},
+			calls,
+		};
+	`).Invoke()
+	stream := NewReadableStreamWithOptions(mock, ReadableStreamOptions{MaxReadWait: 50 * time.Millisecond})
+
+	start := time.Now()
+	if _, err := stream.Read(make([]byte, 16)); err != ErrReadStalled {
+		t.Fatalf("Read returned %v, want %v", err, ErrReadStalled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Read took %v to give up, want about %v", elapsed, 50*time.Millisecond)
+	}
+
+	// The reader was cancelled and released, so the stream isn't left locked.
+	calls := mock.Get("calls")
+	if calls.Length() != 3 || calls.Index(1).String() != "cancel" || calls.Index(2).String() != "releaseLock" {
+		t.Fatalf("reader calls were %v, want read, cancel and releaseLock", js.Global().Get("JSON").Call("stringify", calls))
+	}
+	if stream.Locked() {
+		t.Fatal("stream is still locked after a stalled read")
+	}
+}
+
+func TestAcquireReaderDefaultLeftover(t *testing.T) {
+	stream := NewReadableStream(newTestDefaultReadableStream([]byte("Hello, world!")))
+
+	reader, err := stream.AcquireReader(ReaderModeDefault)
+	if err != nil {
+		t.Fatalf("AcquireReader returned error: %v", err)
+	}
+
+	// The whole chunk arrives at once, so what doesn't fit has to be kept for the following reads.
+	if buffered := stream.Buffered(); buffered != 0 {
+		t.Fatalf("Buffered returned %d before reading, want 0", buffered)
+	}
+	buffer := make([]byte, 5)
+	if n, err := reader.Read(buffer); err != nil || string(buffer[:n]) != "Hello" {
+		t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, "Hello")
+	}
+	if buffered := stream.Buffered(); buffered != len(", world!") {
+		t.Fatalf("Buffered returned %d, want %d", buffered, len(", world!"))
+	}
+
+	data, err := io.ReadAll(iotestHalfReader{reader})
+	if err != nil || string(data) != ", world!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, ", world!")
+	}
+	if buffered := stream.Buffered(); buffered != 0 {
+		t.Fatalf("Buffered returned %d after reading everything, want 0", buffered)
+	}
+}
+
+func TestScratchPool(t *testing.T) {
+	pool := &sync.Pool{}
+	stream := NewReadableStreamWithOptions(newTestDefaultReadableStream([]byte("Hello, "), []byte("world!")),
+		ReadableStreamOptions{ScratchPool: pool})
+
+	reader, err := stream.AcquireReader(ReaderModeDefault)
+	if err != nil {
+		t.Fatalf("AcquireReader returned error: %v", err)
+	}
+
+	buffer := make([]byte, 4)
+	var data []byte
+	for {
+		n, err := reader.Read(buffer)
+		data = append(data, buffer[:n]...)
+		if len(stream.leftover) == 0 && stream.scratchBuffer != nil {
+			t.Fatal("scratch buffer was retained after its data had been read")
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+	}
+	if string(data) != "Hello, world!" {
+		t.Fatalf("read %q, want %q", data, "Hello, world!")
+	}
+}
+
+// newTestFinalDataReader creates a default reader that hands over its last chunk along with done, as some implementations
+// of streams do, rather than in a result of its own.
+func newTestFinalDataReader(chunks ...string) js.Value {
+	reader := js.Global().Get("Object").Call("create", js.Global().Get("ReadableStreamDefaultReader").Get("prototype"))
+	reader.Set("read", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		result := map[string]interface{}{"done": len(chunks) <= 1}
+		if len(chunks) > 0 {
+			result["value"] = js.Global().Get("TextEncoder").New().Call("encode", chunks[0])
+			chunks = chunks[1:]
+		}
+		return js.Global().Get("Promise").Call("resolve", result)
+	}))
+	return reader
+}
+
+func TestReadFinalDataWithDone(t *testing.T) {
+	stream, err := NewReadableStreamFromReader(newTestFinalDataReader("Hello, ", "world!"))
+	if err != nil {
+		t.Fatalf("NewReadableStreamFromReader returned error: %v", err)
+	}
+
+	buffer := make([]byte, 16)
+	for _, want := range []string{"Hello, ", "world!"} {
+		n, err := stream.Read(buffer)
+		if err != nil || string(buffer[:n]) != want {
+			t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, want)
+		}
+	}
+	if n, err := stream.Read(buffer); n != 0 || err != io.EOF {
+		t.Fatalf("Read after the final data returned %d, %v, want 0, io.EOF", n, err)
+	}
+
+	// Final data that doesn't fit is kept for the following reads like any other, before the end of the stream.
+	stream, err = NewReadableStreamFromReader(newTestFinalDataReader("Hello, world!"))
+	if err != nil {
+		t.Fatalf("NewReadableStreamFromReader returned error: %v", err)
+	}
+	data, err := io.ReadAll(iotestHalfReader{stream})
+	if err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+}
+
+func TestNewReadableStreamFromReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader js.Value
+	}{
+		{"default", newTestDefaultReadableStream([]byte("Hello, "), []byte("world!")).Call("getReader")},
+		{"byob", newTestReadableStream([]byte("Hello, "), []byte("world!")).Call("getReader", map[string]interface{}{"mode": "byob"})},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream, err := NewReadableStreamFromReader(test.reader)
+			if err != nil {
+				t.Fatalf("NewReadableStreamFromReader returned error: %v", err)
+			}
+			if !stream.Locked() {
+				t.Fatal("Locked returned false for a stream created from a reader")
+			}
+			if _, err := stream.AcquireReader(ReaderModeDefault); err != ErrReaderAcquired {
+				t.Fatalf("AcquireReader returned %v, want %v", err, ErrReaderAcquired)
+			}
+
+			data, err := io.ReadAll(iotestHalfReader{stream})
+			if err != nil || string(data) != "Hello, world!" {
+				t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello, world!")
+			}
+			if err := stream.Close(); err != nil {
+				t.Fatalf("Close returned error: %v", err)
+			}
+		})
+	}
+
+	if _, err := NewReadableStreamFromReader(newTestReadableStream()); err != ErrNotReader {
+		t.Fatalf("NewReadableStreamFromReader of a stream returned %v, want %v", err, ErrNotReader)
+	}
+}
+
+func TestReadSharedArrayBuffer(t *testing.T) {
+	if js.Global().Get("SharedArrayBuffer").IsUndefined() {
+		t.Skip("SharedArrayBuffer is not available")
+	}
+
+	data := []byte("Hello, world!")
+	shared := js.Global().Get("Uint8Array").New(js.Global().Get("SharedArrayBuffer").New(len(data)))
+	js.CopyBytesToJS(shared, data)
+
+	t.Run("read", func(t *testing.T) {
+		stream := NewReadableStream(js.Global().Get("ReadableStream").New(map[string]interface{}{
+			"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				args[0].Call("enqueue", shared)
+				args[0].Call("close")
+				return nil
+			}),
+		}))
+		reader, err := stream.AcquireReader(ReaderModeDefault)
+		if err != nil {
+			t.Fatalf("AcquireReader returned error: %v", err)
+		}
+
+		read, err := io.ReadAll(iotestHalfReader{reader})
+		if err != nil || string(read) != string(data) {
+			t.Fatalf("ReadAll returned %q, %v, want %q, nil", read, err, data)
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer := WriterToWritableStream(&buffer).Call("getWriter")
+		if _, err := await(writer.Call("write", shared)); err != nil {
+			t.Fatalf("write returned error: %v", err)
+		}
+		if buffer.String() != string(data) {
+			t.Fatalf("writer received %q, want %q", buffer.String(), data)
+		}
+	})
+}
+
+// newBenchmarkReadableStream creates a JavaScript ReadableStream that only supports default readers, and yields chunks
+// of the given size for as long as it is read from.
+func newBenchmarkReadableStream(size int) js.Value {
+	return js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"pull": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			args[0].Call("enqueue", js.Global().Get("Uint8Array").New(size))
+			return nil
+		}),
+	})
+}
+
+// BenchmarkReadLeftover reads 16 KiB chunks into a 1 KiB buffer, so that every chunk leaves data behind. On Node.js,
+// using a ScratchPool took this from about 1 KiB allocated per Read, the leftover of each chunk spread over its 16 Reads,
+// to under 20 bytes.
+func BenchmarkReadLeftover(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		pool *sync.Pool
+	}{
+		{"allocate", nil},
+		{"pool", &sync.Pool{}},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			stream := NewReadableStreamWithOptions(newBenchmarkReadableStream(16*1024),
+				ReadableStreamOptions{ScratchPool: benchmark.pool})
+			reader, err := stream.AcquireReader(ReaderModeDefault)
+			if err != nil {
+				b.Fatalf("AcquireReader returned error: %v", err)
+			}
+
+			buffer := make([]byte, 1024)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(buffer)))
+			for i := 0; i < b.N; i++ {
+				if _, err := reader.Read(buffer); err != nil {
+					b.Fatalf("Read returned error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkReadBuffered reads a byte with ReadByte, which reads a whole 512-byte chunk ahead, then the rest of the chunk
+// with Read, which is served from what ReadByte left behind. It reports 1 await per op, that of ReadByte, showing that
+// the Read never waits on JavaScript.
+func BenchmarkReadBuffered(b *testing.B) {
+	counter := &countingAwaiter{}
+	SetAwaiter(counter)
+	defer SetAwaiter(nil)
+
+	stream := NewReadableStream(newBenchmarkReadableStream(byteReadAhead))
+	buffer := make([]byte, byteReadAhead-1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := stream.ReadByte(); err != nil {
+			b.Fatalf("ReadByte returned error: %v", err)
+		}
+		if n, err := stream.Read(buffer); err != nil || n != len(buffer) {
+			b.Fatalf("Read returned %d, %v, want %d, nil", n, err, len(buffer))
+		}
+	}
+	b.ReportMetric(float64(counter.calls)/float64(b.N), "awaits/op")
+}
+
+func TestReadEmptyChunk(t *testing.T) {
+	stream := NewReadableStream(newTestDefaultReadableStream([]byte{}, []byte("Hello"), []byte{}))
+
+	reader, err := stream.AcquireReader(ReaderModeDefault)
+	if err != nil {
+		t.Fatalf("AcquireReader returned error: %v", err)
+	}
+
+	// An empty chunk isn't the end of the stream, so Read skips over it to the data after it.
+	buffer := make([]byte, 8)
+	n, err := reader.Read(buffer)
+	if err != nil || string(buffer[:n]) != "Hello" {
+		t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, "Hello")
+	}
+	if n, err := reader.Read(buffer); n != 0 || err != io.EOF {
+		t.Fatalf("final Read returned %d, %v, want 0, %v", n, err, io.EOF)
+	}
+}
+
+func TestBYOBBufferSize(t *testing.T) {
+	stream := NewReadableStreamWithOptions(newTestReadableStream([]byte("Hello, "), []byte("world!")),
+		ReadableStreamOptions{BYOBBufferSize: 4})
+
+	buffer := make([]byte, 16)
+	var data []byte
+	for {
+		n, err := stream.Read(buffer)
+		if n > 4 {
+			t.Fatalf("Read returned %d bytes, want at most 4", n)
+		}
+		data = append(data, buffer[:n]...)
+
+		// The same memory is cycled through every read, so the buffer we get back always has the configured size.
+		if !stream.byobBuffer.IsUndefined() && stream.byobBuffer.Get("byteLength").Int() != 4 {
+			t.Fatalf("persistent buffer has %d bytes, want 4", stream.byobBuffer.Get("byteLength").Int())
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+	}
+	if string(data) != "Hello, world!" {
+		t.Fatalf("read %q, want %q", data, "Hello, world!")
+	}
+}
+
+func TestBYOBBufferReuse(t *testing.T) {
+	stream := NewReadableStreamWithOptions(newBenchmarkByteStream(), ReadableStreamOptions{BYOBBufferSize: 4})
+	reader, err := stream.AcquireReader(ReaderModeBYOB)
+	if err != nil {
+		t.Fatalf("AcquireReader returned error: %v", err)
+	}
+
+	buffer := make([]byte, 4)
+	var previous js.Value
+	for i := 0; i < 5; i++ {
+		if _, err := reader.Read(buffer); err != nil {
+			t.Fatalf("Read %d returned error: %v", i, err)
+		}
+
+		// Each read transfers the buffer, detaching the one the previous read handed back, and we carry on with the new one.
+		if !previous.IsUndefined() && previous.Get("byteLength").Int() != 0 {
+			t.Fatalf("after read %d, the previous buffer is still attached", i)
+		}
+		if stream.byobBuffer.IsUndefined() || stream.byobBuffer.Get("byteLength").Int() != 4 {
+			t.Fatalf("after read %d, the persistent buffer wasn't reclaimed", i)
+		}
+		previous = stream.byobBuffer
+	}
+
+	// A view over a detached buffer is never taken back.
+	detached := stream.byobBuffer
+	view := js.Global().Get("Uint8Array").New(detached)
+	js.Global().Get("structuredClone").Invoke(detached, map[string]interface{}{"transfer": []interface{}{detached}})
+	stream.byobBuffer = js.Undefined()
+	stream.reclaimBYOB(view)
+	if !stream.byobBuffer.IsUndefined() {
+		t.Fatal("reclaimBYOB took back a detached buffer")
+	}
+	if _, err := reader.Read(buffer); err != nil {
+		t.Fatalf("Read after dropping the persistent buffer returned error: %v", err)
+	}
+}
+
+func TestSharedBYOBBuffer(t *testing.T) {
+	chunks := func(prefix string) [][]byte {
+		var chunks [][]byte
+		for i := 0; i < 50; i++ {
+			chunks = append(chunks, []byte(fmt.Sprintf("%s%02d;", prefix, i)))
+		}
+		return chunks
+	}
+	want := func(prefix string) string {
+		return string(bytes.Join(chunks(prefix), nil))
+	}
+	options := ReadableStreamOptions{SharedBYOBBuffer: true}
+
+	// Reads from the two streams take turns, each going through the same buffer.
+	first := NewReadableStreamWithOptions(newTestReadableStream(chunks("a")...), options)
+	second := NewReadableStreamWithOptions(newTestReadableStream(chunks("b")...), options)
+	var firstData, secondData []byte
+	buffer := make([]byte, 64)
+	for len(firstData) < len(want("a")) || len(secondData) < len(want("b")) {
+		for _, read := range []struct {
+			stream *ReadableStream
+			data   *[]byte
+		}{{first, &firstData}, {second, &secondData}} {
+			n, err := read.stream.Read(buffer)
+			if err != nil && err != io.EOF {
+				t.Fatalf("Read returned error: %v", err)
+			}
+			*read.data = append(*read.data, buffer[:n]...)
+		}
+	}
+	if string(firstData) != want("a") || string(secondData) != want("b") {
+		t.Fatalf("read %q and %q, want %q and %q", firstData, secondData, want("a"), want("b"))
+	}
+	if sharedScratch.buffer.IsUndefined() || sharedScratch.buffer.Get("byteLength").Int() != sharedScratchSize {
+		t.Fatal("the shared buffer wasn't taken back after the reads")
+	}
+
+	// Reads from different goroutines are serialised, and neither stream sees the other's data.
+	results := make(chan string, 2)
+	for _, prefix := range []string{"c", "d"} {
+		stream := NewReadableStreamWithOptions(newTestReadableStream(chunks(prefix)...), options)
+		go func() {
+			data, err := io.ReadAll(stream)
+			if err != nil {
+				t.Errorf("ReadAll returned error: %v", err)
+			}
+			results <- string(data)
+		}()
+	}
+	if a, b := <-results, <-results; !(a == want("c") && b == want("d")) && !(a == want("d") && b == want("c")) {
+		t.Fatalf("ReadAll returned %q and %q, want %q and %q", a, b, want("c"), want("d"))
+	}
+}
+
+func TestAllocBuffer(t *testing.T) {
+	// The allocator hands out views of a single ArrayBuffer, which it gets back after every read.
+	buffer := js.Global().Get("ArrayBuffer").New(4)
+	var allocated, released int
+	stream := NewReadableStreamWithOptions(newTestReadableStream([]byte("Hello, "), []byte("world!")), ReadableStreamOptions{
+		AllocBuffer: func(n int) js.Value {
+			if buffer.IsUndefined() {
+				t.Fatal("AllocBuffer called before the buffer was released")
+			}
+			allocated++
+			view := js.Global().Get("Uint8Array").New(buffer)
+			buffer = js.Undefined()
+			return view
+		},
+		ReleaseBuffer: func(view js.Value) {
+			released++
+			buffer = view.Get("buffer")
+		},
+	})
+
+	var data []byte
+	chunk := make([]byte, 16)
+	for {
+		n, err := stream.Read(chunk)
+		if n > 4 {
+			t.Fatalf("Read returned %d bytes, want at most the 4 the allocator's buffer holds", n)
+		}
+		data = append(data, chunk[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+	}
+
+	if string(data) != "Hello, world!" {
+		t.Fatalf("read %q, want %q", data, "Hello, world!")
+	}
+	if allocated == 0 || released != allocated {
+		t.Fatalf("allocated %d views and released %d, want the same number of each", allocated, released)
+	}
+	if buffer.IsUndefined() || buffer.Get("byteLength").Int() != 4 {
+		t.Fatal("the allocator didn't get its buffer back")
+	}
+}
+
+func TestReaderReleaseStress(t *testing.T) {
+	// Reads and acquiring and releasing readers race with each other, which must never release a reader while a read
+	// through it is pending.
+	chunks := make([][]byte, 200)
+	var want []byte
+	for i := range chunks {
+		chunks[i] = []byte(fmt.Sprintf("chunk %03d;", i))
+		want = append(want, chunks[i]...)
+	}
+	stream := NewReadableStream(newTestReadableStream(chunks...))
+
+	done := make(chan struct{})
+	cycled := make(chan error, 1)
+	go func() {
+		modes := []string{ReaderModeBYOB, ReaderModeDefault}
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				cycled <- nil
+				return
+			default:
+			}
+
+			reader, err := stream.AcquireReader(modes[i%2])
+			if err == io.ErrClosedPipe {
+				cycled <- nil
+				return
+			}
+			if err != nil {
+				cycled <- fmt.Errorf("AcquireReader returned error: %w", err)
+				return
+			}
+			if err := reader.ReleaseLock(); err != nil {
+				cycled <- fmt.Errorf("ReleaseLock returned error: %w", err)
+				return
+			}
+			// Sleeping hands control back to the event loop, without which no read could ever settle.
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	var data []byte
+	buffer := make([]byte, 7)
+	for {
+		n, err := stream.Read(buffer)
+		data = append(data, buffer[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+	}
+	close(done)
+	if err := <-cycled; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("read %q, want %q", data, want)
+	}
+}
+
+// newBenchmarkByteStream creates a JavaScript byte ReadableStream that fills every BYOB request it gets for as long as it
+// is read from.
+func newBenchmarkByteStream() js.Value {
+	return js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"pull": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			request := args[0].Get("byobRequest")
+			request.Call("respond", request.Get("view").Get("byteLength"))
+			return nil
+		}),
+		"type": "bytes",
+	})
+}
+
+// BenchmarkReadBYOB reads 16 KiB at a time from a byte stream. On Node.js, reusing a persistent BYOB buffer made each
+// Read about a fifth faster, by sparing JavaScript a 16 KiB ArrayBuffer allocation per read. Copying straight from the
+// view a BYOB read resolves with, rather than normalising it like a default reader's chunk, and looking up the Uint8Array
+// constructor only once, took each Read from 29 allocations to 25, and from 321 to 290 bytes.
+func BenchmarkReadBYOB(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		size int
+	}{
+		{"allocate", 0},
+		{"persistent", 16 * 1024},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			stream := NewReadableStreamWithOptions(newBenchmarkByteStream(), ReadableStreamOptions{BYOBBufferSize: benchmark.size})
+			reader, err := stream.AcquireReader(ReaderModeBYOB)
+			if err != nil {
+				b.Fatalf("AcquireReader returned error: %v", err)
+			}
+
+			buffer := make([]byte, 16*1024)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(buffer)))
+			for i := 0; i < b.N; i++ {
+				if _, err := reader.Read(buffer); err != nil {
+					b.Fatalf("Read returned error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestTreatEmptyChunkAsEOF(t *testing.T) {
+	tests := []struct {
+		name       string
+		emptyIsEOF bool
+		want       string
+	}{
+		{"skip", false, "Hello, world!"},
+		{"eof", true, "Hello"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream := NewReadableStreamWithOptions(newTestDefaultReadableStream([]byte("Hello"), []byte{}, []byte(", world!")),
+				ReadableStreamOptions{TreatEmptyChunkAsEOF: test.emptyIsEOF})
+			reader, err := stream.AcquireReader(ReaderModeDefault)
+			if err != nil {
+				t.Fatalf("AcquireReader returned error: %v", err)
+			}
+
+			data, err := io.ReadAll(reader)
+			if err != nil || string(data) != test.want {
+				t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, test.want)
+			}
+		})
+	}
+}
+
+func TestReadInvalidChunk(t *testing.T) {
+	stream := NewReadableStream(js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			args[0].Call("enqueue", "Hello")
+			args[0].Call("enqueue", js.Global().Get("DataView").New(js.Global().Get("ArrayBuffer").New(2)))
+			return nil
+		}),
+	}))
+	reader, err := stream.AcquireReader(ReaderModeDefault)
+	if err != nil {
+		t.Fatalf("AcquireReader returned error: %v", err)
+	}
+
+	buffer := make([]byte, 8)
+	if _, err := reader.Read(buffer); err != ErrInvalidChunk {
+		t.Fatalf("Read of a string chunk returned %v, want %v", err, ErrInvalidChunk)
+	}
+	// A DataView is as good as a Uint8Array.
+	if n, err := reader.Read(buffer); n != 2 || err != nil {
+		t.Fatalf("Read of a DataView chunk returned %d, %v, want 2, nil", n, err)
+	}
+}
+
+// iotestHalfReader reads into at most 4 bytes of the buffer at a time.
+type iotestHalfReader struct {
+	io.Reader
+}
+
+func (r iotestHalfReader) Read(p []byte) (int, error) {
+	if len(p) > 4 {
+		p = p[:4]
+	}
+	return r.Reader.Read(p)
+}
+
+func TestLocked(t *testing.T) {
+	stream := NewReadableStream(newTestReadableStream([]byte("Hello")))
+	if stream.Locked() {
+		t.Fatal("Locked returned true before a reader was acquired")
+	}
+
+	reader, err := stream.AcquireReader(ReaderModeBYOB)
+	if err != nil {
+		t.Fatalf("AcquireReader returned error: %v", err)
+	}
+	if !stream.Locked() {
+		t.Fatal("Locked returned false while a reader was held")
+	}
+
+	if err := reader.ReleaseLock(); err != nil {
+		t.Fatalf("ReleaseLock returned error: %v", err)
+	}
+	if stream.Locked() {
+		t.Fatal("Locked returned true after the reader was released")
+	}
+
+	jsStream, _ := newTestWritableStream()
+	writer := jsStream.Call("getWriter")
+	if !NewWritableStream(jsStream).Locked() {
+		t.Fatal("WritableStream.Locked returned false while a writer was held")
+	}
+	writer.Call("releaseLock")
+}
@@ -0,0 +1,59 @@
+package jsStreams
+
+import (
+	"io"
+	"sync"
+)
+
+// fixedChunkReader reads its stream in blocks of exactly size bytes, returning a single block, or as much of one as fits,
+// per Read.
+type fixedChunkReader struct {
+	stream *ReadableStream
+	lock   sync.Mutex
+	block  []byte
+	// pending is the part of the current block that hasn't been read yet, and err is the error that ended the stream,
+	// which is returned once pending has been read.
+	pending []byte
+	err     error
+}
+
+func (f *fixedChunkReader) Read(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if len(f.pending) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+
+		n, err := io.ReadFull(f.stream, f.block)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		f.pending, f.err = f.block[:n], err
+		if n == 0 {
+			return 0, err
+		}
+	}
+
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
+
+// Close cancels the underlying stream.
+func (f *fixedChunkReader) Close() error {
+	return f.stream.Close()
+}
+
+// FixedChunkReadableStream creates a ReadableStream that yields the contents of r as chunks of exactly size bytes, except
+// for the last, which holds whatever is left once r ends and may be shorter, for consumers that need uniform chunks, such
+// as a block cipher, whatever size of chunks r happens to be made of. Each chunk is only yielded once it is complete, so
+// reading it may wait for several chunks of r. If size is not positive, the default of 32 KiB is used. An error from r is
+// passed on once the data read before it has been yielded, and closing the returned stream cancels r.
+func FixedChunkReadableStream(r *ReadableStream, size int) *ReadableStream {
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	return newGoReadableStreamSize(&fixedChunkReader{stream: r, block: make([]byte, size)}, size)
+}
@@ -0,0 +1,47 @@
+package jsStreams
+
+import (
+	"io"
+	"testing"
+)
+
+func TestFixedChunkReadableStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		size   int
+		want   []string
+	}{
+		{"split", []string{"Hello, world!"}, 4, []string{"Hell", "o, w", "orld", "!"}},
+		{"joined", []string{"He", "l", "lo, wo", "rld", "!!!"}, 5, []string{"Hello", ", wor", "ld!!!"}},
+		{"empty", nil, 4, nil},
+	}
+
+	for _, test := range tests {
+		stream := FixedChunkReadableStream(newChunkedStream(test.chunks...), test.size)
+
+		// A buffer larger than the chunks reads a single chunk at a time.
+		var chunks []string
+		buffer := make([]byte, 64)
+		for {
+			n, err := stream.Read(buffer)
+			if n > 0 {
+				chunks = append(chunks, string(buffer[:n]))
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: Read returned error: %v", test.name, err)
+			}
+		}
+		if len(chunks) != len(test.want) {
+			t.Fatalf("%s: read %q, want %q", test.name, chunks, test.want)
+		}
+		for i := range chunks {
+			if chunks[i] != test.want[i] {
+				t.Fatalf("%s: read %q, want %q", test.name, chunks, test.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"io"
+	"syscall/js"
+)
+
+// contentTypes maps the JavaScript streams created by ReaderToReadableStreamTyped to their content types. It is a WeakMap,
+// so that a stream's entry goes away along with the stream.
+var contentTypes = js.Global().Get("WeakMap").New()
+
+// ReaderToReadableStreamTyped converts an io.Reader to a JavaScript ReadableStream, like ReaderToReadableStream, and
+// records contentType as the MIME type of its contents, which ReadableStreamToResponse and ToBlob then use by default, so
+// that the type doesn't have to be passed along separately. The type stays with the JavaScript stream, so any
+// ReadableStream wrapping it sees it, through ContentType.
+func ReaderToReadableStreamTyped(r io.Reader, contentType string, cancel ...func()) js.Value {
+	stream := ReaderToReadableStream(r, cancel...)
+	contentTypes.Call("set", stream, contentType)
+	return stream
+}
+
+// ContentType returns the MIME type recorded for the stream when it was created by ReaderToReadableStreamTyped, or an
+// empty string if it wasn't.
+func (r *ReadableStream) ContentType() string {
+	contentType := contentTypes.Call("get", r.stream)
+	if contentType.Type() != js.TypeString {
+		return ""
+	}
+	return contentType.String()
+}
+
+// ReadableStreamToResponse creates a JavaScript Response whose body is r, as a service worker needs to respond to a
+// request with data produced in Go. init is passed on to the Response constructor, to set the status and headers, and may
+// be nil. If init doesn't set a Content-Type header, it is set to the stream's ContentType, if it has one. The Response
+// takes over the stream, so it must not be locked, and must not be read from afterwards.
+func ReadableStreamToResponse(r *ReadableStream, init map[string]interface{}) js.Value {
+	var response js.Value
+	if init == nil {
+		response = js.Global().Get("Response").New(r.JSValue())
+	} else {
+		response = js.Global().Get("Response").New(r.JSValue(), init)
+	}
+
+	if contentType := r.ContentType(); contentType != "" {
+		headers := response.Get("headers")
+		if !headers.Call("has", "Content-Type").Bool() {
+			headers.Call("set", "Content-Type", contentType)
+		}
+	}
+	return response
+}
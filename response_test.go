@@ -0,0 +1,67 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadableStreamToResponse(t *testing.T) {
+	stream := newStringStream("Hello, world!")
+	response := ReadableStreamToResponse(stream, map[string]interface{}{
+		"status":  201,
+		"headers": map[string]interface{}{"Content-Type": "text/plain"},
+	})
+
+	if status := response.Get("status").Int(); status != 201 {
+		t.Fatalf("Response has status %d, want 201", status)
+	}
+	if contentType := response.Get("headers").Call("get", "Content-Type").String(); !strings.HasPrefix(contentType, "text/plain") {
+		t.Fatalf("Response has Content-Type %q, want %q", contentType, "text/plain")
+	}
+
+	text, err := await(response.Call("text"))
+	if err != nil {
+		t.Fatalf("text returned error: %v", err)
+	}
+	if text.String() != "Hello, world!" {
+		t.Fatalf("Response body is %q, want %q", text.String(), "Hello, world!")
+	}
+}
+
+func TestReaderToReadableStreamTyped(t *testing.T) {
+	newStream := func() *ReadableStream {
+		return NewReadableStream(ReaderToReadableStreamTyped(strings.NewReader("{}"), "application/json"))
+	}
+	if contentType := newStream().ContentType(); contentType != "application/json" {
+		t.Fatalf("ContentType returned %q, want %q", contentType, "application/json")
+	}
+	if contentType := newStringStream("{}").ContentType(); contentType != "" {
+		t.Fatalf("ContentType of an untyped stream returned %q, want none", contentType)
+	}
+
+	// The type becomes the Response's Content-Type, unless the Response is given one of its own.
+	for _, test := range []struct {
+		init map[string]interface{}
+		want string
+	}{
+		{nil, "application/json"},
+		{map[string]interface{}{"status": 201}, "application/json"},
+		{map[string]interface{}{"headers": map[string]interface{}{"Content-Type": "text/plain"}}, "text/plain"},
+	} {
+		response := ReadableStreamToResponse(newStream(), test.init)
+		if contentType := response.Get("headers").Call("get", "Content-Type").String(); contentType != test.want {
+			t.Fatalf("Response has Content-Type %q, want %q", contentType, test.want)
+		}
+	}
+
+	// The same goes for a Blob.
+	blob, err := newStream().ToBlob("")
+	if err != nil {
+		t.Fatalf("ToBlob returned error: %v", err)
+	}
+	if blobType := blob.Get("type").String(); blobType != "application/json" {
+		t.Fatalf("Blob has type %q, want %q", blobType, "application/json")
+	}
+}
@@ -0,0 +1,188 @@
+package jsStreams
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"sync/atomic"
+)
+
+// resumableHeaderSize is the size of the header at the start of every frame written by a ResumableWriter, made up of the
+// 8-byte big-endian offset of the payload in the data, followed by the 4-byte big-endian CRC-32 of the payload.
+const resumableHeaderSize = 12
+
+// ErrInvalidOffset is returned by ResumableWriter.Resume if the offset is beyond what the stream is known to have
+// accepted.
+var ErrInvalidOffset = errors.New("offset is beyond the data acknowledged")
+
+// ErrFrameCorrupt is returned by ResumableReader.Read if a frame is malformed, or its payload doesn't match its checksum.
+var ErrFrameCorrupt = errors.New("frame is corrupt")
+
+// ErrFrameGap is returned by ResumableReader.Read if a frame starts beyond the end of the data read so far, so that some
+// of the data in between is missing.
+var ErrFrameGap = errors.New("frame does not follow the data read so far")
+
+// ResumableWriter writes data to a WritableStream as a series of frames, each carrying up to chunkSize bytes of the data,
+// prefixed with their offset in the data and a CRC-32 checksum, for uploads over a link that can fail part way through.
+// Each frame is written with WriteFrame, so it reaches the sink whole. LastAcked reports how much of the data the stream
+// has accepted, and if the stream fails, Resume carries on from an offset on a new stream, such as the offset the other
+// side says it has received. The frames are read back by a ResumableReader.
+type ResumableWriter struct {
+	lock      sync.Mutex
+	stream    *WritableStream
+	chunkSize int
+	// buffer holds the data that doesn't yet fill a frame, which starts at offset.
+	buffer []byte
+	offset int64
+	acked  atomic.Int64
+}
+
+// NewResumableWriter creates a ResumableWriter that writes frames of up to chunkSize bytes of data to w. If chunkSize is
+// not positive, the default of 32 KiB is used.
+func NewResumableWriter(w *WritableStream, chunkSize int) *ResumableWriter {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	return &ResumableWriter{stream: w, chunkSize: chunkSize}
+}
+
+// Write adds p to the data, writing out every frame it fills. The data that doesn't fill a frame is held back until more
+// is written, or until Flush or Close. If a frame fails to be written, the stream has to be replaced with Resume.
+func (r *ResumableWriter) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.buffer = append(r.buffer, p...)
+	for len(r.buffer) >= r.chunkSize {
+		if err := r.writeFrame(r.chunkSize); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// writeFrame writes the first n bytes of the buffer as a frame, and removes them from it. The caller must hold the lock.
+func (r *ResumableWriter) writeFrame(n int) error {
+	frame := make([]byte, resumableHeaderSize, resumableHeaderSize+n)
+	binary.BigEndian.PutUint64(frame, uint64(r.offset))
+	binary.BigEndian.PutUint32(frame[8:], crc32.ChecksumIEEE(r.buffer[:n]))
+	if err := r.stream.WriteFrame(append(frame, r.buffer[:n]...)); err != nil {
+		return err
+	}
+
+	r.buffer = r.buffer[:copy(r.buffer, r.buffer[n:])]
+	r.offset += int64(n)
+	r.acked.Store(r.offset)
+	return nil
+}
+
+// Flush writes out the data held back because it doesn't fill a frame, as a shorter frame.
+func (r *ResumableWriter) Flush() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.buffer) == 0 {
+		return nil
+	}
+	return r.writeFrame(len(r.buffer))
+}
+
+// Close flushes the data held back, then closes the stream.
+func (r *ResumableWriter) Close() error {
+	if err := r.Flush(); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.stream.Close()
+}
+
+// LastAcked returns the offset just past the last frame the stream accepted, which is how much of the data has been
+// handed to the sink. Data held back in a partial frame isn't counted.
+func (r *ResumableWriter) LastAcked() int64 {
+	return r.acked.Load()
+}
+
+// Resume carries on writing on w, typically a new stream to the same destination after the previous one failed, from
+// offset in the data, which must not be beyond LastAcked. Any data held back is dropped, and the caller must then write
+// the data again from offset onwards. An offset before LastAcked resends data that was already accepted, in case it was
+// lost after the sink received it, which a ResumableReader skips over.
+func (r *ResumableWriter) Resume(w *WritableStream, offset int64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if offset < 0 || offset > r.acked.Load() {
+		return fmt.Errorf("%w: %d, acknowledged %d", ErrInvalidOffset, offset, r.acked.Load())
+	}
+
+	r.stream = w
+	r.buffer = r.buffer[:0]
+	r.offset = offset
+	r.acked.Store(offset)
+	return nil
+}
+
+// ResumableReader reads the data written by a ResumableWriter back from a ReadableStream, checking the checksum of every
+// frame and putting the data back together. Offset reports how much of the data has been read, which is where the writer
+// should resume from if the stream fails, and Resume carries on reading from the new stream. Data that is sent again,
+// because the writer resumed from an earlier offset, is skipped.
+type ResumableReader struct {
+	stream *ReadableStream
+	offset int64
+	// pending is the part of the last frame that hasn't been read yet.
+	pending []byte
+}
+
+// NewResumableReader creates a ResumableReader that reads frames written by a ResumableWriter from r.
+func NewResumableReader(r *ReadableStream) *ResumableReader {
+	return &ResumableReader{stream: r}
+}
+
+// Read reads the data carried by the frames, returning io.EOF once the stream ends cleanly between frames, which may be
+// before the end of the data if the writer's stream failed. It returns an error wrapping ErrFrameCorrupt if a frame is
+// corrupt, or ErrFrameGap if some of the data is missing, in which case the writer should resume from Offset.
+func (r *ResumableReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		frame, err := r.stream.ReadFrame()
+		if err != nil {
+			return 0, err
+		}
+		if len(frame) < resumableHeaderSize {
+			return 0, fmt.Errorf("%w: frame of %d bytes is too short", ErrFrameCorrupt, len(frame))
+		}
+
+		offset := int64(binary.BigEndian.Uint64(frame))
+		payload := frame[resumableHeaderSize:]
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(frame[8:]) {
+			return 0, fmt.Errorf("%w: checksum mismatch at offset %d", ErrFrameCorrupt, offset)
+		}
+		if offset > r.offset {
+			return 0, fmt.Errorf("%w: frame at offset %d, want %d", ErrFrameGap, offset, r.offset)
+		}
+
+		// Skip whatever part of the frame has already been read.
+		if skip := r.offset - offset; skip < int64(len(payload)) {
+			r.pending = payload[skip:]
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	r.offset += int64(n)
+	return n, nil
+}
+
+// Offset returns how much of the data has been read so far, which is the offset the writer should resume from.
+func (r *ResumableReader) Offset() int64 {
+	return r.offset
+}
+
+// Resume carries on reading from stream, typically a new stream the writer resumed on, after the previous one ended or
+// failed. Any data left from the last frame on the previous stream is still returned first.
+func (r *ResumableReader) Resume(stream *ReadableStream) {
+	r.stream = stream
+}
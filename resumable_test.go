@@ -0,0 +1,90 @@
+package jsStreams
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestResumableWriter(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	sentErr := errors.New("connection lost")
+
+	// The first upload fails after a few frames have got through.
+	first := &recordingSink{}
+	writer := NewResumableWriter(newGoWritableStream(first), 64)
+	if _, err := writer.Write(data[:300]); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	first.err = sentErr
+	if _, err := writer.Write(data[300:]); err == nil || err.Error() != sentErr.Error() {
+		t.Fatalf("Write to a failed stream returned %v, want %v", err, sentErr)
+	}
+	if writer.LastAcked() != 256 {
+		t.Fatalf("LastAcked returned %d, want %d", writer.LastAcked(), 256)
+	}
+
+	reader := NewResumableReader(newGoReadableStream(io.NopCloser(bytes.NewReader(first.Bytes()))))
+	received, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(received, data[:256]) {
+		t.Fatalf("ReadAll returned %d bytes, %v, want the first %d bytes", len(received), err, 256)
+	}
+
+	if err := writer.Resume(newGoWritableStream(&recordingSink{}), writer.LastAcked()+1); !errors.Is(err, ErrInvalidOffset) {
+		t.Fatalf("Resume beyond LastAcked returned %v, want %v", err, ErrInvalidOffset)
+	}
+
+	// The second upload resends from an earlier offset than the reader has got to, which the reader skips.
+	second := &recordingSink{}
+	offset := reader.Offset() - 100
+	if err := writer.Resume(newGoWritableStream(second), offset); err != nil {
+		t.Fatalf("Resume returned error: %v", err)
+	}
+	if _, err := writer.Write(data[offset:]); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if writer.LastAcked() != int64(len(data)) {
+		t.Fatalf("LastAcked returned %d, want %d", writer.LastAcked(), len(data))
+	}
+
+	reader.Resume(newGoReadableStream(io.NopCloser(bytes.NewReader(second.Bytes()))))
+	rest, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(append(received, rest...), data) {
+		t.Fatalf("ReadAll returned %d bytes, %v, want the remaining %d bytes", len(rest), err, len(data)-256)
+	}
+}
+
+func TestResumableReaderCorrupt(t *testing.T) {
+	sink := &recordingSink{}
+	writer := NewResumableWriter(newGoWritableStream(sink), 0)
+	if _, err := writer.Write([]byte("Hello, world!")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	corrupted := append([]byte(nil), sink.Bytes()...)
+	corrupted[len(corrupted)-1] ^= 1
+	reader := NewResumableReader(newGoReadableStream(io.NopCloser(bytes.NewReader(corrupted))))
+	if _, err := io.ReadAll(reader); !errors.Is(err, ErrFrameCorrupt) {
+		t.Fatalf("ReadAll of a corrupted frame returned %v, want %v", err, ErrFrameCorrupt)
+	}
+
+	// A frame starting beyond what has been read is a gap.
+	gap := &recordingSink{}
+	writer = NewResumableWriter(newGoWritableStream(gap), 4)
+	if _, err := writer.Write([]byte("Hello, world!")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	frames := gap.Bytes()
+	frameSize := 4 + resumableHeaderSize + 4
+	reader = NewResumableReader(newGoReadableStream(io.NopCloser(bytes.NewReader(frames[frameSize:]))))
+	if _, err := io.ReadAll(reader); !errors.Is(err, ErrFrameGap) {
+		t.Fatalf("ReadAll with a missing frame returned %v, want %v", err, ErrFrameGap)
+	}
+}
@@ -0,0 +1,199 @@
+package jsStreams
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// retryReader reads from a stream obtained from factory, replacing it with a fresh one whenever a read fails. lock
+// serialises reads, while streamLock guards stream and closed, so that Close can close the current stream without
+// waiting for a read from it to return.
+type retryReader struct {
+	factory    func() (*ReadableStream, error)
+	maxRetries int
+	stream     *ReadableStream
+	failures   int
+	closed     bool
+	lock       sync.Mutex
+	streamLock sync.Mutex
+}
+
+func (r *retryReader) Read(p []byte) (n int, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for {
+		stream, closed, err := r.current()
+		if closed {
+			return 0, io.ErrClosedPipe
+		}
+		if err == nil {
+			n, err = stream.Read(p)
+			if err == nil || err == io.EOF {
+				r.failures = 0
+				return n, err
+			}
+			r.discard(stream)
+		}
+
+		// Whatever the failed stream returned alongside the error has still been delivered.
+		if r.failures >= r.maxRetries || n > 0 {
+			return n, err
+		}
+		r.failures++
+	}
+}
+
+// current returns the stream to read from, calling factory for a fresh one if there is none, or reports that the reader
+// has been closed.
+func (r *retryReader) current() (stream *ReadableStream, closed bool, err error) {
+	r.streamLock.Lock()
+	if r.closed {
+		r.streamLock.Unlock()
+		return nil, true, nil
+	}
+	if r.stream != nil {
+		defer r.streamLock.Unlock()
+		return r.stream, false, nil
+	}
+	r.streamLock.Unlock()
+
+	// factory may take a while, so Close isn't held up waiting for it.
+	stream, err = r.factory()
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.streamLock.Lock()
+	defer r.streamLock.Unlock()
+
+	if r.closed {
+		_ = stream.Close()
+		return nil, true, nil
+	}
+	r.stream = stream
+	return stream, false, nil
+}
+
+// discard closes stream after a failed read, and drops it unless Close already has.
+func (r *retryReader) discard(stream *ReadableStream) {
+	r.streamLock.Lock()
+	if r.stream == stream {
+		r.stream = nil
+	}
+	r.streamLock.Unlock()
+
+	_ = stream.Close()
+}
+
+// Close closes the current stream. It doesn't wait for a read in progress, which returns once its stream has been closed.
+func (r *retryReader) Close() error {
+	r.streamLock.Lock()
+	stream := r.stream
+	r.closed = true
+	r.stream = nil
+	r.streamLock.Unlock()
+
+	if stream == nil {
+		return nil
+	}
+	return stream.Close()
+}
+
+// RetryReadableStream creates a ReadableStream that reads from a stream returned by factory, and, if a read from it
+// fails, discards it and calls factory again for a fresh stream to continue from. Up to maxRetries failures in a row are
+// retried, counting both failed reads and errors returned by factory, before the last error is passed on to the reader.
+// A successful read resets the count. factory is first called on the first read.
+//
+// The replacement stream is read from the start, so it is up to factory to resume where the previous stream left off,
+// for instance by counting the bytes delivered so far and requesting the rest from the source. Nothing returned by a
+// stream before it failed is requested again, but a source that resumes from an earlier point than the failure will have
+// its data delivered more than once, so the returned stream guarantees at-least-once delivery, not exactly-once. Closing
+// the returned stream closes the current stream.
+func RetryReadableStream(factory func() (*ReadableStream, error), maxRetries int) *ReadableStream {
+	return newGoReadableStream(&retryReader{factory: factory, maxRetries: maxRetries})
+}
+
+// retryWriter writes to a stream obtained from factory, replacing it with a fresh one whenever a write fails.
+type retryWriter struct {
+	factory    func() (*WritableStream, error)
+	maxRetries int
+	backoff    func(attempt int) time.Duration
+	stream     *WritableStream
+	closed     bool
+	lock       sync.Mutex
+}
+
+func (w *retryWriter) Write(p []byte) (written int, err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	for failures := 0; ; failures++ {
+		if w.stream == nil {
+			w.stream, err = w.factory()
+		}
+		if err == nil {
+			// Whatever the failed stream accepted before failing isn't written again.
+			var n int
+			n, err = w.stream.Write(p[written:])
+			written += n
+			if err == nil {
+				return written, nil
+			}
+			_ = w.stream.Close()
+			w.stream = nil
+		}
+
+		if failures >= w.maxRetries {
+			return written, err
+		}
+		if w.backoff != nil {
+			time.Sleep(w.backoff(failures + 1))
+		}
+	}
+}
+
+func (w *retryWriter) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.stream == nil {
+		return nil
+	}
+	return w.stream.Flush()
+}
+
+func (w *retryWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.closed = true
+	if w.stream == nil {
+		return nil
+	}
+
+	err := w.stream.Close()
+	w.stream = nil
+	return err
+}
+
+// RetryWritableStream creates a WritableStream that writes to a stream returned by factory, and, if a write to it fails,
+// discards it and calls factory again for a fresh stream to write the rest of that write to. Up to maxRetries failures
+// are retried for each write, counting both failed writes and errors returned by factory, before the last error is
+// returned to the writer. Before each retry, it waits for backoff(attempt), where attempt counts from 1, unless backoff is
+// nil. factory is first called on the first write.
+//
+// The replacement stream only receives the part of the failed write that the failed stream didn't accept, and whatever
+// is written after it, so it is up to factory to resume where the previous stream left off, for instance by reopening an
+// append-only sink for appending. A write can fail after part of it has reached the sink, in which case that part is
+// written again, so the returned stream guarantees at-least-once delivery, not exactly-once, and the sink must either
+// tolerate duplicated data or be written idempotently, for instance at explicit offsets. Errors from flushing or closing
+// the stream are not retried. Closing the returned stream closes the current stream.
+func RetryWritableStream(factory func() (*WritableStream, error), maxRetries int, backoff func(attempt int) time.Duration) *WritableStream {
+	return newGoWritableStream(&retryWriter{factory: factory, maxRetries: maxRetries, backoff: backoff})
+}
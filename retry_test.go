@@ -0,0 +1,137 @@
+package jsStreams
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestRetryReadableStream(t *testing.T) {
+	lost := errors.New("connection lost")
+
+	// The first stream fails part way through, and the second one resumes after the data it delivered.
+	var calls int
+	stream := RetryReadableStream(func() (*ReadableStream, error) {
+		calls++
+		if calls == 1 {
+			return newGoReadableStream(io.NopCloser(io.MultiReader(strings.NewReader("Hello, "), iotest.ErrReader(lost)))), nil
+		}
+		return newStringStream("world!"), nil
+	}, 1)
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, want %q", data, "Hello, world!")
+	}
+	if calls != 2 {
+		t.Fatalf("factory was called %d times, want 2", calls)
+	}
+}
+
+func TestRetryReadableStreamExhausted(t *testing.T) {
+	lost := errors.New("connection lost")
+
+	var calls int
+	stream := RetryReadableStream(func() (*ReadableStream, error) {
+		calls++
+		return newGoReadableStream(io.NopCloser(iotest.ErrReader(lost))), nil
+	}, 2)
+
+	if _, err := io.ReadAll(stream); err == nil || !strings.Contains(err.Error(), lost.Error()) {
+		t.Fatalf("ReadAll returned %v, want %v", err, lost)
+	}
+	if calls != 3 {
+		t.Fatalf("factory was called %d times, want 3", calls)
+	}
+}
+
+func TestRetryReadableStreamCloseDuringRead(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	created := make(chan struct{})
+	stream := RetryReadableStream(func() (*ReadableStream, error) {
+		close(created)
+		return newGoReadableStream(pipeReader), nil
+	}, 1)
+
+	// Nothing is ever written, so this read stalls until the stream is closed.
+	read := make(chan error, 1)
+	go func() {
+		_, err := stream.Read(make([]byte, 1))
+		read <- err
+	}()
+	<-created
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- stream.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return while a read was pending")
+	}
+	if _, err := pipeWriter.Write([]byte("a")); err != io.ErrClosedPipe {
+		t.Fatalf("writing to the current stream's source returned %v, want %v", err, io.ErrClosedPipe)
+	}
+	select {
+	case err := <-read:
+		if err == nil {
+			t.Fatal("pending Read returned no error once the stream was closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending Read did not return once the stream was closed")
+	}
+}
+
+func TestRetryWritableStream(t *testing.T) {
+	rejected := errors.New("write rejected")
+
+	// The first sink rejects the first write, and the second one accepts everything.
+	var calls int
+	var attempts []int
+	first, second := &recordingSink{err: rejected}, &recordingSink{}
+	stream := RetryWritableStream(func() (*WritableStream, error) {
+		calls++
+		if calls == 1 {
+			return newGoWritableStream(first), nil
+		}
+		return newGoWritableStream(second), nil
+	}, 1, func(attempt int) time.Duration {
+		attempts = append(attempts, attempt)
+		return time.Millisecond
+	})
+
+	for _, chunk := range []string{"Hello, ", "world!"} {
+		if _, err := stream.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if second.String() != "Hello, world!" || !second.closed {
+		t.Fatalf("second sink received %q and was closed: %v, want %q and true", second.String(), second.closed,
+			"Hello, world!")
+	}
+	if calls != 2 || fmt.Sprint(attempts) != "[1]" {
+		t.Fatalf("factory was called %d times and backoff was asked for %v, want 2 and [1]", calls, attempts)
+	}
+
+	// Once the retries run out, the error is passed on.
+	stream = RetryWritableStream(func() (*WritableStream, error) {
+		return newGoWritableStream(&recordingSink{err: rejected}), nil
+	}, 2, nil)
+	if _, err := stream.Write([]byte("lost")); err == nil || !strings.Contains(err.Error(), rejected.Error()) {
+		t.Fatalf("Write returned %v, want %v", err, rejected)
+	}
+}
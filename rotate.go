@@ -0,0 +1,92 @@
+package jsStreams
+
+import (
+	"sync"
+)
+
+// rotatingWriter writes to a sequence of sinks, moving on to a new one whenever the current one has taken maxBytes.
+type rotatingWriter struct {
+	newSink  func(index int) (*WritableStream, error)
+	maxBytes int64
+	lock     sync.Mutex
+
+	// sink is the sink being written to, if one has been opened since the last was filled, index is its index, and
+	// written is how much it has taken.
+	sink    *WritableStream
+	index   int
+	written int64
+}
+
+func (r *rotatingWriter) Write(p []byte) (n int, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for len(p) > 0 {
+		if r.sink == nil {
+			r.sink, err = r.newSink(r.index)
+			if err != nil {
+				r.sink = nil
+				return n, err
+			}
+			r.written = 0
+		}
+
+		// A write that crosses the end of a segment is split between it and the next.
+		segment := p
+		if r.maxBytes > 0 && int64(len(segment)) > r.maxBytes-r.written {
+			segment = segment[:r.maxBytes-r.written]
+		}
+		written, err := r.sink.Write(segment)
+		n += written
+		r.written += int64(written)
+		p = p[written:]
+		if err != nil {
+			return n, err
+		}
+
+		if r.maxBytes > 0 && r.written >= r.maxBytes {
+			sink := r.sink
+			r.sink = nil
+			r.index++
+			if err := sink.Close(); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (r *rotatingWriter) Flush() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.sink == nil {
+		return nil
+	}
+	return r.sink.Flush()
+}
+
+// Close closes the sink being written to, if there is one.
+func (r *rotatingWriter) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.sink == nil {
+		return nil
+	}
+	sink := r.sink
+	r.sink = nil
+	return sink.Close()
+}
+
+// RotatingWritableStream creates a WritableStream that splits what is written to it into segments of maxBytes, each
+// written to a sink of its own, for data too large for any one target, such as a file in chunked storage. newSink is
+// called with the index of each segment, starting at 0, to open its sink once there is data for it, and each sink is
+// closed as soon as it has taken maxBytes, so a write that crosses the end of a segment is split between two sinks. The
+// last segment may be shorter, and its sink is closed when the returned stream is. If maxBytes is not positive,
+// everything is written to a single sink. If newSink or a sink fails, the write fails with its error, having written
+// whatever came before to the sinks.
+func RotatingWritableStream(newSink func(index int) (*WritableStream, error), maxBytes int64) *WritableStream {
+	return newGoWritableStream(&rotatingWriter{newSink: newSink, maxBytes: maxBytes})
+}
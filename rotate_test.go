@@ -0,0 +1,53 @@
+package jsStreams
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRotatingWritableStream(t *testing.T) {
+	var sinks []*recordingSink
+	stream := RotatingWritableStream(func(index int) (*WritableStream, error) {
+		if index != len(sinks) {
+			t.Errorf("newSink was called with index %d, want %d", index, len(sinks))
+		}
+		sinks = append(sinks, &recordingSink{})
+		return newGoWritableStream(sinks[index]), nil
+	}, 10)
+
+	// 25 bytes, in writes of 7, so that the second and third writes cross the ends of segments.
+	data := "0123456789abcdefghijABCDE"
+	for i := 0; i < len(data); i += 7 {
+		if _, err := stream.Write([]byte(data[i:min(i+7, len(data))])); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if len(sinks) != 3 || !sinks[0].closed || !sinks[1].closed || sinks[2].closed {
+		t.Fatalf("wrote to %d sinks, want 3, of which only the first two are closed", len(sinks))
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	want := []string{"0123456789", "abcdefghij", "ABCDE"}
+	for i, sink := range sinks {
+		if sink.String() != want[i] || !sink.closed {
+			t.Fatalf("sink %d received %q, closed %v, want %q, closed", i, sink.String(), sink.closed, want[i])
+		}
+	}
+}
+
+func TestRotatingWritableStreamSinkError(t *testing.T) {
+	sinkErr := errors.New("out of space")
+	stream := RotatingWritableStream(func(index int) (*WritableStream, error) {
+		if index > 0 {
+			return nil, sinkErr
+		}
+		return newGoWritableStream(&recordingSink{}), nil
+	}, 5)
+
+	if _, err := stream.Write([]byte(strings.Repeat("x", 8))); err == nil || err.Error() != sinkErr.Error() {
+		t.Fatalf("Write returned %v, want %v", err, sinkErr)
+	}
+}
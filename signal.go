@@ -0,0 +1,49 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"context"
+	"syscall/js"
+)
+
+// signalContext returns a context that is cancelled once signal, a JavaScript AbortSignal, is aborted, or straight away
+// if it already has been. The returned cancel function stops listening to the signal, and must always be called.
+func signalContext(signal js.Value) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if signal.Get("aborted").Bool() {
+		cancel()
+		return ctx, cancel
+	}
+
+	onAbort := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		cancel()
+		return nil
+	})
+	signal.Call("addEventListener", "abort", onAbort, map[string]interface{}{"once": true})
+
+	return ctx, func() {
+		cancel()
+		signal.Call("removeEventListener", "abort", onAbort)
+		onAbort.Release()
+	}
+}
+
+// ReadWithSignal reads up to len(p) bytes into p, like ReadContext, but gives up once signal, a JavaScript AbortSignal
+// such as one from an AbortController, is aborted, returning context.Canceled. As with ReadContext, data read after
+// giving up isn't lost, but kept for the next read. This lets Go code take part in cancellation driven by JavaScript.
+func (r *ReadableStream) ReadWithSignal(signal js.Value, p []byte) (int, error) {
+	ctx, cancel := signalContext(signal)
+	defer cancel()
+	return r.ReadContext(ctx, p)
+}
+
+// WriteWithSignal writes p to the stream, like Write, but gives up once signal, a JavaScript AbortSignal such as one from
+// an AbortController, is aborted, returning 0 and context.Canceled. A JavaScript write can't be taken back, so one that
+// has already started carries on in the background, and may still reach the sink. Until it finishes, other writes wait
+// for it. p is copied before writing, so it can be reused as soon as WriteWithSignal returns.
+func (w *WritableStream) WriteWithSignal(signal js.Value, p []byte) (int, error) {
+	ctx, cancel := signalContext(signal)
+	defer cancel()
+	return w.writeContext(ctx, p)
+}
@@ -0,0 +1,84 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"context"
+	"io"
+	"syscall/js"
+	"testing"
+)
+
+func TestReadWithSignal(t *testing.T) {
+	pulled := make(chan struct{}, 1)
+	var controller js.Value
+	stream := NewReadableStream(js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			controller = args[0]
+			return nil
+		}),
+		"pull": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			select {
+			case pulled <- struct{}{}:
+			default:
+			}
+			return nil
+		}),
+		"type": "bytes",
+	}, map[string]interface{}{"highWaterMark": 0}))
+
+	abortController := js.Global().Get("AbortController").New()
+	go func() {
+		<-pulled
+		abortController.Call("abort")
+	}()
+
+	if _, err := stream.ReadWithSignal(abortController.Get("signal"), make([]byte, 5)); err != context.Canceled {
+		t.Fatalf("ReadWithSignal returned %v, want %v", err, context.Canceled)
+	}
+
+	// The abandoned read still receives the data, which is kept for the next read.
+	data := js.Global().Get("Uint8Array").New(5)
+	js.CopyBytesToJS(data, []byte("Hello"))
+	controller.Call("enqueue", data)
+	controller.Call("close")
+	if data, err := io.ReadAll(stream); err != nil || string(data) != "Hello" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello")
+	}
+
+	// A signal that has already been aborted gives up straight away.
+	if _, err := stream.ReadWithSignal(abortController.Get("signal"), make([]byte, 5)); err != context.Canceled {
+		t.Fatalf("ReadWithSignal with an aborted signal returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWriteWithSignal(t *testing.T) {
+	writing := make(chan struct{}, 1)
+	stream := NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+		"write": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			writing <- struct{}{}
+			promise, _, _ := newPromise()
+			return promise
+		}),
+	}))
+
+	abortController := js.Global().Get("AbortController").New()
+	go func() {
+		<-writing
+		abortController.Call("abort")
+	}()
+
+	if n, err := stream.WriteWithSignal(abortController.Get("signal"), []byte("Hello")); n != 0 || err != context.Canceled {
+		t.Fatalf("WriteWithSignal returned %d, %v, want 0, %v", n, err, context.Canceled)
+	}
+
+	// A signal that is never aborted doesn't get in the way.
+	writable, sink := newTestWritableStream()
+	signal := js.Global().Get("AbortController").New().Get("signal")
+	if n, err := NewWritableStream(writable).WriteWithSignal(signal, []byte("Hello")); n != 5 || err != nil {
+		t.Fatalf("WriteWithSignal returned %d, %v, want 5, nil", n, err)
+	}
+	if string(sink.bytes()) != "Hello" {
+		t.Fatalf("sink received %q, want %q", sink.bytes(), "Hello")
+	}
+}
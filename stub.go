@@ -0,0 +1,285 @@
+//go:build !js
+
+package jsStreams
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// This file provides the public API of the package outside of WASM, so that it (and anything importing it) compiles and
+// its pure-Go parts can be tested on any platform. Functions that take or return a js.Value only exist under GOOS=js.
+// Outside of GOOS=js, streams are backed by Go readers and writers instead of JavaScript streams, and any operation that
+// would require JavaScript returns errors.ErrUnsupported.
+
+// ReadableStream implements io.ReadCloser for a JavaScript ReadableStream.
+type ReadableStream struct {
+	source    io.Reader
+	lock      sync.Mutex
+	closed    atomic.Bool
+	leftover  []byte
+	finished  closeNotifier
+	bytesRead atomic.Int64
+	readRate  rateMeter
+
+	// expectedLength is the length of the whole stream, as given to SetExpectedLength, or nil if it hasn't been.
+	expectedLength atomic.Pointer[int64]
+}
+
+// Read reads up to len(p) bytes into p. It returns the number of bytes read (0 <= n <= len(p)) and any error encountered.
+func (r *ReadableStream) Read(p []byte) (n int, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.readHeld(p)
+}
+
+// readHeld reads up to len(p) bytes into p, exactly like Read, for a caller that already holds the stream's lock.
+func (r *ReadableStream) readHeld(p []byte) (n int, err error) {
+	if r.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(r.leftover) > 0 {
+		n = copy(p, r.leftover)
+		r.leftover = r.leftover[n:]
+		r.addBytesRead(int64(n))
+		return n, nil
+	}
+	if r.source == nil {
+		return 0, errors.ErrUnsupported
+	}
+
+	n, err = r.source.Read(p)
+	if DebugContractChecks {
+		if violation := checkRead(r.source, p, n); violation != nil {
+			panic(violation)
+		}
+	}
+	r.addBytesRead(int64(n))
+	if err == io.EOF {
+		r.finished.finish(nil)
+	} else if err != nil {
+		r.finished.finish(err)
+	}
+
+	return n, err
+}
+
+// readChunkHeld reads into p exactly like readHeld, as every read outside of GOOS=js is a single read of the source.
+func (r *ReadableStream) readChunkHeld(p []byte) (int, error) {
+	return r.readHeld(p)
+}
+
+// unread puts data back at the front of the stream, to be returned by the next read. The caller must hold the stream's
+// lock.
+func (r *ReadableStream) unread(data []byte) {
+	r.leftover = append(append([]byte(nil), data...), r.leftover...)
+	r.addBytesRead(-int64(len(data)))
+}
+
+// ReadByte implements io.ByteReader, reading a single byte from the stream.
+func (r *ReadableStream) ReadByte() (byte, error) {
+	var one [1]byte
+	_, err := io.ReadFull(r, one[:])
+	return one[0], err
+}
+
+// Close closes the ReadableStream, closing its source if it is an io.Closer. It doesn't wait for a Read in progress, as
+// the source may only return once it has been closed, so the source must allow Close to be called during a Read. If the
+// stream is already closed, Close does nothing.
+func (r *ReadableStream) Close() error {
+	if !r.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	r.finished.finish(nil)
+	if closer, ok := r.source.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// Locked always returns false outside of GOOS=js, as there are no JavaScript readers to lock the stream.
+func (r *ReadableStream) Locked() bool {
+	return false
+}
+
+// BytesRead returns the total number of bytes read from the stream so far.
+func (r *ReadableStream) BytesRead() int64 {
+	return r.bytesRead.Load()
+}
+
+// Buffered returns the number of bytes that have been put back into the stream but not yet read, such as what ReadUntil
+// read past its delimiter, which the next Read returns without reading from the source.
+func (r *ReadableStream) Buffered() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return len(r.leftover)
+}
+
+// newGoReadableStream creates a ReadableStream backed by a Go io.ReadCloser.
+func newGoReadableStream(source io.ReadCloser) *ReadableStream {
+	return &ReadableStream{source: source}
+}
+
+// newGoReadableStreamSize creates a ReadableStream backed by a Go io.ReadCloser, which is read directly, whatever the
+// chunk size.
+func newGoReadableStreamSize(source io.ReadCloser, chunkSize int) *ReadableStream {
+	return newGoReadableStream(source)
+}
+
+// NewFakeReadableStream creates a ReadableStream that reads from r, for testing code built on this package without a
+// JavaScript runtime. Each Read of the stream is a single Read of r, as each read of a JavaScript stream is a single
+// chunk, so an io.PipeReader, say, delivers every write to its io.PipeWriter as a chunk of its own. Closing the stream
+// closes r, if it is an io.Closer. It only exists outside of GOOS=js.
+func NewFakeReadableStream(r io.Reader) *ReadableStream {
+	return &ReadableStream{source: r}
+}
+
+// WritableStream implements io.WriteCloser for a JavaScript WritableStream.
+type WritableStream struct {
+	sink         io.Writer
+	lock         sync.Mutex
+	closed       atomic.Bool
+	finished     closeNotifier
+	bytesWritten atomic.Int64
+	writeRate    rateMeter
+	flush        func() error
+}
+
+// newGoWritableStream creates a WritableStream backed by a Go io.WriteCloser.
+func newGoWritableStream(sink io.WriteCloser) *WritableStream {
+	w := &WritableStream{sink: sink}
+	if flusher, ok := sink.(interface{ Flush() error }); ok {
+		w.flush = flusher.Flush
+	}
+	return w
+}
+
+// NewFakeWritableStream creates a WritableStream that writes to w, for testing code built on this package without a
+// JavaScript runtime. Each Write of the stream is a single Write of w, and closing the stream closes w, if it is an
+// io.Closer, so writing to an io.PipeWriter lets the data be read from the other end of the pipe, through
+// NewFakeReadableStream if need be. It only exists outside of GOOS=js.
+func NewFakeWritableStream(w io.Writer) *WritableStream {
+	return &WritableStream{sink: w}
+}
+
+// Write writes len(p) bytes from p to the underlying data stream. It returns the number of bytes written from p (0 <= n <= len(p))
+// and any error encountered that caused the write to stop early.
+func (w *WritableStream) Write(p []byte) (n int, err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if w.sink == nil {
+		return 0, errors.ErrUnsupported
+	}
+
+	n, err = w.sink.Write(p)
+	if DebugContractChecks {
+		if violation := checkWrite(w.sink, p, n, err); violation != nil {
+			panic(violation)
+		}
+	}
+	w.addBytesWritten(int64(n))
+	if err != nil {
+		w.finished.finish(err)
+	}
+	return n, err
+}
+
+// ReadFrom implements io.ReaderFrom, writing everything read from src to the stream until src returns io.EOF.
+func (w *WritableStream) ReadFrom(src io.Reader) (int64, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	if w.sink == nil {
+		return 0, errors.ErrUnsupported
+	}
+
+	n, err := io.Copy(w.sink, src)
+	w.addBytesWritten(n)
+	return n, err
+}
+
+// BytesWritten returns the total number of bytes written to the stream so far.
+func (w *WritableStream) BytesWritten() int64 {
+	return w.bytesWritten.Load()
+}
+
+// Close closes the WritableStream. If the stream is already closed, Close does nothing.
+func (w *WritableStream) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed.Load() {
+		return nil
+	}
+
+	w.closed.Store(true)
+	var err error
+	if closer, ok := w.sink.(io.Closer); ok {
+		err = closer.Close()
+	}
+	w.finished.finish(err)
+
+	return err
+}
+
+// Locked always returns false outside of GOOS=js, as there are no JavaScript writers to lock the stream.
+func (w *WritableStream) Locked() bool {
+	return false
+}
+
+// DesiredSize always returns false outside of GOOS=js, as there is no queue to report on.
+func (w *WritableStream) DesiredSize() (size int, ok bool) {
+	return 0, false
+}
+
+// Ready returns immediately outside of GOOS=js, as there is no queue to wait on.
+func (w *WritableStream) Ready() error {
+	if w.sink == nil {
+		return errors.ErrUnsupported
+	}
+
+	return nil
+}
+
+// Reader is a JavaScript reader acquired for a ReadableStream. Readers require JavaScript, so outside of GOOS=js they
+// can never be acquired.
+type Reader struct{}
+
+// AcquireReader returns errors.ErrUnsupported outside of GOOS=js.
+func (r *ReadableStream) AcquireReader(mode string) (*Reader, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// Read returns errors.ErrUnsupported outside of GOOS=js.
+func (r *Reader) Read(p []byte) (int, error) {
+	return 0, errors.ErrUnsupported
+}
+
+// ReleaseLock returns errors.ErrUnsupported outside of GOOS=js.
+func (r *Reader) ReleaseLock() error {
+	return errors.ErrUnsupported
+}
+
+// Cancel returns errors.ErrUnsupported outside of GOOS=js.
+func (r *Reader) Cancel(reason string) error {
+	return errors.ErrUnsupported
+}
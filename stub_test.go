@@ -0,0 +1,93 @@
+//go:build !js
+
+package jsStreams
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFakeStreams(t *testing.T) {
+	// Frames written to one end of a pipe come out of the other, however the writes and reads line up.
+	pipeReader, pipeWriter := io.Pipe()
+	writable, readable := NewFakeWritableStream(pipeWriter), NewFakeReadableStream(pipeReader)
+	go func() {
+		for _, payload := range []string{"Hello", "", "world!"} {
+			if err := writable.WriteFrame([]byte(payload)); err != nil {
+				t.Errorf("WriteFrame returned error: %v", err)
+			}
+		}
+		writable.Close()
+	}()
+
+	for _, want := range []string{"Hello", "", "world!"} {
+		payload, err := readable.ReadFrame()
+		if err != nil || string(payload) != want {
+			t.Fatalf("ReadFrame returned %q, %v, want %q, nil", payload, err, want)
+		}
+	}
+	if _, err := readable.ReadFrame(); err != io.EOF {
+		t.Fatalf("ReadFrame at the end of the stream returned %v, want %v", err, io.EOF)
+	}
+
+	// The limit and concatenation helpers work over fakes as well.
+	concatenated := ConcatReadableStreams(NewFakeReadableStream(strings.NewReader("Hello, ")),
+		NewFakeReadableStream(strings.NewReader("world!")))
+	if data, err := concatenated.ReadAllLimit(int64(len("Hello, world!"))); err != nil || string(data) != "Hello, world!" {
+		t.Fatalf("ReadAllLimit returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+	if _, err := NewFakeReadableStream(strings.NewReader("Hello, world!")).ReadAllLimit(5); err != ErrTooLarge {
+		t.Fatalf("ReadAllLimit of too much returned %v, want %v", err, ErrTooLarge)
+	}
+}
+
+func TestFakeStreamsContractChecks(t *testing.T) {
+	enableContractChecks(t)
+
+	violation := func(f func()) (err error) {
+		defer func() {
+			err, _ = recover().(error)
+		}()
+		f()
+		return nil
+	}
+	if err := violation(func() {
+		_, _ = NewFakeReadableStream(overstatingReader{}).Read(make([]byte, 4))
+	}); !errors.Is(err, ErrContractViolation) {
+		t.Fatalf("an overstated Read panicked with %v, want %v", err, ErrContractViolation)
+	}
+	if err := violation(func() {
+		_, _ = NewFakeWritableStream(shortWriter{}).Write(make([]byte, 4))
+	}); !errors.Is(err, ErrContractViolation) {
+		t.Fatalf("a short Write panicked with %v, want %v", err, ErrContractViolation)
+	}
+
+	// Streams that keep to the contract are left alone.
+	if data, err := io.ReadAll(NewFakeReadableStream(strings.NewReader("Hello, world!"))); err != nil ||
+		string(data) != "Hello, world!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "Hello, world!")
+	}
+}
+
+func TestFakeStreamBuffered(t *testing.T) {
+	stream := NewFakeReadableStream(strings.NewReader("key: value\nHello, world!"))
+	if buffered := stream.Buffered(); buffered != 0 {
+		t.Fatalf("Buffered returned %d before reading, want 0", buffered)
+	}
+
+	// What ReadUntil read past the delimiter is buffered until it is read.
+	if _, err := stream.ReadUntil([]byte("\n"), true); err != nil {
+		t.Fatalf("ReadUntil returned error: %v", err)
+	}
+	if buffered := stream.Buffered(); buffered != len("Hello, world!") {
+		t.Fatalf("Buffered returned %d, want %d", buffered, len("Hello, world!"))
+	}
+	if _, err := stream.Read(make([]byte, 5)); err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if buffered := stream.Buffered(); buffered != len(", world!") {
+		t.Fatalf("Buffered returned %d after a Read, want %d", buffered, len(", world!"))
+	}
+}
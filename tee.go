@@ -0,0 +1,180 @@
+package jsStreams
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrInvalidMaxBuffer is returned by TeeBounded if maxBuffer is not positive.
+var ErrInvalidMaxBuffer = errors.New("maxBuffer must be positive")
+
+// boundedTee reads a stream once on behalf of two branches, buffering what one branch has read but the other hasn't yet.
+type boundedTee struct {
+	source    *ReadableStream
+	maxBuffer int
+
+	lock sync.Mutex
+	cond *sync.Cond
+	// buffer holds the data at least one open branch hasn't read yet, which starts at offset base in the stream, and
+	// offsets holds how far into the stream each branch has read.
+	buffer  []byte
+	base    int64
+	offsets [2]int64
+	closed  [2]bool
+	ended   [2]bool
+	reading bool
+	err     error
+
+	sourceOnce sync.Once
+	// peak is the most that has ever been buffered at once.
+	peak int
+}
+
+func newBoundedTee(source *ReadableStream, maxBuffer int) *boundedTee {
+	tee := &boundedTee{source: source, maxBuffer: maxBuffer}
+	tee.cond = sync.NewCond(&tee.lock)
+	return tee
+}
+
+// read reads up to len(p) bytes into p for the given branch, reading more from the source if the branch has caught up
+// with everything buffered. If the buffer is full, the branch waits for the other one to catch up before reading more,
+// which holds back the source until it does.
+func (t *boundedTee) read(branch int, p []byte) (int, error) {
+	n, err, finished := t.readLocked(branch, p)
+	if finished {
+		t.closeSource()
+	}
+	return n, err
+}
+
+// readLocked does the work of read while holding the lock, reporting whether both branches are now finished with the
+// source.
+func (t *boundedTee) readLocked(branch int, p []byte) (int, error, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for {
+		if t.closed[branch] {
+			return 0, io.ErrClosedPipe, false
+		}
+
+		if start := t.offsets[branch] - t.base; start < int64(len(t.buffer)) {
+			n := copy(p, t.buffer[start:])
+			t.offsets[branch] += int64(n)
+			t.trim()
+			return n, nil, false
+		}
+		if t.err != nil {
+			// A branch that has ended may never be closed, so it counts as finished with the source.
+			t.ended[branch] = true
+			return 0, t.err, t.finished()
+		}
+
+		room := t.maxBuffer - len(t.buffer)
+		if t.reading || room <= 0 {
+			t.cond.Wait()
+			continue
+		}
+
+		// Only one branch reads from the source at a time, and never more than there is room for.
+		if room > len(p) {
+			room = len(p)
+		}
+		chunk := make([]byte, room)
+		t.reading = true
+		t.lock.Unlock()
+		n, err := t.source.Read(chunk)
+		t.lock.Lock()
+		t.reading = false
+
+		t.buffer = append(t.buffer, chunk[:n]...)
+		if len(t.buffer) > t.peak {
+			t.peak = len(t.buffer)
+		}
+		t.err = err
+		t.cond.Broadcast()
+	}
+}
+
+// trim drops the data every open branch has read from the buffer, and wakes up anything waiting for room in it. The
+// caller must hold the lock.
+func (t *boundedTee) trim() {
+	var consumed int64 = -1
+	for branch, offset := range t.offsets {
+		if !t.closed[branch] && (consumed < 0 || offset < consumed) {
+			consumed = offset
+		}
+	}
+	if consumed < 0 {
+		consumed = t.base + int64(len(t.buffer))
+	}
+
+	if drop := consumed - t.base; drop > 0 {
+		t.buffer = append(t.buffer[:0], t.buffer[drop:]...)
+		t.base = consumed
+		t.cond.Broadcast()
+	}
+}
+
+// finished reports whether both branches have been closed or have ended. The caller must hold the lock.
+func (t *boundedTee) finished() bool {
+	return (t.closed[0] || t.ended[0]) && (t.closed[1] || t.ended[1])
+}
+
+// closeSource closes the source, only the first time it is called.
+func (t *boundedTee) closeSource() (err error) {
+	t.sourceOnce.Do(func() {
+		err = t.source.Close()
+	})
+	return err
+}
+
+// close closes the given branch, so that the other branch no longer waits for it, closing the source once both branches
+// have been closed or have ended.
+func (t *boundedTee) close(branch int) error {
+	t.lock.Lock()
+	if t.closed[branch] {
+		t.lock.Unlock()
+		return nil
+	}
+	t.closed[branch] = true
+	t.trim()
+	t.cond.Broadcast()
+	finished := t.finished()
+	t.lock.Unlock()
+
+	if finished {
+		return t.closeSource()
+	}
+	return nil
+}
+
+// teeBranch is one of the two branches of a boundedTee.
+type teeBranch struct {
+	tee    *boundedTee
+	branch int
+}
+
+func (b *teeBranch) Read(p []byte) (int, error) {
+	return b.tee.read(b.branch, p)
+}
+
+func (b *teeBranch) Close() error {
+	return b.tee.close(b.branch)
+}
+
+// TeeBounded splits r into two ReadableStreams that each yield all of its data, like a JavaScript stream's tee, but never
+// buffers more than maxBuffer bytes that one branch has read and the other hasn't. Once that much is buffered, the faster
+// branch waits for the slower one to catch up before r is read any further, so the slower branch holds back both the
+// faster one and r, rather than letting the buffer grow without bound. Closing a branch stops the other one waiting for
+// it, and r is closed once both are closed. As each branch holds back the other, both must be read concurrently, or
+// closed, once the buffer fills up. If maxBuffer is not positive, ErrInvalidMaxBuffer is returned.
+func TeeBounded(r *ReadableStream, maxBuffer int) (*ReadableStream, *ReadableStream, error) {
+	if maxBuffer <= 0 {
+		return nil, nil, ErrInvalidMaxBuffer
+	}
+
+	tee := newBoundedTee(r, maxBuffer)
+	return newGoReadableStream(&teeBranch{tee: tee, branch: 0}), newGoReadableStream(&teeBranch{tee: tee, branch: 1}), nil
+}
@@ -0,0 +1,99 @@
+package jsStreams
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTeeBounded(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	const maxBuffer = 64
+	tee := newBoundedTee(newGoReadableStream(io.NopCloser(bytes.NewReader(data))), maxBuffer)
+
+	// One branch reads as fast as it can, while the other dawdles over small reads.
+	var fast, slow []byte
+	var fastErr, slowErr error
+	var wait sync.WaitGroup
+	wait.Add(2)
+	go func() {
+		defer wait.Done()
+		fast, fastErr = io.ReadAll(&teeBranch{tee: tee, branch: 0})
+	}()
+	go func() {
+		defer wait.Done()
+		buffer := make([]byte, 7)
+		for {
+			n, err := tee.read(1, buffer)
+			slow = append(slow, buffer[:n]...)
+			if err != nil {
+				if err != io.EOF {
+					slowErr = err
+				}
+				return
+			}
+			if len(slow)%700 == 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+	wait.Wait()
+
+	if fastErr != nil || !bytes.Equal(fast, data) {
+		t.Fatalf("fast branch read %d bytes and %v, want %d bytes and nil", len(fast), fastErr, len(data))
+	}
+	if slowErr != nil || !bytes.Equal(slow, data) {
+		t.Fatalf("slow branch read %d bytes and %v, want %d bytes and nil", len(slow), slowErr, len(data))
+	}
+	if tee.peak > maxBuffer {
+		t.Fatalf("tee buffered %d bytes at once, want at most %d", tee.peak, maxBuffer)
+	}
+}
+
+func TestTeeBoundedClose(t *testing.T) {
+	source := &closeRecorder{Reader: bytes.NewReader(bytes.Repeat([]byte("x"), 1000)), closed: make(chan struct{})}
+	first, second, err := TeeBounded(newGoReadableStream(source), 16)
+	if err != nil {
+		t.Fatalf("TeeBounded returned error: %v", err)
+	}
+
+	// Once the second branch is closed, the first no longer waits for it, even once it is more than the buffer ahead.
+	if err := second.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := first.ReadFull(make([]byte, 100)); err != nil {
+		t.Fatalf("ReadFull returned error: %v", err)
+	}
+	select {
+	case <-source.closed:
+		t.Fatal("source was closed while a branch was still open")
+	default:
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	// Closing a stream doesn't wait for its source to be closed.
+	select {
+	case <-source.closed:
+	case <-time.After(time.Second):
+		t.Fatal("source wasn't closed once both branches were")
+	}
+
+	if _, _, err := TeeBounded(newStringStream("Hello"), 0); err != ErrInvalidMaxBuffer {
+		t.Fatalf("TeeBounded with no buffer returned %v, want %v", err, ErrInvalidMaxBuffer)
+	}
+}
+
+// closeRecorder closes closed once it has been closed.
+type closeRecorder struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (c *closeRecorder) Close() error {
+	close(c.closed)
+	return nil
+}
@@ -0,0 +1,89 @@
+package jsStreams
+
+import (
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+// textReader reads UTF-8 text from a stream, holding back the start of a rune split across chunks until the rest of it
+// has arrived.
+type textReader struct {
+	source *ReadableStream
+	lock   sync.Mutex
+	// buffer holds what has been read from the source, of which the first partial bytes are the start of a rune that is
+	// still incomplete.
+	buffer  []byte
+	partial int
+	// ready is the text that is ready to be returned, which only ever ends on a rune boundary, and err is the error the
+	// source returned, which is returned once ready is empty.
+	ready      []byte
+	readyStore []byte
+	err        error
+}
+
+func (t *textReader) Read(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for len(t.ready) == 0 {
+		if t.err != nil {
+			return 0, t.err
+		}
+
+		n, err := t.source.Read(t.buffer[t.partial:])
+		total := t.partial + n
+		cut := runeBoundary(t.buffer[:total])
+		if err != nil {
+			// Nothing more is coming to complete a rune, so whatever is left is passed on as it is.
+			t.err = err
+			cut = total
+		}
+
+		t.ready = append(t.readyStore[:0], t.buffer[:cut]...)
+		t.readyStore = t.ready
+		t.partial = copy(t.buffer, t.buffer[cut:total])
+	}
+
+	n := len(t.ready)
+	if n > len(p) {
+		n = runeBoundary(t.ready[:len(p)])
+		if n == 0 {
+			// Not even the first rune fits in p.
+			return 0, io.ErrShortBuffer
+		}
+	}
+	copy(p, t.ready[:n])
+	t.ready = t.ready[n:]
+	return n, nil
+}
+
+// runeBoundary returns the length of the longest prefix of b that doesn't end part way through a UTF-8 rune. Bytes that
+// can't be the start of a valid rune are treated as complete, so invalid UTF-8 is never held back.
+func runeBoundary(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return len(b)
+			}
+			return i
+		}
+	}
+	return len(b)
+}
+
+// TextReader returns an io.Reader over the stream's UTF-8 text whose reads never split a rune, even when the stream's
+// chunks do, by holding back the start of a rune until the rest of it has been read. This makes it safe to convert each
+// read to a string on its own. A read into a buffer too small for the next rune returns io.ErrShortBuffer, so buffers
+// should be at least utf8.UTFMax bytes. Invalid UTF-8 is passed on as it is, as is an incomplete rune at the end of the
+// stream.
+func (r *ReadableStream) TextReader() io.Reader {
+	return &textReader{
+		source: r,
+		buffer: make([]byte, defaultChunkSize),
+	}
+}
@@ -0,0 +1,57 @@
+package jsStreams
+
+import (
+	"io"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTextReader(t *testing.T) {
+	// "€" is the three bytes e2 82 ac, and "😀" the four bytes f0 9f 98 80, each split across chunks.
+	reader := newChunkedStream("caf\xe2\x82", "\xac, ", "\xf0", "\x9f\x98", "\x80!").TextReader()
+
+	buffer := make([]byte, 16)
+	var text string
+	for {
+		n, err := reader.Read(buffer)
+		if !utf8.Valid(buffer[:n]) {
+			t.Fatalf("Read returned %q, which splits a rune", buffer[:n])
+		}
+		text += string(buffer[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+	}
+	if text != "caf€, 😀!" {
+		t.Fatalf("read %q, want %q", text, "caf€, 😀!")
+	}
+}
+
+func TestTextReaderSmallBuffer(t *testing.T) {
+	reader := newChunkedStream("a€b").TextReader()
+
+	// A buffer too small for a rune only takes the runes before it.
+	buffer := make([]byte, 2)
+	if n, err := reader.Read(buffer); n != 1 || err != nil || buffer[0] != 'a' {
+		t.Fatalf("Read returned %q, %v, want %q, nil", buffer[:n], err, "a")
+	}
+	if n, err := reader.Read(buffer); n != 0 || err != io.ErrShortBuffer {
+		t.Fatalf("Read of a rune into a short buffer returned %d, %v, want 0, %v", n, err, io.ErrShortBuffer)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil || string(data) != "€b" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "€b")
+	}
+}
+
+func TestTextReaderTruncated(t *testing.T) {
+	// An incomplete rune at the end of the stream is passed on, rather than lost.
+	data, err := io.ReadAll(newChunkedStream("ok", "\xe2\x82").TextReader())
+	if err != nil || string(data) != "ok\xe2\x82" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "ok\xe2\x82")
+	}
+}
@@ -0,0 +1,56 @@
+package jsStreams
+
+import (
+	"sync"
+	"time"
+)
+
+// throttleReader paces the data read from a stream so that it never runs ahead of a fixed rate.
+type throttleReader struct {
+	source         *ReadableStream
+	bytesPerSecond int
+	start          time.Time
+	delivered      int64
+	lock           sync.Mutex
+}
+
+func (t *throttleReader) Read(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.start.IsZero() {
+		t.start = time.Now()
+	}
+
+	// Reading a tenth of a second's worth at a time keeps delivery smooth, rather than in bursts of a whole buffer.
+	burst := t.bytesPerSecond / 10
+	if burst < 1 {
+		burst = 1
+	}
+	if len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := t.source.Read(p)
+	t.delivered += int64(n)
+
+	due := t.start.Add(time.Duration(t.delivered * int64(time.Second) / int64(t.bytesPerSecond)))
+	time.Sleep(time.Until(due))
+
+	return n, err
+}
+
+func (t *throttleReader) Close() error {
+	return t.source.Close()
+}
+
+// ThrottleReadableStream creates a ReadableStream that yields the same bytes as r, but no faster than bytesPerSecond, by
+// holding back each read until the time it is due at that rate. This is useful to simulate a slow network in tests, or
+// to limit how fast a client consumes a source. The rate is averaged from the first read, so a reader that falls behind
+// is allowed to catch up. If bytesPerSecond is not positive, r is returned as is. Closing the returned stream closes r.
+func ThrottleReadableStream(r *ReadableStream, bytesPerSecond int) *ReadableStream {
+	if bytesPerSecond <= 0 {
+		return r
+	}
+	return newGoReadableStream(&throttleReader{source: r, bytesPerSecond: bytesPerSecond})
+}
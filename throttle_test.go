@@ -0,0 +1,28 @@
+package jsStreams
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottleReadableStream(t *testing.T) {
+	data := strings.Repeat("a", 200)
+	stream := ThrottleReadableStream(newChunkedStream(data), 1000)
+
+	start := time.Now()
+	read, err := io.ReadAll(stream)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if string(read) != data {
+		t.Fatalf("ReadAll returned %d bytes, want %d", len(read), len(data))
+	}
+
+	// 200 bytes at 1000 bytes per second should take about 200 milliseconds.
+	if elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("reading took %v, want about 200ms", elapsed)
+	}
+}
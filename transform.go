@@ -0,0 +1,53 @@
+package jsStreams
+
+import (
+	"sync"
+)
+
+// transformReader reads chunks from a stream, passing each through a transform function before handing out the result.
+type transformReader struct {
+	source    *ReadableStream
+	transform func([]byte) ([]byte, error)
+	buffer    []byte
+	pending   []byte
+	lock      sync.Mutex
+}
+
+func (t *transformReader) Read(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for len(t.pending) == 0 {
+		n, err := t.source.Read(t.buffer)
+		if n > 0 {
+			output, transformErr := t.transform(t.buffer[:n])
+			if transformErr != nil {
+				return 0, transformErr
+			}
+			t.pending = output
+		}
+		if err != nil && len(t.pending) == 0 {
+			return 0, err
+		}
+	}
+
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+func (t *transformReader) Close() error {
+	return t.source.Close()
+}
+
+// TeeThroughGo creates a ReadableStream that yields the data read from src, after passing each chunk through transform.
+// Unlike a JavaScript TransformStream, the transform runs in Go, so it can make use of Go libraries. The source is read
+// lazily, one chunk at a time, as the returned stream is read, and the chunk passed to transform is only valid until it
+// returns. If transform returns an error, it is passed on to the reader. Closing the returned stream closes src.
+func TeeThroughGo(src *ReadableStream, transform func([]byte) ([]byte, error)) *ReadableStream {
+	return newGoReadableStream(&transformReader{
+		source:    src,
+		transform: transform,
+		buffer:    make([]byte, defaultChunkSize),
+	})
+}
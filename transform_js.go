@@ -0,0 +1,79 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall/js"
+)
+
+// ErrNotTransformStream is returned by NewTransformStreamChecked if the value doesn't have a readable and a writable side.
+var ErrNotTransformStream = errors.New("value must be a TransformStream, with a readable and a writable side")
+
+// TransformStream is a JavaScript TransformStream, or any other pair of a writable side and a readable side, such as a
+// CompressionStream or a TextDecoderStream, that data can be piped through.
+type TransformStream struct {
+	stream js.Value
+}
+
+// NewTransformStream creates a new TransformStream from a JavaScript TransformStream, or any object with a readable and a
+// writable property, such as a CompressionStream.
+func NewTransformStream(stream js.Value) *TransformStream {
+	return &TransformStream{stream: stream}
+}
+
+// NewTransformStreamChecked creates a new TransformStream like NewTransformStream, but first checks that stream has a
+// readable and a writable side, returning ErrNotTransformStream if it doesn't.
+func NewTransformStreamChecked(stream js.Value) (*TransformStream, error) {
+	if stream.Type() != js.TypeObject ||
+		!hasMethods(stream.Get("readable"), "getReader") || !hasMethods(stream.Get("writable"), "getWriter") {
+		return nil, ErrNotTransformStream
+	}
+	return NewTransformStream(stream), nil
+}
+
+// JSValue returns the underlying JavaScript TransformStream.
+func (t *TransformStream) JSValue() js.Value {
+	return t.stream
+}
+
+// PipeThrough pipes the ReadableStream through t, returning t's readable side, which yields the transformed data. The
+// pipe takes over the ReadableStream, which must not be locked and must not be read from afterwards, and runs in the
+// background as the returned stream is read.
+func (r *ReadableStream) PipeThrough(t *TransformStream) (piped *ReadableStream, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed.Load() {
+		return nil, io.ErrClosedPipe
+	}
+	if r.reader != nil || r.stream.Get("locked").Bool() {
+		return nil, ErrStreamLocked
+	}
+
+	return NewReadableStream(r.stream.Call("pipeThrough", t.stream)), nil
+}
+
+// PipeThroughAll pipes the ReadableStream through each of ts in turn, as repeated calls to PipeThrough would, returning
+// the readable side of the last one. This makes pipelines such as decompressing, decoding and then parsing a stream
+// easier to build. With no transforms, the ReadableStream itself is returned.
+func (r *ReadableStream) PipeThroughAll(ts ...*TransformStream) (*ReadableStream, error) {
+	piped := r
+	for _, t := range ts {
+		var err error
+		piped, err = piped.PipeThrough(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return piped, nil
+}
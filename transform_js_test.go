@@ -0,0 +1,58 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"bytes"
+	"io"
+	"syscall/js"
+	"testing"
+)
+
+// newTestTransformStream creates a JavaScript TransformStream that passes each chunk through transform.
+func newTestTransformStream(transform func([]byte) []byte) js.Value {
+	return js.Global().Get("TransformStream").New(map[string]interface{}{
+		"transform": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			chunk := make([]byte, args[0].Length())
+			js.CopyBytesToGo(chunk, args[0])
+			chunk = transform(chunk)
+
+			buffer := js.Global().Get("Uint8Array").New(len(chunk))
+			js.CopyBytesToJS(buffer, chunk)
+			args[1].Call("enqueue", buffer)
+			return nil
+		}),
+	})
+}
+
+func TestPipeThroughAll(t *testing.T) {
+	stream := NewReadableStream(newTestReadableStream([]byte("Hello, "), []byte("world!")))
+	piped, err := stream.PipeThroughAll(
+		NewTransformStream(newTestTransformStream(bytes.ToUpper)),
+		NewTransformStream(newTestTransformStream(func(chunk []byte) []byte {
+			return bytes.ReplaceAll(chunk, []byte("O"), []byte("0"))
+		})),
+	)
+	if err != nil {
+		t.Fatalf("PipeThroughAll returned error: %v", err)
+	}
+	if _, err := stream.PipeThrough(NewTransformStream(newTestTransformStream(bytes.ToUpper))); err != ErrStreamLocked {
+		t.Fatalf("PipeThrough of a stream being piped returned %v, want %v", err, ErrStreamLocked)
+	}
+
+	// A TransformStream's readable side isn't a byte stream, so it is read through a default reader.
+	if data, err := io.ReadAll(piped); err != nil || string(data) != "HELL0, W0RLD!" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "HELL0, W0RLD!")
+	}
+}
+
+func TestNewTransformStreamChecked(t *testing.T) {
+	if _, err := NewTransformStreamChecked(newTestTransformStream(bytes.ToUpper)); err != nil {
+		t.Fatalf("NewTransformStreamChecked returned error: %v", err)
+	}
+	for _, value := range []js.Value{js.Undefined(), js.ValueOf("stream"), js.Global().Get("Object").New()} {
+		if _, err := NewTransformStreamChecked(value); err != ErrNotTransformStream {
+			t.Fatalf("NewTransformStreamChecked(%v) returned %v, want %v", value, err, ErrNotTransformStream)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package jsStreams
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTeeThroughGo(t *testing.T) {
+	var expected strings.Builder
+	stream := TeeThroughGo(newStringStream("Hello, world!"), func(chunk []byte) ([]byte, error) {
+		encoded := base64.StdEncoding.EncodeToString(chunk)
+		expected.WriteString(encoded)
+		return []byte(encoded), nil
+	})
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+	if expected.Len() == 0 || string(data) != expected.String() {
+		t.Fatalf("ReadAll returned %q, want %q", data, expected.String())
+	}
+}
+
+func TestTeeThroughGoError(t *testing.T) {
+	transformErr := errors.New("transform failed")
+	stream := TeeThroughGo(newStringStream("Hello, world!"), func(chunk []byte) ([]byte, error) {
+		return nil, transformErr
+	})
+
+	if _, err := io.ReadAll(stream); err == nil || !strings.Contains(err.Error(), transformErr.Error()) {
+		t.Fatalf("ReadAll returned %v, want %v", err, transformErr)
+	}
+}
@@ -0,0 +1,130 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+// WebSocketReader creates a ReadableStream from the messages received by a JavaScript WebSocket. Each message becomes a
+// chunk of the stream, in the order the messages were received: text messages are encoded as UTF-8, and binary messages
+// are copied as they are. The WebSocket's binaryType is set to "arraybuffer" so binary messages can be copied without
+// being read asynchronously, although Blobs are still handled if it is changed back. The stream is closed when the
+// WebSocket closes and errored if the WebSocket reports an error. Closing the returned ReadableStream closes the WebSocket.
+func WebSocketReader(ws js.Value) *ReadableStream {
+	var closed bool
+	chain := js.Global().Get("Promise").Call("resolve")
+	encoder := js.Global().Get("TextEncoder").New()
+	ws.Set("binaryType", "arraybuffer")
+
+	return NewReadableStream(js.Global().Get("ReadableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			readController := args[0]
+
+			// Blobs have to be read asynchronously, so every message is chained onto the previous one to keep them in order.
+			// A byte stream throws on an empty chunk, so empty messages, such as heartbeats, are skipped.
+			enqueue := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				chunk := js.Global().Get("Uint8Array").New(args[0].Index(1))
+				if !closed && chunk.Length() > 0 {
+					readController.Call("enqueue", chunk)
+				}
+				return nil
+			})
+
+			// If a message can't be read, the stream is errored, as it would be missing data, but the chain carries on, so
+			// that the messages after it, and the close, settle rather than wait on it forever.
+			fail := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				if !closed {
+					closed = true
+					readController.Call("error", args[0])
+				}
+				return nil
+			})
+
+			finish := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				if !closed {
+					closed = true
+					closeController(readController)
+				}
+				return nil
+			})
+
+			ws.Call("addEventListener", "message", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				data := args[0].Get("data")
+				switch {
+				case data.Type() == js.TypeString:
+					data = encoder.Call("encode", data)
+				case data.InstanceOf(js.Global().Get("Blob")):
+					data = data.Call("arrayBuffer")
+				}
+				chain = js.Global().Get("Promise").Call("all", []interface{}{chain, data}).
+					Call("then", enqueue).Call("catch", fail)
+				return nil
+			}))
+
+			ws.Call("addEventListener", "close", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				chain = chain.Call("then", finish)
+				return nil
+			}))
+
+			ws.Call("addEventListener", "error", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				if !closed {
+					closed = true
+					readController.Call("error", js.Global().Get("Error").New("WebSocket error"))
+				}
+				return nil
+			}))
+
+			return nil
+		}),
+		"cancel": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			closed = true
+			ws.Call("close")
+			return nil
+		}),
+		"type": "bytes",
+	}))
+}
+
+// WebSocketWriter creates a WritableStream that sends every chunk written to it as a binary message over a JavaScript
+// WebSocket. If the WebSocket is still connecting, writes wait until it has opened. Closing the returned WritableStream
+// closes the WebSocket.
+func WebSocketWriter(ws js.Value) *WritableStream {
+	return NewWritableStream(js.Global().Get("WritableStream").New(map[string]interface{}{
+		"start": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			// readyState 0 is CONNECTING, anything else either can be written to or will reject the send itself.
+			if ws.Get("readyState").Int() != 0 {
+				return nil
+			}
+
+			promise, resolve, reject := newPromise()
+			ws.Call("addEventListener", "open", resolve)
+			ws.Call("addEventListener", "error", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				reject.Invoke(js.Global().Get("Error").New("WebSocket error"))
+				return nil
+			}))
+			return promise
+		}),
+		"write": js.FuncOf(func(this js.Value, args []js.Value) (result interface{}) {
+			defer func() {
+				// send throws if the WebSocket has already closed, which should reject the write rather than crash.
+				recovered := recover()
+				if recovered != nil {
+					result = js.Global().Get("Promise").Call("reject", js.Global().Get("Error").New(fmt.Sprint(recovered)))
+				}
+			}()
+
+			ws.Call("send", args[0])
+			return nil
+		}),
+		"close": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			ws.Call("close")
+			return nil
+		}),
+		"abort": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			ws.Call("close")
+			return nil
+		}),
+	}))
+}
@@ -0,0 +1,133 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// newTestWebSocket creates an EventTarget standing in for a WebSocket, which closes itself when its close method is
+// called.
+func newTestWebSocket() js.Value {
+	ws := js.Global().Get("EventTarget").New()
+	ws.Set("binaryType", "blob")
+	ws.Set("close", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		ws.Call("dispatchEvent", js.Global().Get("Event").New("close"))
+		return nil
+	}))
+	return ws
+}
+
+func TestWebSocketReaderTextAndBinary(t *testing.T) {
+	ws := newTestWebSocket()
+	stream := WebSocketReader(ws)
+	if binaryType := ws.Get("binaryType").String(); binaryType != "arraybuffer" {
+		t.Fatalf("binaryType is %q, want %q", binaryType, "arraybuffer")
+	}
+
+	binary := js.Global().Get("Uint8Array").New(3)
+	js.CopyBytesToJS(binary, []byte{0, 1, 2})
+	for _, data := range []interface{}{"héllo ", binary.Get("buffer"), " wörld", js.Global().Get("Blob").New([]interface{}{"!"})} {
+		ws.Call("dispatchEvent", js.Global().Get("MessageEvent").New("message", map[string]interface{}{"data": data}))
+	}
+	ws.Call("close")
+
+	data, err := io.ReadAll(stream)
+	if want := "héllo \x00\x01\x02 wörld!"; err != nil || string(data) != want {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, want)
+	}
+}
+
+func TestWebSocketReaderEmptyMessages(t *testing.T) {
+	ws := newTestWebSocket()
+	stream := WebSocketReader(ws)
+
+	// Empty text and binary messages, such as heartbeats, don't hold up the ones around them.
+	for _, data := range []interface{}{"ping", "", js.Global().Get("ArrayBuffer").New(0), "pong"} {
+		ws.Call("dispatchEvent", js.Global().Get("MessageEvent").New("message", map[string]interface{}{"data": data}))
+	}
+	ws.Call("close")
+
+	data, err := io.ReadAll(stream)
+	if err != nil || string(data) != "pingpong" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "pingpong")
+	}
+}
+
+// newTestSendingWebSocket creates an EventTarget standing in for a WebSocket that is still connecting, recording every
+// frame sent on it and how many times it has been closed.
+func newTestSendingWebSocket() (ws js.Value, frames func() []string, closes func() int) {
+	ws = js.Global().Get("EventTarget").New()
+	ws.Set("readyState", 0)
+	var sent []string
+	var closed int
+	ws.Set("send", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		frame, _ := toUint8Array(args[0])
+		data := make([]byte, frame.Length())
+		js.CopyBytesToGo(data, frame)
+		sent = append(sent, string(data))
+		return nil
+	}))
+	ws.Set("close", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		closed++
+		return nil
+	}))
+	return ws, func() []string { return sent }, func() int { return closed }
+}
+
+func TestWebSocketWriter(t *testing.T) {
+	ws, frames, closes := newTestSendingWebSocket()
+	stream := WebSocketWriter(ws)
+
+	// Writes wait for the WebSocket to open.
+	written := make(chan error, 1)
+	go func() {
+		_, err := stream.Write([]byte("Hello, "))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		t.Fatalf("Write returned %v while the WebSocket was connecting", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if sent := frames(); len(sent) != 0 {
+		t.Fatalf("frames %q were sent while the WebSocket was connecting", sent)
+	}
+	ws.Set("readyState", 1)
+	ws.Call("dispatchEvent", js.Global().Get("Event").New("open"))
+	if err := <-written; err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	// Every Write is sent as a frame of its own.
+	if _, err := stream.Write([]byte("world!")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if want := []string{"Hello, ", "world!"}; fmt.Sprint(frames()) != fmt.Sprint(want) {
+		t.Fatalf("sent frames %q, want %q", frames(), want)
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if closes() != 1 {
+		t.Fatalf("Close closed the WebSocket %d times, want 1", closes())
+	}
+
+	// Aborting the stream closes the WebSocket too.
+	ws, _, closes = newTestSendingWebSocket()
+	ws.Set("readyState", 1)
+	stream = WebSocketWriter(ws)
+	err := errors.New("protocol error")
+	if abortErr := stream.abort(goErrorToJS(err), err); abortErr != nil {
+		t.Fatalf("abort returned error: %v", abortErr)
+	}
+	if closes() != 1 {
+		t.Fatalf("aborting closed the WebSocket %d times, want 1", closes())
+	}
+}
@@ -0,0 +1,33 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"syscall/js"
+)
+
+// WebTransportBidiStream creates a DuplexStream from a JavaScript WebTransportBidirectionalStream, or any other object
+// with readable and writable members, such as those returned by WebTransport.createBidirectionalStream.
+func WebTransportBidiStream(stream js.Value) *DuplexStream {
+	return NewDuplexStream(stream.Get("readable"), stream.Get("writable"))
+}
+
+// WebTransportSendStream creates a WritableStream from a JavaScript WebTransportSendStream, such as those returned by
+// WebTransport.createUnidirectionalStream. An object with a writable member, such as a bidirectional stream, is also
+// accepted, in which case its writable side is used.
+func WebTransportSendStream(stream js.Value) *WritableStream {
+	if stream.Get("getWriter").Type() != js.TypeFunction {
+		stream = stream.Get("writable")
+	}
+	return NewWritableStream(stream)
+}
+
+// WebTransportReceiveStream creates a ReadableStream from a JavaScript WebTransportReceiveStream, such as those yielded
+// by WebTransport.incomingUnidirectionalStreams. An object with a readable member, such as a bidirectional stream, is
+// also accepted, in which case its readable side is used.
+func WebTransportReceiveStream(stream js.Value) *ReadableStream {
+	if stream.Get("getReader").Type() != js.TypeFunction {
+		stream = stream.Get("readable")
+	}
+	return NewReadableStream(stream)
+}
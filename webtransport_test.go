@@ -0,0 +1,47 @@
+//go:build js
+
+package jsStreams
+
+import (
+	"io"
+	"syscall/js"
+	"testing"
+)
+
+func TestWebTransportStreams(t *testing.T) {
+	newBidi := func() (js.Value, *testSink) {
+		writable, sink := newTestWritableStream()
+		bidi := js.Global().Get("Object").New()
+		bidi.Set("readable", newTestReadableStream([]byte("ping")))
+		bidi.Set("writable", writable)
+		return bidi, sink
+	}
+
+	bidi, sink := newBidi()
+	duplex := WebTransportBidiStream(bidi)
+	if data, err := io.ReadAll(duplex); err != nil || string(data) != "ping" {
+		t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "ping")
+	}
+	if _, err := duplex.Write([]byte("pong")); err != nil || string(sink.bytes()) != "pong" {
+		t.Fatalf("Write returned %v and sink received %q, want nil and %q", err, sink.bytes(), "pong")
+	}
+
+	// Unidirectional helpers accept either the stream itself or an object containing it.
+	bidi, _ = newBidi()
+	for _, receive := range []js.Value{newTestReadableStream([]byte("ping")), bidi} {
+		if data, err := io.ReadAll(WebTransportReceiveStream(receive)); err != nil || string(data) != "ping" {
+			t.Fatalf("ReadAll returned %q, %v, want %q, nil", data, err, "ping")
+		}
+	}
+
+	writable, writableSink := newTestWritableStream()
+	bidi, bidiSink := newBidi()
+	for _, send := range []struct {
+		stream js.Value
+		sink   *testSink
+	}{{writable, writableSink}, {bidi, bidiSink}} {
+		if _, err := WebTransportSendStream(send.stream).Write([]byte("pong")); err != nil || string(send.sink.bytes()) != "pong" {
+			t.Fatalf("Write returned %v and sink received %q, want nil and %q", err, send.sink.bytes(), "pong")
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package jsStreams
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WriteFrame writes payload to the stream as a single length-prefixed frame, made up of a 4-byte big-endian length
+// followed by the payload, which can be read back with ReadableStream.ReadFrame. The length and payload are written as a
+// single chunk, so the sink never sees one without the other. A payload longer than MaxFrameSize, which ReadFrame would
+// refuse, is not written, and an error wrapping ErrTooLarge is returned.
+func (w *WritableStream) WriteFrame(payload []byte) error {
+	if len(payload) > MaxFrameSize {
+		return fmt.Errorf("%w: frame of %d bytes", ErrTooLarge, len(payload))
+	}
+
+	frame := make([]byte, 4, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	_, err := w.Write(append(frame, payload...))
+	return err
+}
+
+// WriteBuffers writes the contents of bufs to the stream in order, like net.Buffers, returning the total number of bytes
+// written. The buffers are coalesced into a single chunk, so the whole lot costs one write to the sink, instead of one per
+// buffer. Either all of the bytes are written, or none are.
+func (w *WritableStream) WriteBuffers(bufs [][]byte) (int64, error) {
+	var total int
+	for _, buf := range bufs {
+		total += len(buf)
+	}
+
+	chunk := make([]byte, 0, total)
+	for _, buf := range bufs {
+		chunk = append(chunk, buf...)
+	}
+
+	n, err := w.Write(chunk)
+	return int64(n), err
+}
+
+// WriteFromReader writes everything read from r to the stream, returning once r reaches io.EOF. It waits for the stream
+// to be ready before each chunk, so a slow sink holds back reading from r, and it closes neither r nor the stream. It
+// does the same as ReadFrom, but isn't part of an io interface, so io.Copy and the like never call it by accident.
+func (w *WritableStream) WriteFromReader(r io.Reader) error {
+	_, err := w.ReadFrom(r)
+	return err
+}
+
+// Flush writes out any data the stream is holding back, for a stream created by this package that holds data back, such
+// as one returned by CoalescingWriter. Any other stream hands every Write to its sink before the Write returns, so Flush
+// does nothing.
+func (w *WritableStream) Flush() error {
+	if w.flush == nil {
+		return nil
+	}
+	return w.flush()
+}
+
+// String describes the stream for debugging, such as "WritableStream{locked:false, closed:false, bytesWritten:1234}". It
+// never blocks, even while a write is in progress, so it is always safe to log a stream.
+func (w *WritableStream) String() string {
+	return fmt.Sprintf("WritableStream{locked:%v, closed:%v, bytesWritten:%d}", w.Locked(), w.closed.Load(), w.BytesWritten())
+}
+
+// WaitClosed blocks until the stream has finished, returning nil once Close has flushed everything written to the sink
+// and the sink has closed, or the error the stream failed with, such as the reason it was aborted. This lets code other
+// than the caller of Close confirm that the sink actually finished. Only Close and writes made through this package are
+// observed, so a stream aborted by JavaScript code is only seen to have failed once a write or Close runs into the error.
+// WaitClosed doesn't hold a writer, so it doesn't interfere with writes in the meantime.
+func (w *WritableStream) WaitClosed() error {
+	return w.finished.wait()
+}
+
+// writeContext writes p to the stream, like Write, but gives up once ctx is done, returning 0 and ctx's error. A write
+// that has already started carries on in the background, and other writes wait for it. p is copied before writing, so it
+// can be reused as soon as writeContext returns.
+func (w *WritableStream) writeContext(ctx context.Context, p []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	results := make(chan result, 1)
+	data := append([]byte(nil), p...)
+
+	go func() {
+		n, err := w.Write(data)
+		results <- result{n, err}
+	}()
+
+	select {
+	case res := <-results:
+		return res.n, res.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// WaitWritable blocks until the stream is ready to accept more data, as Ready does, or until ctx is done, in which case it
+// returns ctx's error, so that a stream can be fitted into a readiness loop. Nothing is written. If WaitWritable gives up,
+// the wait carries on in the background, and writes wait for it.
+func (w *WritableStream) WaitWritable(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		ready <- w.Ready()
+	}()
+
+	select {
+	case err := <-ready:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,151 @@
+package jsStreams
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// countingSink records everything written to it, along with the number of writes.
+type countingSink struct {
+	recordingSink
+	writes int
+}
+
+func (c *countingSink) Write(p []byte) (int, error) {
+	c.writes++
+	return c.recordingSink.Write(p)
+}
+
+func TestWriteBuffers(t *testing.T) {
+	sink := &countingSink{}
+	stream := newGoWritableStream(sink)
+
+	n, err := stream.WriteBuffers([][]byte{[]byte("Hello"), nil, []byte(", "), []byte("world!")})
+	if err != nil {
+		t.Fatalf("WriteBuffers returned error: %v", err)
+	}
+	if n != int64(len("Hello, world!")) {
+		t.Fatalf("WriteBuffers returned %d, want %d", n, len("Hello, world!"))
+	}
+	if sink.String() != "Hello, world!" {
+		t.Fatalf("sink received %q, want %q", sink.String(), "Hello, world!")
+	}
+	if sink.writes != 1 {
+		t.Fatalf("sink received %d writes, want 1", sink.writes)
+	}
+}
+
+func TestWriteFrameLimit(t *testing.T) {
+	// A frame of exactly MaxFrameSize bytes round-trips through ReadFrame.
+	sink := &recordingSink{}
+	payload := make([]byte, MaxFrameSize)
+	payload[len(payload)-1] = 1
+	if err := newGoWritableStream(sink).WriteFrame(payload); err != nil {
+		t.Fatalf("WriteFrame returned error: %v", err)
+	}
+	frame, err := newGoReadableStream(io.NopCloser(bytes.NewReader(sink.Bytes()))).ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame returned error: %v", err)
+	}
+	if !bytes.Equal(frame, payload) {
+		t.Fatalf("ReadFrame returned %d bytes, want the %d written", len(frame), len(payload))
+	}
+
+	// One byte more is refused without anything reaching the sink.
+	sink = &recordingSink{}
+	if err := newGoWritableStream(sink).WriteFrame(make([]byte, MaxFrameSize+1)); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("WriteFrame returned %v, want %v", err, ErrTooLarge)
+	}
+	if sink.Len() != 0 {
+		t.Fatalf("sink received %d bytes of a refused frame, want none", sink.Len())
+	}
+}
+
+func TestBytesWritten(t *testing.T) {
+	stream := newGoWritableStream(&recordingSink{})
+	if _, err := stream.Write([]byte("Hello")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if _, err := stream.ReadFrom(strings.NewReader(", world!")); err != nil {
+		t.Fatalf("ReadFrom returned error: %v", err)
+	}
+	if written := stream.BytesWritten(); written != int64(len("Hello, world!")) {
+		t.Fatalf("BytesWritten returned %d, want %d", written, len("Hello, world!"))
+	}
+}
+
+func TestWriteFromReader(t *testing.T) {
+	sink := &recordingSink{}
+	stream := newGoWritableStream(sink)
+
+	if err := stream.WriteFromReader(strings.NewReader("Hello, world!")); err != nil {
+		t.Fatalf("WriteFromReader returned error: %v", err)
+	}
+	if err := stream.WriteFromReader(strings.NewReader(" Again.")); err != nil {
+		t.Fatalf("second WriteFromReader returned error: %v", err)
+	}
+	if sink.String() != "Hello, world! Again." {
+		t.Fatalf("sink received %q, want %q", sink.String(), "Hello, world! Again.")
+	}
+	if sink.closed {
+		t.Fatal("WriteFromReader closed the stream")
+	}
+}
+
+func TestWritableStreamString(t *testing.T) {
+	stream := newGoWritableStream(&recordingSink{})
+	if _, err := stream.Write([]byte("Hello")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	want := "WritableStream{locked:false, closed:true, bytesWritten:5}"
+	if s := stream.String(); s != want {
+		t.Fatalf("String returned %q, want %q", s, want)
+	}
+}
+
+func TestWritableStreamWaitClosed(t *testing.T) {
+	sink := &recordingSink{}
+	stream := newGoWritableStream(sink)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- stream.WaitClosed()
+	}()
+
+	if _, err := stream.Write([]byte("Hello")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	select {
+	case err := <-closed:
+		t.Fatalf("WaitClosed returned %v before the stream was closed", err)
+	default:
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if err := <-closed; err != nil || !sink.closed {
+		t.Fatalf("WaitClosed returned %v with the sink closed %v, want nil and true", err, sink.closed)
+	}
+}
+
+func TestWaitWritable(t *testing.T) {
+	stream := newGoWritableStream(&recordingSink{})
+	if err := stream.WaitWritable(context.Background()); err != nil {
+		t.Fatalf("WaitWritable returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := stream.WaitWritable(ctx); err != context.Canceled {
+		t.Fatalf("WaitWritable with a cancelled context returned %v, want %v", err, context.Canceled)
+	}
+}